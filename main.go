@@ -4,7 +4,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime/debug"
+	"strings"
 
 	"github.com/soulnov23/go-tool/pkg/framework"
 	"github.com/soulnov23/go-tool/pkg/utils"
@@ -18,6 +20,18 @@ var (
 	gitCommitAuthor string
 )
 
+// setFlags 可以重复指定的-set key=value
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
 	defer func() {
 		if err := recover(); err != nil {
@@ -27,9 +41,17 @@ func main() {
 
 	// 定义需要解析的命令行参数
 	var version bool
+	var checkConfig bool
+	var printConfig bool
 	var path string
+	var env string
+	var sets setFlags
 	flag.BoolVar(&version, "version", false, "show server version")
+	flag.BoolVar(&checkConfig, "check-config", false, "check server config and exit")
+	flag.BoolVar(&printConfig, "print-config", false, "print merged server config and exit")
 	flag.StringVar(&path, "conf", "./go_tool.yaml", "server config file path")
+	flag.StringVar(&env, "env", os.Getenv(framework.EnvNameKey), "deploy environment, merge go_tool.<env>.yaml over server config")
+	flag.Var(&sets, "set", "override server config, like -set server.services[0].timeout=5000, can be repeated")
 	// 开始解析命令行
 	flag.Parse()
 	// 命令行参数都不匹配，打印help
@@ -45,5 +67,28 @@ func main() {
 		fmt.Printf("git commit author: %s\n", gitCommitAuthor)
 		return
 	}
-	framework.New(path).Serve()
+	// 配置按顺序合并：配置文件、环境配置文件、环境变量、命令行参数
+	var sources []framework.ConfigSource
+	if env != "" {
+		sources = append(sources, framework.OptionalFileSource(framework.EnvFilePath(path, env)))
+	}
+	sources = append(sources, framework.EnvSource(framework.DefaultEnvPrefix), framework.SetSource(sets...))
+	if printConfig {
+		buffer, err := framework.ConfigYAML(path, sources...)
+		if err != nil {
+			fmt.Printf("load config %s failed: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Print(utils.BytesToString(buffer))
+		return
+	}
+	if checkConfig {
+		if err := framework.CheckConfig(path, sources...); err != nil {
+			fmt.Printf("check config %s failed: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("check config %s ok\n", path)
+		return
+	}
+	framework.New(path, sources...).Serve()
 }
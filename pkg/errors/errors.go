@@ -1,12 +1,14 @@
 package errors
 
 import (
+	stderrors "errors"
 	"strings"
 	"sync"
 	"text/template"
 
 	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
 	"github.com/soulnov23/go-tool/pkg/utils"
+	"google.golang.org/protobuf/proto"
 )
 
 //go:generate protoc --proto_path=. --go_out=paths=source_relative:. --validate_out=lang=go,paths=source_relative:. errors.proto
@@ -20,6 +22,14 @@ func (e *Error) Error() string {
 	return utils.Stringify(e)
 }
 
+// Clone 预定义的错误是全局变量，填充消息模板前先复制一份
+func (e *Error) Clone() *Error {
+	if e == nil {
+		return nil
+	}
+	return proto.Clone(e).(*Error)
+}
+
 func (e *Error) WithMessageValues(values any) *Error {
 	value, ok := templateCache.Load(e.Message)
 	if !ok {
@@ -49,7 +59,9 @@ func FromError(err error) *Error {
 	if err == nil {
 		return nil
 	}
-	if innerErr, ok := err.(*Error); ok && innerErr != nil {
+	// 被fmt.Errorf("%w")包装过的错误也能取出内层的*Error
+	var innerErr *Error
+	if stderrors.As(err, &innerErr) && innerErr != nil {
 		return innerErr
 	}
 	return Parse(err.Error())
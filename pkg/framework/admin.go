@@ -0,0 +1,244 @@
+package framework
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"github.com/soulnov23/go-tool/pkg/framework/transport"
+	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
+	pkglog "github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "******"
+
+// sensitiveKeys 配置中字段名包含这些词时不输出原值
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "dsn", "credential", "authorization", "private_key", "access_key"}
+
+// registerAdmin 在pprof端口上注册admin接口，方便线上排查卡住的实例
+func (s *Server) registerAdmin() {
+	s.ProfileProfiler.HandleFunc("/admin/services", s.adminServices)
+	s.ProfileProfiler.HandleFunc("/admin/connections", s.adminConnections)
+	s.ProfileProfiler.HandleFunc("/admin/plugins", s.adminPlugins)
+	s.ProfileProfiler.HandleFunc("/admin/config", s.adminConfig)
+	s.ProfileProfiler.HandleFunc("/admin/log/level", s.adminLogLevel)
+	s.ProfileProfiler.HandleFunc("/admin/shutdown", s.adminShutdown)
+	s.ProfileProfiler.HandleFunc("/metrics", s.adminMetrics)
+}
+
+type adminService struct {
+	Name     string   `json:"name"`
+	Address  string   `json:"address"`
+	Network  string   `json:"network"`
+	Protocol string   `json:"protocol"`
+	Timeout  int64    `json:"timeout"` // 单位 毫秒
+	RPCs     []string `json:"rpcs"`
+}
+
+func (s *Server) adminServices(w http.ResponseWriter, r *http.Request) {
+	services := make([]*adminService, 0, len(s.services))
+	for _, service := range s.services {
+		services = append(services, &adminService{
+			Name:     service.name,
+			Address:  service.address,
+			Network:  service.network,
+			Protocol: service.protocol,
+			Timeout:  service.timeout.Milliseconds(),
+			RPCs:     service.rpcNames(),
+		})
+	}
+	slices.SortFunc(services, func(a, b *adminService) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	writeJSON(w, services)
+}
+
+func (s *Server) adminConnections(w http.ResponseWriter, r *http.Request) {
+	connections := make(map[string][]*transport.EpollInfo, len(s.services)) // k=service_name,v=epolls
+	for name, service := range s.services {
+		if service.serverTransport == nil {
+			continue
+		}
+		connections[name] = service.serverTransport.Epolls()
+	}
+	writeJSON(w, connections)
+}
+
+type adminPlugin struct {
+	Type      string         `json:"type"`
+	Instances []string       `json:"instances"`       // 已经初始化的实例名
+	Stats     map[string]any `json:"stats,omitempty"` // k=name,v=实现了plugin.Stater的实例状态
+}
+
+func (s *Server) adminPlugins(w http.ResponseWriter, r *http.Request) {
+	types := plugin.Types()
+	plugins := make([]*adminPlugin, 0, len(types))
+	for _, typ := range types {
+		item := &adminPlugin{
+			Type:      typ,
+			Instances: plugin.Names(typ),
+		}
+		for _, name := range item.Instances {
+			stats, err := plugin.Stats(typ, name)
+			if err != nil {
+				stats = err.Error()
+			}
+			if stats == nil {
+				continue
+			}
+			if item.Stats == nil {
+				item.Stats = map[string]any{}
+			}
+			item.Stats[name] = stats
+		}
+		plugins = append(plugins, item)
+	}
+	writeJSON(w, plugins)
+}
+
+// adminConfig 输出合并后生效的配置，敏感字段替换成******
+func (s *Server) adminConfig(w http.ResponseWriter, r *http.Request) {
+	buffer, err := marshalConfig(s.config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(buffer)
+}
+
+func marshalConfig(config *Config) ([]byte, error) {
+	// 先序列化再解析成新的节点，修改时不会影响插件配置中共用的节点
+	buffer, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(buffer, node); err != nil {
+		return nil, err
+	}
+	redact(node)
+	return yaml.Marshal(node)
+}
+
+func redact(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			redact(child)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if isSensitive(key.Value) {
+				*value = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redacted}
+				continue
+			}
+			redact(value)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			redact(node.Alias)
+		}
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminLogLevel GET查看日志级别，POST level=info修改日志级别，logger=frame修改框架日志，默认修改业务日志
+func (s *Server) adminLogLevel(w http.ResponseWriter, r *http.Request) {
+	var logger pkglog.Logger
+	switch name := r.FormValue("logger"); name {
+	case "", "default":
+		logger = pkglog.DefaultLogger
+	case "frame":
+		logger = log.DefaultLogger
+	default:
+		http.Error(w, "logger["+name+"] not support", http.StatusBadRequest)
+		return
+	}
+	setter, ok := logger.(pkglog.LevelSetter)
+	if !ok {
+		http.Error(w, "logger not support level", http.StatusNotImplemented)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		if !s.authorized(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if err := setter.SetLevel(r.FormValue("level")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.DefaultLogger.InfoFields("admin set log level", zap.String("logger", r.FormValue("logger")), zap.String("level", r.FormValue("level")), zap.String("remote_address", r.RemoteAddr))
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, setter.Levels())
+}
+
+// adminShutdown 和收到退出信号一样优雅退出
+func (s *Server) adminShutdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	log.DefaultLogger.InfoFields("admin shutdown", zap.String("remote_address", r.RemoteAddr))
+	select {
+	case s.shutdown <- struct{}{}:
+	default:
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authorized 修改状态的接口校验admin_token，没有配置admin_token时只允许本机访问，pprof端口可能监听在0.0.0.0
+func (s *Server) authorized(r *http.Request) bool {
+	if s.adminToken == "" {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// adminMetrics 以Prometheus文本格式输出指标，供Prometheus直接抓取
+func (s *Server) adminMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.WriteText(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	buffer, err := jsoniter.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buffer)
+}
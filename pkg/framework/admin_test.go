@@ -0,0 +1,113 @@
+package framework
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	pkglog "github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	plugins := plugin.Config{}
+	if err := yaml.Unmarshal([]byte("database:\n    orders:\n        dsn: root:123456@tcp(127.0.0.1:3306)/test\n        max_open_conns: 10\n"), &plugins); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	log.DefaultLogger = pkglog.DefaultLogger
+	rpcService := newService("rpc_service", "127.0.0.1:6666", "tcp", "rpc", time.Second, nil)
+	_ = rpcService.register("Echo", nil)
+	_ = rpcService.register("Add", nil)
+	return &Server{
+		services: map[string]*service{rpcService.name: rpcService},
+		config:   &Config{Plugins: plugins},
+		shutdown: make(chan struct{}, 1),
+	}
+}
+
+func TestAdminServices(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.adminServices(w, httptest.NewRequest(http.MethodGet, "/admin/services", nil))
+	if body := w.Body.String(); !strings.Contains(body, `"rpcs":["Add","Echo"]`) {
+		t.Errorf("adminServices() = %s", body)
+	}
+}
+
+func TestAdminConfig(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.adminConfig(w, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+	body := w.Body.String()
+	if strings.Contains(body, "123456") || !strings.Contains(body, redacted) || !strings.Contains(body, "max_open_conns: 10") {
+		t.Errorf("adminConfig() = %s", body)
+	}
+	node := s.config.Plugins["database"]["orders"]
+	if buffer, _ := yaml.Marshal(&node); !strings.Contains(string(buffer), "123456") {
+		t.Errorf("adminConfig() modified config: %s", buffer)
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	s := newTestServer(t)
+	metrics.GetCounter(metrics.Name("admin_test_total", "rpc", "Echo")).Inc()
+	w := httptest.NewRecorder()
+	s.adminMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if body := w.Body.String(); !strings.Contains(body, `admin_test_total{rpc="Echo"} 1`) {
+		t.Errorf("adminMetrics() = %s", body)
+	}
+}
+
+func TestAdminShutdown(t *testing.T) {
+	s := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.adminShutdown(w, httptest.NewRequest(http.MethodGet, "/admin/shutdown", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("adminShutdown() GET code = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		remoteAddress string
+		authorization string
+		want          int
+	}{
+		{name: "remote without token", remoteAddress: "192.0.2.1:1234", want: http.StatusForbidden},
+		{name: "loopback without token", remoteAddress: "127.0.0.1:1234", want: http.StatusAccepted},
+		{name: "wrong token", token: "secret", remoteAddress: "127.0.0.1:1234", authorization: "Bearer wrong", want: http.StatusForbidden},
+		{name: "missing token", token: "secret", remoteAddress: "127.0.0.1:1234", want: http.StatusForbidden},
+		{name: "remote with token", token: "secret", remoteAddress: "192.0.2.1:1234", authorization: "Bearer secret", want: http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.adminToken = tt.token
+			r := httptest.NewRequest(http.MethodPost, "/admin/shutdown", nil)
+			r.RemoteAddr = tt.remoteAddress
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			s.adminShutdown(w, r)
+			if w.Code != tt.want {
+				t.Errorf("adminShutdown() POST code = %d, want %d", w.Code, tt.want)
+			}
+			select {
+			case <-s.shutdown:
+				if tt.want != http.StatusAccepted {
+					t.Error("adminShutdown() triggered without authorization")
+				}
+			default:
+				if tt.want == http.StatusAccepted {
+					t.Error("adminShutdown() not triggered")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+)
+
+const (
+	RoundRobin     = "round_robin"
+	WeightedRandom = "weighted_random"
+	LeastInFlight  = "least_in_flight"
+	P2C            = "p2c"
+	ConsistentHash = "consistent_hash"
+
+	defaultWeight = 100
+)
+
+var (
+	ErrNoAvailableNode = errors.New("no available node")
+
+	builders = map[string]Builder{}
+	mutex    = sync.RWMutex{}
+)
+
+func init() {
+	Register(RoundRobin, newRoundRobin)
+	Register(WeightedRandom, newWeightedRandom)
+	Register(LeastInFlight, newLeastInFlight)
+	Register(P2C, newP2C)
+	Register(ConsistentHash, newConsistentHash)
+}
+
+// Done 调用结束后回调，用于统计正在进行中的请求
+type Done func(err error)
+
+func noopDone(err error) {}
+
+type Balancer interface {
+	Pick(ctx context.Context, nodes []*registry.Node) (*registry.Node, Done, error)
+}
+
+type Builder func(opts ...Option) Balancer
+
+func Register(name string, builder Builder) {
+	value := reflect.ValueOf(builder)
+	if builder == nil || value.Kind() == reflect.Pointer && value.IsNil() {
+		panic("register nil balancer")
+	}
+	if name == "" {
+		panic("register empty name of balancer")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	builders[name] = builder
+}
+
+// New 每个目标服务使用独立的Balancer，避免不同服务之间的状态互相干扰
+func New(name string, opts ...Option) (Balancer, error) {
+	mutex.RLock()
+	builder, ok := builders[name]
+	mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("balancer[%s] not support", name)
+	}
+	return builder(opts...), nil
+}
+
+func weight(node *registry.Node) int {
+	if node.Weight <= 0 {
+		return defaultWeight
+	}
+	return node.Weight
+}
@@ -0,0 +1,115 @@
+package balancer
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+)
+
+func newNodes(weights ...int) []*registry.Node {
+	nodes := make([]*registry.Node, 0, len(weights))
+	for i, weight := range weights {
+		nodes = append(nodes, &registry.Node{
+			Name:    "cache_service",
+			Address: "127.0.0.1:" + strconv.Itoa(6000+i),
+			Network: "tcp",
+			Weight:  weight,
+		})
+	}
+	return nodes
+}
+
+func TestRoundRobin(t *testing.T) {
+	b, err := New(RoundRobin)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	nodes := newNodes(100, 100, 100)
+	for i := range 6 {
+		node, done, err := b.Pick(context.Background(), nodes)
+		if err != nil {
+			t.Fatalf("Pick: %v", err)
+		}
+		done(nil)
+		if node != nodes[i%len(nodes)] {
+			t.Errorf("Pick() = %v, want %v", node.Address, nodes[i%len(nodes)].Address)
+		}
+	}
+}
+
+func TestWeightedRandom(t *testing.T) {
+	b, _ := New(WeightedRandom)
+	nodes := newNodes(900, 100)
+	counts := map[string]int{}
+	for range 10000 {
+		node, _, _ := b.Pick(context.Background(), nodes)
+		counts[node.Address]++
+	}
+	if counts[nodes[0].Address] < 8500 || counts[nodes[0].Address] > 9500 {
+		t.Errorf("weighted random counts = %v", counts)
+	}
+}
+
+func TestLeastInFlight(t *testing.T) {
+	for _, name := range []string{LeastInFlight, P2C} {
+		t.Run(name, func(t *testing.T) {
+			b, _ := New(name)
+			nodes := newNodes(100, 100)
+			busy, _, _ := b.Pick(context.Background(), nodes)
+			// busy实例有一个请求未结束，后续请求都应该落到另一个实例
+			for range 10 {
+				node, done, _ := b.Pick(context.Background(), nodes)
+				if node == busy {
+					t.Fatalf("Pick() = busy node %v", node.Address)
+				}
+				done(nil)
+				done(nil)
+			}
+		})
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	b, _ := New(ConsistentHash)
+	nodes := newNodes(100, 100, 100, 100)
+	picked := map[string]string{}
+	for i := range 1000 {
+		key := "key_" + strconv.Itoa(i)
+		ctx := metadata.NewContext(context.Background(), metadata.MD{defaultHashKey: key})
+		node, _, _ := b.Pick(ctx, nodes)
+		again, _, _ := b.Pick(ctx, nodes)
+		if node != again {
+			t.Fatalf("Pick(%s) not stable: %v != %v", key, node.Address, again.Address)
+		}
+		picked[key] = node.Address
+	}
+
+	// 摘掉一个实例，只有落在该实例上的key会迁移
+	removed := nodes[0].Address
+	moved := 0
+	for key, address := range picked {
+		ctx := metadata.NewContext(context.Background(), metadata.MD{defaultHashKey: key})
+		node, _, _ := b.Pick(ctx, nodes[1:])
+		if node.Address != address {
+			if address != removed {
+				t.Fatalf("key %s moved from %s to %s", key, address, node.Address)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 500 {
+		t.Errorf("moved keys = %d", moved)
+	}
+}
+
+func TestNoAvailableNode(t *testing.T) {
+	for _, name := range []string{RoundRobin, WeightedRandom, LeastInFlight, P2C, ConsistentHash} {
+		b, _ := New(name)
+		if _, _, err := b.Pick(context.Background(), nil); err != ErrNoAvailableNode {
+			t.Errorf("%s Pick() error = %v, want ErrNoAvailableNode", name, err)
+		}
+	}
+}
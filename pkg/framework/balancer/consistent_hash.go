@@ -0,0 +1,107 @@
+package balancer
+
+import (
+	"context"
+	"hash/crc32"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+)
+
+// consistentHash 按请求元数据中的key选择实例，实例变化时只有少量key会迁移，适合分片缓存保持数据局部性
+type consistentHash struct {
+	opts *Options
+
+	mutex       sync.RWMutex
+	fingerprint string
+	ring        *hashRing
+}
+
+func newConsistentHash(opts ...Option) Balancer {
+	return &consistentHash{
+		opts: newOptions(opts...),
+	}
+}
+
+func (b *consistentHash) Pick(ctx context.Context, nodes []*registry.Node) (*registry.Node, Done, error) {
+	if len(nodes) == 0 {
+		return nil, nil, ErrNoAvailableNode
+	}
+	key := metadata.FromContext(ctx).Get(b.opts.HashKey)
+	// 没有key无法保持局部性，退化为随机
+	if key == "" {
+		return nodes[rand.IntN(len(nodes))], noopDone, nil
+	}
+	return b.getRing(nodes).get(key), noopDone, nil
+}
+
+func (b *consistentHash) getRing(nodes []*registry.Node) *hashRing {
+	fingerprint := nodesFingerprint(nodes)
+	b.mutex.RLock()
+	if b.ring != nil && b.fingerprint == fingerprint {
+		ring := b.ring
+		b.mutex.RUnlock()
+		return ring
+	}
+	b.mutex.RUnlock()
+
+	ring := newHashRing(nodes, b.opts.Replicas)
+	b.mutex.Lock()
+	b.fingerprint = fingerprint
+	b.ring = ring
+	b.mutex.Unlock()
+	return ring
+}
+
+func nodesFingerprint(nodes []*registry.Node) string {
+	keys := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		keys = append(keys, node.Address+"/"+strconv.Itoa(weight(node)))
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ",")
+}
+
+type hashRing struct {
+	hashes []uint32
+	nodes  map[uint32]*registry.Node
+}
+
+func newHashRing(nodes []*registry.Node, replicas int) *hashRing {
+	ring := &hashRing{
+		nodes: make(map[uint32]*registry.Node),
+	}
+	for _, node := range nodes {
+		n := max(replicas*weight(node)/defaultWeight, 1)
+		for i := range n {
+			hash := crc32.ChecksumIEEE([]byte(node.Address + "#" + strconv.Itoa(i)))
+			// 哈希冲突时保留地址小的，保证不同进程构建出相同的环
+			if exist, ok := ring.nodes[hash]; ok && exist.Address < node.Address {
+				continue
+			}
+			if _, ok := ring.nodes[hash]; !ok {
+				ring.hashes = append(ring.hashes, hash)
+			}
+			ring.nodes[hash] = node
+		}
+	}
+	slices.Sort(ring.hashes)
+	return ring
+}
+
+func (r *hashRing) get(key string) *registry.Node {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	index := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= hash
+	})
+	if index == len(r.hashes) {
+		index = 0
+	}
+	return r.nodes[r.hashes[index]]
+}
@@ -0,0 +1,92 @@
+package balancer
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+)
+
+// inFlight 统计每个地址正在进行中的请求数
+type inFlight struct {
+	counters sync.Map // k=address,v=*atomic.Int64
+}
+
+func (f *inFlight) counter(address string) *atomic.Int64 {
+	if value, ok := f.counters.Load(address); ok {
+		return value.(*atomic.Int64)
+	}
+	value, _ := f.counters.LoadOrStore(address, &atomic.Int64{})
+	return value.(*atomic.Int64)
+}
+
+func (f *inFlight) acquire(node *registry.Node) Done {
+	counter := f.counter(node.Address)
+	counter.Add(1)
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			counter.Add(-1)
+		})
+	}
+}
+
+// load 按权重归一化，权重越大能承受的并发越多
+func (f *inFlight) load(node *registry.Node) float64 {
+	return float64(f.counter(node.Address).Load()+1) / float64(weight(node))
+}
+
+type leastInFlight struct {
+	inFlight
+}
+
+func newLeastInFlight(opts ...Option) Balancer {
+	return &leastInFlight{}
+}
+
+func (b *leastInFlight) Pick(ctx context.Context, nodes []*registry.Node) (*registry.Node, Done, error) {
+	if len(nodes) == 0 {
+		return nil, nil, ErrNoAvailableNode
+	}
+	// 从随机位置开始遍历，负载相同时打散到不同实例
+	start := rand.IntN(len(nodes))
+	var picked *registry.Node
+	minLoad := 0.0
+	for i := range nodes {
+		node := nodes[(start+i)%len(nodes)]
+		if load := b.load(node); picked == nil || load < minLoad {
+			picked, minLoad = node, load
+		}
+	}
+	return picked, b.acquire(picked), nil
+}
+
+// p2c power of two choices，随机选两个实例取负载低的一个
+type p2c struct {
+	inFlight
+}
+
+func newP2C(opts ...Option) Balancer {
+	return &p2c{}
+}
+
+func (b *p2c) Pick(ctx context.Context, nodes []*registry.Node) (*registry.Node, Done, error) {
+	if len(nodes) == 0 {
+		return nil, nil, ErrNoAvailableNode
+	}
+	if len(nodes) == 1 {
+		return nodes[0], b.acquire(nodes[0]), nil
+	}
+	i := rand.IntN(len(nodes))
+	j := rand.IntN(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+	picked := nodes[i]
+	if b.load(nodes[j]) < b.load(picked) {
+		picked = nodes[j]
+	}
+	return picked, b.acquire(picked), nil
+}
@@ -0,0 +1,36 @@
+package balancer
+
+const (
+	defaultHashKey  = "hash_key"
+	defaultReplicas = 160
+)
+
+type Options struct {
+	HashKey  string // 一致性哈希从请求元数据中取key的字段名
+	Replicas int    // 一致性哈希每个权重为100的实例的虚拟节点数
+}
+
+type Option func(*Options)
+
+func WithHashKey(hashKey string) Option {
+	return func(o *Options) {
+		o.HashKey = hashKey
+	}
+}
+
+func WithReplicas(replicas int) Option {
+	return func(o *Options) {
+		o.Replicas = replicas
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	o := &Options{
+		HashKey:  defaultHashKey,
+		Replicas: defaultReplicas,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,49 @@
+package balancer
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+)
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func newRoundRobin(opts ...Option) Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(ctx context.Context, nodes []*registry.Node) (*registry.Node, Done, error) {
+	if len(nodes) == 0 {
+		return nil, nil, ErrNoAvailableNode
+	}
+	index := (b.next.Add(1) - 1) % uint64(len(nodes))
+	return nodes[index], noopDone, nil
+}
+
+type weightedRandom struct{}
+
+func newWeightedRandom(opts ...Option) Balancer {
+	return &weightedRandom{}
+}
+
+func (b *weightedRandom) Pick(ctx context.Context, nodes []*registry.Node) (*registry.Node, Done, error) {
+	if len(nodes) == 0 {
+		return nil, nil, ErrNoAvailableNode
+	}
+	total := 0
+	for _, node := range nodes {
+		total += weight(node)
+	}
+	n := rand.IntN(total)
+	for _, node := range nodes {
+		n -= weight(node)
+		if n < 0 {
+			return node, noopDone, nil
+		}
+	}
+	return nodes[len(nodes)-1], noopDone, nil
+}
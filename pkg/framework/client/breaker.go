@@ -0,0 +1,123 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker 关闭时统计最近WindowSize次调用的失败率，达到阈值后熔断，
+// 熔断OpenTimeout后进入半开，放行HalfOpenMaxRequests个探测请求，全部成功恢复，任意失败重新熔断
+type circuitBreaker struct {
+	config   *CircuitBreakerConfig
+	onChange func(from, to breakerState)
+
+	mutex     sync.Mutex
+	state     breakerState
+	results   []bool // 环形窗口，true表示失败
+	index     int
+	total     int
+	failures  int
+	openedAt  time.Time
+	probing   int // 半开时正在进行中的探测请求数
+	successes int // 半开时探测成功的请求数
+}
+
+func newCircuitBreaker(config *CircuitBreakerConfig, onChange func(from, to breakerState)) *circuitBreaker {
+	return &circuitBreaker{
+		config:   config,
+		onChange: onChange,
+		results:  make([]bool, config.WindowSize),
+	}
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < time.Duration(b.config.OpenTimeout)*time.Millisecond {
+			return false
+		}
+		b.transition(stateHalfOpen)
+		fallthrough
+	case stateHalfOpen:
+		if b.probing+b.successes >= b.config.HalfOpenMaxRequests {
+			return false
+		}
+		b.probing++
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) record(failure bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch b.state {
+	case stateHalfOpen:
+		if b.probing > 0 {
+			b.probing--
+		}
+		if failure {
+			b.transition(stateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenMaxRequests {
+			b.transition(stateClosed)
+		}
+	case stateClosed:
+		if b.total == len(b.results) {
+			if b.results[b.index] {
+				b.failures--
+			}
+		} else {
+			b.total++
+		}
+		b.results[b.index] = failure
+		b.index = (b.index + 1) % len(b.results)
+		if failure {
+			b.failures++
+		}
+		if b.total >= b.config.MinRequests && float64(b.failures)/float64(b.total) >= b.config.FailureRatio {
+			b.transition(stateOpen)
+		}
+	}
+}
+
+func (b *circuitBreaker) transition(to breakerState) {
+	from := b.state
+	b.state = to
+	b.probing = 0
+	b.successes = 0
+	switch to {
+	case stateOpen:
+		b.openedAt = time.Now()
+	case stateClosed:
+		clear(b.results)
+		b.index, b.total, b.failures = 0, 0, 0
+	}
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/balancer"
+	"github.com/soulnov23/go-tool/pkg/framework/codec"
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+	"github.com/soulnov23/go-tool/pkg/framework/transport"
+	"github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+	"github.com/soulnov23/go-tool/pkg/tracing"
+	"github.com/soulnov23/go-tool/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const defaultNetwork = "tcp"
+
+type Client struct {
+	opts *Options
+
+	mutex      sync.Mutex
+	transports map[string]transport.ClientTransport // k=network,v=ClientTransport
+	policies   map[string]*policy                   // k=service_name,v=policy
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		opts: &Options{
+			BalancerName: balancer.RoundRobin,
+			Services:     make(map[string]*ServiceConfig),
+			Logger:       log.DefaultLogger,
+		},
+		transports: make(map[string]transport.ClientTransport),
+		policies:   make(map[string]*policy),
+	}
+	for _, opt := range opts {
+		opt(c.opts)
+	}
+	return c
+}
+
+// Invoke 调用目标服务，幂等的rpc按配置重试和对冲，每次调用结果计入熔断统计
+func (c *Client) Invoke(ctx context.Context, serviceName string, rpcName string, request string) (string, error) {
+	p, err := c.policy(serviceName)
+	if err != nil {
+		return "", err
+	}
+	metrics.GetCounter(metrics.Name("client_requests_total", "service", serviceName, "rpc", rpcName)).Inc()
+	maxAttempts := p.maxAttempts(rpcName)
+	for attempt := 1; ; attempt++ {
+		if !p.allow(c.opts.Logger, rpcName) {
+			return "", ErrCircuitBreakerOpen.Clone().WithMessageValues(serviceName)
+		}
+		response, err := c.hedge(ctx, p, rpcName, request)
+		p.record(err)
+		if err == nil {
+			return response, nil
+		}
+		metrics.GetCounter(metrics.Name("client_errors_total", "service", serviceName, "rpc", rpcName)).Inc()
+		if attempt >= maxAttempts || !p.retryable(err) {
+			return "", err
+		}
+		backoff := p.backoff(attempt)
+		metrics.GetCounter(metrics.Name("client_retry_total", "service", serviceName, "rpc", rpcName)).Inc()
+		tracing.Logger(ctx, c.opts.Logger).WarnFields("client retry", zap.String("service", serviceName), zap.String("rpc", rpcName),
+			zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+		if err := sleep(ctx, backoff); err != nil {
+			return "", classify(err)
+		}
+	}
+}
+
+type result struct {
+	response string
+	err      error
+}
+
+// hedge 超过历史耗时分位数仍未返回时向另一个实例发起相同的请求，取最先成功的结果
+func (c *Client) hedge(ctx context.Context, p *policy, rpcName string, request string) (string, error) {
+	delay := p.hedgeDelay(rpcName)
+	if delay <= 0 {
+		return c.invoke(ctx, p, rpcName, request)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan *result, p.config.Hedging.MaxHedges+1)
+	launch := func() {
+		go func() {
+			response, err := c.invoke(ctx, p, rpcName, request)
+			results <- &result{response: response, err: err}
+		}()
+	}
+	launch()
+	inFlight, hedges := 1, 0
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil || inFlight == 0 {
+				return r.response, r.err
+			}
+		case <-timer.C:
+			if hedges >= p.config.Hedging.MaxHedges {
+				continue
+			}
+			hedges++
+			inFlight++
+			metrics.GetCounter(metrics.Name("client_hedge_total", "service", p.name, "rpc", rpcName)).Inc()
+			tracing.Logger(ctx, c.opts.Logger).InfoFields("client hedge", zap.String("service", p.name), zap.String("rpc", rpcName),
+				zap.Int("hedge", hedges), zap.Duration("delay", delay))
+			launch()
+			timer.Reset(delay)
+		}
+	}
+}
+
+// invoke 单次调用，跳过连接池不健康的实例后由Balancer选择一个实例，未指定Discovery时使用registry.DefaultDiscovery
+func (c *Client) invoke(ctx context.Context, p *policy, rpcName string, request string) (response string, err error) {
+	discovery := c.opts.Discovery
+	if discovery == nil {
+		discovery = registry.DefaultDiscovery
+	}
+	nodes, err := discovery.Resolve(ctx, p.name)
+	if err != nil {
+		return "", fmt.Errorf("discovery.Resolve: %w", err)
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("service[%s]: %w", p.name, registry.ErrNotFound)
+	}
+	nodes = c.healthyNodes(nodes)
+	node, done, err := p.balancer.Pick(ctx, nodes)
+	if err != nil {
+		return "", fmt.Errorf("service[%s] balancer.Pick: %w", p.name, err)
+	}
+	defer func() {
+		done(err)
+	}()
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	md := metadata.FromContext(ctx).Clone()
+	ctx, span := tracing.DefaultTracer.StartClientSpan(ctx, p.name+"/"+rpcName, md)
+	defer span.End()
+	span.SetAttribute("rpc.service", p.name)
+	span.SetAttribute("rpc.method", rpcName)
+	span.SetAttribute("net.peer.name", node.Address)
+	ctx = metadata.NewContext(ctx, md)
+
+	clientTransport, err := c.transport(node.Network)
+	if err != nil {
+		span.SetError(err)
+		return "", err
+	}
+	// 请求帧带上rpc名称和元数据，服务端从元数据中提取traceparent
+	frame := codec.EncodeRequest(&codec.Request{RPCName: rpcName, Metadata: md, Body: utils.StringToBytes(request)})
+	begin := time.Now()
+	var reply *codec.Response
+	buf, err := clientTransport.RoundTrip(ctx, node.Address, frame)
+	if err == nil {
+		reply, err = codec.DecodeResponse(buf)
+	}
+	if err != nil {
+		e := classify(fmt.Errorf("service[%s] rpc[%s] address[%s]: %w", p.name, rpcName, node.Address, err))
+		span.SetError(e)
+		return "", e
+	}
+	p.latency.Observe(float64(time.Since(begin).Microseconds()) / 1e3)
+	if reply.Error != "" {
+		// handler返回的错误，服务端已经转换成errors.Error
+		e := errors.Parse(reply.Error)
+		if e == nil {
+			e = ErrServiceUnavailable.Clone().WithMessageValues(reply.Error)
+		}
+		span.SetError(e)
+		return "", e
+	}
+	return string(reply.Body), nil
+}
+
+// healthyNodes 全部不健康时返回原列表，避免没有实例可选
+func (c *Client) healthyNodes(nodes []*registry.Node) []*registry.Node {
+	healthy := make([]*registry.Node, 0, len(nodes))
+	for _, node := range nodes {
+		clientTransport, err := c.transport(node.Network)
+		if err != nil || clientTransport.Healthy(node.Address) {
+			healthy = append(healthy, node)
+		}
+	}
+	if len(healthy) == 0 {
+		return nodes
+	}
+	return healthy
+}
+
+func (c *Client) policy(serviceName string) (*policy, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	p, ok := c.policies[serviceName]
+	if !ok {
+		config, ok := c.opts.Services[serviceName]
+		if !ok {
+			config = &ServiceConfig{Name: serviceName}
+		}
+		var err error
+		p, err = newPolicy(serviceName, config, c.opts)
+		if err != nil {
+			return nil, err
+		}
+		c.policies[serviceName] = p
+	}
+	return p, nil
+}
+
+func (c *Client) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for network, clientTransport := range c.transports {
+		clientTransport.Close()
+		delete(c.transports, network)
+	}
+}
+
+func (c *Client) transport(network string) (transport.ClientTransport, error) {
+	if network == "" {
+		network = defaultNetwork
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	clientTransport, ok := c.transports[network]
+	if !ok {
+		clientTransport = transport.NewClientTransport(network)
+		if clientTransport == nil {
+			return nil, fmt.Errorf("network[%s] not support", network)
+		}
+		c.transports[network] = clientTransport
+	}
+	return clientTransport, nil
+}
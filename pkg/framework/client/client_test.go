@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/codec"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+	"github.com/soulnov23/go-tool/pkg/framework/transport"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+)
+
+// newEchoServer 第一个连接的请求延迟delay后返回，用于验证对冲
+func newEchoServer(t *testing.T, delay time.Duration) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var conns atomic.Int64
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			first := conns.Add(1) == 1
+			go func() {
+				defer conn.Close()
+				for {
+					frame, err := transport.ReadFrame(conn, transport.DefaultMaxFrameSize)
+					if err != nil {
+						return
+					}
+					request, err := codec.DecodeRequest(frame)
+					if err != nil {
+						return
+					}
+					if first {
+						time.Sleep(delay)
+					}
+					if err := transport.WriteFrame(conn, codec.EncodeResponse(&codec.Response{Body: request.Body})); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// deadAddress 返回一个没有监听的地址
+func deadAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func newDiscovery(name string, addresses ...string) registry.Discovery {
+	nodes := make([]*registry.Node, 0, len(addresses))
+	for _, address := range addresses {
+		nodes = append(nodes, &registry.Node{Address: address, Network: "tcp"})
+	}
+	return registry.NewStatic(map[string][]*registry.Node{name: nodes})
+}
+
+func TestInvoke(t *testing.T) {
+	c := New(WithDiscovery(newDiscovery("echo_service", newEchoServer(t, 0))), WithTimeout(time.Second))
+	defer c.Close()
+	response, err := c.Invoke(context.Background(), "echo_service", "Echo", "hello world")
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if response != "hello world" {
+		t.Errorf("Invoke() = %v, want hello world", response)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	c := New(
+		WithDiscovery(newDiscovery("retry_service", deadAddress(t))),
+		WithServiceConfig(&ServiceConfig{
+			Name:           "retry_service",
+			IdempotentRPCs: []string{"Get"},
+			Retry:          &RetryConfig{MaxAttempts: 3, InitialBackoff: 1, MaxBackoff: 2},
+		}),
+	)
+	defer c.Close()
+
+	_, err := c.Invoke(context.Background(), "retry_service", "Get", "")
+	if e := errors.FromError(err); e == nil || e.Name != ErrServiceUnavailable.Name {
+		t.Fatalf("Invoke() error = %v, want ServiceUnavailable", err)
+	}
+	if got := metrics.GetCounter(metrics.Name("client_retry_total", "service", "retry_service", "rpc", "Get")).Value(); got != 2 {
+		t.Errorf("retry count = %d, want 2", got)
+	}
+
+	// 非幂等的rpc不重试
+	_, _ = c.Invoke(context.Background(), "retry_service", "Set", "")
+	if got := metrics.GetCounter(metrics.Name("client_retry_total", "service", "retry_service", "rpc", "Set")).Value(); got != 0 {
+		t.Errorf("retry count = %d, want 0", got)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	c := New(
+		WithDiscovery(newDiscovery("breaker_service", deadAddress(t))),
+		WithServiceConfig(&ServiceConfig{
+			Name:           "breaker_service",
+			CircuitBreaker: &CircuitBreakerConfig{WindowSize: 10, MinRequests: 3, FailureRatio: 0.5, OpenTimeout: 50},
+		}),
+	)
+	defer c.Close()
+	for range 3 {
+		_, _ = c.Invoke(context.Background(), "breaker_service", "Get", "")
+	}
+	_, err := c.Invoke(context.Background(), "breaker_service", "Get", "")
+	if e := errors.FromError(err); e == nil || e.Name != ErrCircuitBreakerOpen.Name {
+		t.Fatalf("Invoke() error = %v, want CircuitBreakerOpen", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	var changes []string
+	b := newCircuitBreaker(&CircuitBreakerConfig{WindowSize: 4, MinRequests: 2, FailureRatio: 0.5, OpenTimeout: 10, HalfOpenMaxRequests: 2},
+		func(from, to breakerState) { changes = append(changes, to.String()) })
+	b.record(false)
+	b.record(true)
+	if b.allow() {
+		t.Fatal("allow() = true after open")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !b.allow() || !b.allow() || b.allow() {
+		t.Fatal("half open should allow exactly 2 probes")
+	}
+	b.record(false)
+	b.record(true)
+	time.Sleep(20 * time.Millisecond)
+	b.allow()
+	b.allow()
+	b.record(false)
+	b.record(false)
+	if !b.allow() {
+		t.Fatal("allow() = false after closed")
+	}
+	want := []string{"open", "half_open", "open", "half_open", "closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("state changes = %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestHedging(t *testing.T) {
+	address := newEchoServer(t, 500*time.Millisecond)
+	c := New(
+		WithDiscovery(newDiscovery("hedge_service", address)),
+		WithServiceConfig(&ServiceConfig{
+			Name:           "hedge_service",
+			IdempotentRPCs: []string{"Get"},
+			Hedging:        &HedgingConfig{Percentile: 99, MaxHedges: 1, MinSamples: 10},
+		}),
+	)
+	defer c.Close()
+	p, _ := c.policy("hedge_service")
+	for range 10 {
+		p.latency.Observe(1)
+	}
+
+	begin := time.Now()
+	response, err := c.Invoke(context.Background(), "hedge_service", "Get", "hello")
+	if err != nil || response != "hello" {
+		t.Fatalf("Invoke() = %v, %v", response, err)
+	}
+	if elapsed := time.Since(begin); elapsed > 300*time.Millisecond {
+		t.Errorf("hedged Invoke() elapsed = %v", elapsed)
+	}
+	if got := metrics.GetCounter(metrics.Name("client_hedge_total", "service", "hedge_service", "rpc", "Get")).Value(); got != 1 {
+		t.Errorf("hedge count = %d, want 1", got)
+	}
+}
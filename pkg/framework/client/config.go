@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+const pluginType = "client"
+
+// DefaultClient 由client插件的default实例创建，未配置时使用默认策略
+var DefaultClient = New()
+
+func init() {
+	plugin.Register(pluginType, &ClientPlugin{})
+}
+
+type Config struct {
+	Timeout  int64            `yaml:"timeout"`  // 单次调用超时时间 单位 毫秒
+	Balancer string           `yaml:"balancer"` // 默认负载均衡策略
+	Services []*ServiceConfig `yaml:"services"` // 按目标服务配置
+}
+
+type ServiceConfig struct {
+	Name           string                `yaml:"name"`
+	Timeout        int64                 `yaml:"timeout"`         // 单位 毫秒
+	Balancer       string                `yaml:"balancer"`        // round_robin weighted_random least_in_flight p2c consistent_hash
+	HashKey        string                `yaml:"hash_key"`        // consistent_hash从请求元数据中取key的字段名
+	IdempotentRPCs []string              `yaml:"idempotent_rpcs"` // 只有幂等的rpc才会重试和对冲
+	Retry          *RetryConfig          `yaml:"retry"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker"`
+	Hedging        *HedgingConfig        `yaml:"hedging"`
+}
+
+type RetryConfig struct {
+	MaxAttempts    int     `yaml:"max_attempts"`    // 包含第一次调用的总次数
+	InitialBackoff int64   `yaml:"initial_backoff"` // 单位 毫秒
+	MaxBackoff     int64   `yaml:"max_backoff"`     // 单位 毫秒
+	Multiplier     float64 `yaml:"multiplier"`      // 退避倍数
+	Jitter         float64 `yaml:"jitter"`          // 退避时间随机浮动比例 0~1
+	RetryableCodes []int32 `yaml:"retryable_codes"` // 可重试的errors.Error错误码
+}
+
+type CircuitBreakerConfig struct {
+	WindowSize          int     `yaml:"window_size"`            // 统计最近多少次调用
+	MinRequests         int     `yaml:"min_requests"`           // 窗口内至少多少次调用才计算失败率
+	FailureRatio        float64 `yaml:"failure_ratio"`          // 失败率达到多少熔断 0~1
+	OpenTimeout         int64   `yaml:"open_timeout"`           // 熔断多久后进入半开 单位 毫秒
+	HalfOpenMaxRequests int     `yaml:"half_open_max_requests"` // 半开时放行的探测请求数，全部成功才恢复
+}
+
+type HedgingConfig struct {
+	Percentile float64 `yaml:"percentile"`  // 超过历史耗时的该分位数仍未返回时发起对冲请求 0~100
+	MaxHedges  int     `yaml:"max_hedges"`  // 最多额外发起的请求数
+	MinSamples uint64  `yaml:"min_samples"` // 耗时样本数不足时不对冲
+}
+
+const (
+	defaultRetryMaxAttempts           = 3
+	defaultRetryInitialBackoff        = 10
+	defaultRetryMaxBackoff            = 1000
+	defaultRetryMultiplier            = 2
+	defaultRetryJitter                = 0.2
+	defaultBreakerWindowSize          = 100
+	defaultBreakerMinRequests         = 20
+	defaultBreakerFailureRatio        = 0.5
+	defaultBreakerOpenTimeout         = 5000
+	defaultBreakerHalfOpenMaxRequests = 1
+	defaultHedgingPercentile          = 95
+	defaultHedgingMaxHedges           = 1
+	defaultHedgingMinSamples          = 100
+)
+
+// setDefaults 未配置的字段使用默认值
+func (c *ServiceConfig) setDefaults() {
+	if retry := c.Retry; retry != nil {
+		if retry.MaxAttempts <= 0 {
+			retry.MaxAttempts = defaultRetryMaxAttempts
+		}
+		if retry.InitialBackoff <= 0 {
+			retry.InitialBackoff = defaultRetryInitialBackoff
+		}
+		if retry.MaxBackoff <= 0 {
+			retry.MaxBackoff = defaultRetryMaxBackoff
+		}
+		if retry.Multiplier < 1 {
+			retry.Multiplier = defaultRetryMultiplier
+		}
+		if retry.Jitter < 0 || retry.Jitter > 1 {
+			retry.Jitter = defaultRetryJitter
+		}
+		if len(retry.RetryableCodes) == 0 {
+			retry.RetryableCodes = []int32{ErrServiceUnavailable.Code}
+		}
+	}
+	if breaker := c.CircuitBreaker; breaker != nil {
+		if breaker.WindowSize <= 0 {
+			breaker.WindowSize = defaultBreakerWindowSize
+		}
+		if breaker.MinRequests <= 0 {
+			breaker.MinRequests = defaultBreakerMinRequests
+		}
+		if breaker.FailureRatio <= 0 || breaker.FailureRatio > 1 {
+			breaker.FailureRatio = defaultBreakerFailureRatio
+		}
+		if breaker.OpenTimeout <= 0 {
+			breaker.OpenTimeout = defaultBreakerOpenTimeout
+		}
+		if breaker.HalfOpenMaxRequests <= 0 {
+			breaker.HalfOpenMaxRequests = defaultBreakerHalfOpenMaxRequests
+		}
+	}
+	if hedging := c.Hedging; hedging != nil {
+		if hedging.Percentile <= 0 || hedging.Percentile >= 100 {
+			hedging.Percentile = defaultHedgingPercentile
+		}
+		if hedging.MaxHedges <= 0 {
+			hedging.MaxHedges = defaultHedgingMaxHedges
+		}
+		if hedging.MinSamples == 0 {
+			hedging.MinSamples = defaultHedgingMinSamples
+		}
+	}
+}
+
+type ClientPlugin struct{}
+
+func (p *ClientPlugin) Type() string {
+	return pluginType
+}
+
+func (p *ClientPlugin) DependsOn() []string {
+	return []string{log.PluginType}
+}
+
+// Stop 关闭实例的连接池
+func (p *ClientPlugin) Stop(ctx context.Context, name string) error {
+	client, err := plugin.Get[*Client](pluginType, name)
+	if err != nil {
+		return err
+	}
+	client.Close()
+	return nil
+}
+
+func (p *ClientPlugin) Setup(ctx context.Context, name string, node yaml.Node) (any, error) {
+	config := &Config{}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] invalid config: %v", pluginType, name, err)
+	}
+	var opts []Option
+	if config.Timeout > 0 {
+		opts = append(opts, WithTimeout(time.Duration(config.Timeout)*time.Millisecond))
+	}
+	if config.Balancer != "" {
+		opts = append(opts, WithBalancer(config.Balancer))
+	}
+	for _, serviceConfig := range config.Services {
+		if serviceConfig == nil || serviceConfig.Name == "" {
+			return nil, fmt.Errorf("plugin type[%s] name[%s] service name is empty", pluginType, name)
+		}
+		opts = append(opts, WithServiceConfig(serviceConfig))
+	}
+	opts = append(opts, WithLogger(log.FrameLogger()))
+	client := New(opts...)
+	if name == plugin.DefaultName {
+		DefaultClient = client
+	}
+	return client, nil
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+)
+
+var (
+	ErrServiceUnavailable = &errors.Error{
+		Code:    503,
+		Status:  "Service Unavailable",
+		Name:    "ServiceUnavailable",
+		Message: "{{.}}",
+	}
+	ErrGatewayTimeout = &errors.Error{
+		Code:    504,
+		Status:  "Gateway Timeout",
+		Name:    "GatewayTimeout",
+		Message: "{{.}}",
+	}
+	ErrCircuitBreakerOpen = &errors.Error{
+		Code:    503,
+		Status:  "Service Unavailable",
+		Name:    "CircuitBreakerOpen",
+		Message: "circuit breaker of service {{.}} is open",
+	}
+)
+
+// classify 把传输层错误转换成errors.Error，超时对应504，其它对应503
+func classify(err error) *errors.Error {
+	if e := errors.FromError(err); e != nil {
+		return e
+	}
+	var netErr net.Error
+	if stderrors.Is(err, context.DeadlineExceeded) || stderrors.As(err, &netErr) && netErr.Timeout() {
+		return ErrGatewayTimeout.Clone().WithMessageValues(err.Error())
+	}
+	return ErrServiceUnavailable.Clone().WithMessageValues(err.Error())
+}
+
+// isFailure 4xx是调用方的问题，不计入熔断的失败次数
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	e := errors.FromError(err)
+	return e == nil || e.Code >= 500
+}
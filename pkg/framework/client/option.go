@@ -0,0 +1,53 @@
+package client
+
+import (
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/framework/balancer"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+	"github.com/soulnov23/go-tool/pkg/log"
+)
+
+type Options struct {
+	Discovery       registry.Discovery
+	Timeout         time.Duration
+	BalancerName    string
+	BalancerOptions []balancer.Option
+	Services        map[string]*ServiceConfig // k=service_name,v=ServiceConfig
+	Logger          log.Logger
+}
+
+type Option func(*Options)
+
+func WithDiscovery(discovery registry.Discovery) Option {
+	return func(o *Options) {
+		o.Discovery = discovery
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+func WithBalancer(name string, opts ...balancer.Option) Option {
+	return func(o *Options) {
+		o.BalancerName = name
+		o.BalancerOptions = opts
+	}
+}
+
+// WithServiceConfig 按目标服务配置超时、负载均衡、重试、熔断和对冲
+func WithServiceConfig(config *ServiceConfig) Option {
+	return func(o *Options) {
+		config.setDefaults()
+		o.Services[config.Name] = config
+	}
+}
+
+func WithLogger(logger log.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/balancer"
+	"github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// policy 单个目标服务的调用策略
+type policy struct {
+	name     string
+	timeout  time.Duration
+	config   *ServiceConfig
+	balancer balancer.Balancer
+	breaker  *circuitBreaker
+	latency  *metrics.Histogram
+}
+
+func newPolicy(name string, config *ServiceConfig, opts *Options) (*policy, error) {
+	p := &policy{
+		name:    name,
+		timeout: opts.Timeout,
+		config:  config,
+		latency: metrics.GetHistogram(metrics.Name("client_latency_ms", "service", name), metrics.DefaultLatencyBounds),
+	}
+	balancerName, balancerOpts := opts.BalancerName, opts.BalancerOptions
+	if config.Timeout > 0 {
+		p.timeout = time.Duration(config.Timeout) * time.Millisecond
+	}
+	if config.Balancer != "" {
+		balancerName = config.Balancer
+	}
+	if config.HashKey != "" {
+		balancerOpts = append(slices.Clone(balancerOpts), balancer.WithHashKey(config.HashKey))
+	}
+	b, err := balancer.New(balancerName, balancerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	p.balancer = b
+	if config.CircuitBreaker != nil {
+		p.breaker = newCircuitBreaker(config.CircuitBreaker, func(from, to breakerState) {
+			metrics.GetCounter(metrics.Name("client_circuit_breaker_state_total", "service", name, "state", to.String())).Inc()
+			opts.Logger.WarnFields("client circuit breaker state change", zap.String("service", name), zap.String("from", from.String()), zap.String("to", to.String()))
+		})
+	}
+	return p, nil
+}
+
+func (p *policy) idempotent(rpcName string) bool {
+	return slices.Contains(p.config.IdempotentRPCs, rpcName)
+}
+
+func (p *policy) maxAttempts(rpcName string) int {
+	if p.config.Retry == nil || !p.idempotent(rpcName) {
+		return 1
+	}
+	return p.config.Retry.MaxAttempts
+}
+
+func (p *policy) retryable(err error) bool {
+	e := errors.FromError(err)
+	if e == nil || e.Name == ErrCircuitBreakerOpen.Name {
+		return false
+	}
+	return slices.Contains(p.config.Retry.RetryableCodes, e.Code)
+}
+
+// backoff 指数退避，attempt从1开始，在[1-jitter, 1+jitter]范围内随机浮动
+func (p *policy) backoff(attempt int) time.Duration {
+	retry := p.config.Retry
+	backoff := float64(retry.InitialBackoff) * math.Pow(retry.Multiplier, float64(attempt-1))
+	backoff = math.Min(backoff, float64(retry.MaxBackoff))
+	backoff *= 1 + retry.Jitter*(2*rand.Float64()-1)
+	return time.Duration(backoff * float64(time.Millisecond))
+}
+
+// hedgeDelay 返回0表示不对冲
+func (p *policy) hedgeDelay(rpcName string) time.Duration {
+	hedging := p.config.Hedging
+	if hedging == nil || !p.idempotent(rpcName) {
+		return 0
+	}
+	snapshot := p.latency.Snapshot()
+	if snapshot.Count < hedging.MinSamples {
+		return 0
+	}
+	return time.Duration(snapshot.Quantile(hedging.Percentile/100) * float64(time.Millisecond))
+}
+
+func (p *policy) allow(logger log.Logger, rpcName string) bool {
+	if p.breaker == nil || p.breaker.allow() {
+		return true
+	}
+	metrics.GetCounter(metrics.Name("client_circuit_breaker_reject_total", "service", p.name, "rpc", rpcName)).Inc()
+	logger.WarnFields("client circuit breaker reject", zap.String("service", p.name), zap.String("rpc", rpcName))
+	return false
+}
+
+func (p *policy) record(err error) {
+	if p.breaker == nil {
+		return
+	}
+	p.breaker.record(isFailure(err))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+)
+
+// 字符串按uvarint长度 + 内容编码，请求体和响应体不带长度，占用帧中剩余的字节
+//
+// 请求：rpc_name | 元数据个数 | key value ... | body
+// 响应：error | body
+
+var errTruncated = errors.New("truncated")
+
+// Request 请求帧的内容，Metadata透传traceparent等请求元数据
+type Request struct {
+	RPCName  string
+	Metadata metadata.MD
+	Body     []byte
+}
+
+// Response 响应帧的内容，Error是handler返回的错误，为空时表示成功
+type Response struct {
+	Error string
+	Body  []byte
+}
+
+func EncodeRequest(request *Request) []byte {
+	size := binary.MaxVarintLen64*(2+2*len(request.Metadata)) + len(request.RPCName) + len(request.Body)
+	for key, value := range request.Metadata {
+		size += len(key) + len(value)
+	}
+	buf := make([]byte, 0, size)
+	buf = appendString(buf, request.RPCName)
+	buf = binary.AppendUvarint(buf, uint64(len(request.Metadata)))
+	for key, value := range request.Metadata {
+		buf = appendString(buf, key)
+		buf = appendString(buf, value)
+	}
+	return append(buf, request.Body...)
+}
+
+func DecodeRequest(buf []byte) (*Request, error) {
+	request := &Request{}
+	var err error
+	if request.RPCName, buf, err = readString(buf); err != nil {
+		return nil, fmt.Errorf("decode rpc name: %v", err)
+	}
+	count, n := binary.Uvarint(buf)
+	if n <= 0 || count > uint64(len(buf)) {
+		return nil, fmt.Errorf("decode metadata count: %v", errTruncated)
+	}
+	buf = buf[n:]
+	request.Metadata = make(metadata.MD, count)
+	for range count {
+		var key, value string
+		if key, buf, err = readString(buf); err != nil {
+			return nil, fmt.Errorf("decode metadata key: %v", err)
+		}
+		if value, buf, err = readString(buf); err != nil {
+			return nil, fmt.Errorf("decode metadata[%s]: %v", key, err)
+		}
+		request.Metadata.Set(key, value)
+	}
+	request.Body = buf
+	return request, nil
+}
+
+func EncodeResponse(response *Response) []byte {
+	buf := make([]byte, 0, binary.MaxVarintLen64+len(response.Error)+len(response.Body))
+	buf = appendString(buf, response.Error)
+	return append(buf, response.Body...)
+}
+
+func DecodeResponse(buf []byte) (*Response, error) {
+	response := &Response{}
+	var err error
+	if response.Error, buf, err = readString(buf); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	response.Body = buf
+	return response, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func readString(buf []byte) (string, []byte, error) {
+	size, n := binary.Uvarint(buf)
+	if n <= 0 || size > uint64(len(buf)-n) {
+		return "", nil, errTruncated
+	}
+	buf = buf[n:]
+	return string(buf[:size]), buf[size:], nil
+}
@@ -0,0 +1,63 @@
+package codec
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+)
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *Request
+	}{
+		{
+			name:    "empty",
+			request: &Request{Metadata: metadata.MD{}},
+		},
+		{
+			name: "metadata",
+			request: &Request{
+				RPCName:  "Echo",
+				Metadata: metadata.MD{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "caller": ""},
+				Body:     []byte("hello world"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeRequest(EncodeRequest(tt.request))
+			if err != nil {
+				t.Fatalf("DecodeRequest: %v", err)
+			}
+			if got.RPCName != tt.request.RPCName || !maps.Equal(got.Metadata, tt.request.Metadata) || !bytes.Equal(got.Body, tt.request.Body) {
+				t.Errorf("DecodeRequest() = %+v, want %+v", got, tt.request)
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	want := &Response{Error: `{"code":503}`, Body: []byte("body")}
+	got, err := DecodeResponse(EncodeResponse(want))
+	if err != nil {
+		t.Fatalf("DecodeResponse: %v", err)
+	}
+	if got.Error != want.Error || !bytes.Equal(got.Body, want.Body) {
+		t.Errorf("DecodeResponse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	buf := EncodeRequest(&Request{RPCName: "Echo", Metadata: metadata.MD{"caller": "a"}})
+	for i := range len(buf) {
+		if _, err := DecodeRequest(buf[:i]); err == nil {
+			t.Errorf("DecodeRequest(buf[:%d]) error = nil, want truncated", i)
+		}
+	}
+	if _, err := DecodeResponse([]byte{5, 'a'}); err == nil {
+		t.Error("DecodeResponse() error = nil, want truncated")
+	}
+}
@@ -1,32 +1,45 @@
 package framework
 
 import (
+	"github.com/soulnov23/go-tool/pkg/framework/limiter"
 	"github.com/soulnov23/go-tool/pkg/framework/plugin"
 )
 
 const (
 	defaultConfigPath               = "../conf/go_tool.yaml"
-	defaultUpdateGOMAXPROCSInterval = 60000
+	defaultUpdateGOMAXPROCSInterval = 60000 // 单位 毫秒
+	defaultNetwork                  = "tcp"
+	defaultProtocol                 = "rpc"
+	defaultServiceTimeout           = 3000  // 单位 毫秒
+	defaultPluginTimeout            = 10000 // 单位 毫秒
 )
 
+// 支持的应用层协议
+var protocols = []string{"rpc", "http"}
+
 type Config struct {
 	ProfileProfiler *struct {
 		Address      string `yaml:"address"`
 		ReadTimeout  int64  `yaml:"read_timeout"`
 		WriteTimeout int64  `yaml:"write_timeout"`
 		IdleTimeout  int64  `yaml:"idle_timeout"`
+		AdminToken   string `yaml:"admin_token"` // 修改状态的admin接口需要带Authorization: Bearer <admin_token>，为空时只允许本机访问
 	} `yaml:"pprof"`
 
 	Server *struct {
-		UpdateGOMAXPROCSInterval int64 `yaml:"update_gomaxprocs_interval"`
-		MaxCloseWaitTime         int64 `yaml:"max_close_wait_time"`
+		UpdateGOMAXPROCSInterval int64            `yaml:"update_gomaxprocs_interval"` // 默认 defaultUpdateGOMAXPROCSInterval
+		MaxCloseWaitTime         int64            `yaml:"max_close_wait_time"`        // 0表示不限制
+		PluginTimeout            int64            `yaml:"plugin_timeout"`             // 插件Setup、Start和Stop的超时时间，默认 defaultPluginTimeout
+		PluginTimeouts           map[string]int64 `yaml:"plugin_timeouts"`            // 按插件类型覆盖plugin_timeout
 
 		Services []*struct {
-			Name     string `yaml:"name"`
-			Address  string `yaml:"address"`
-			Network  string `yaml:"network"`
-			Protocol string `yaml:"protocol"`
-			Timeout  int64  `yaml:"timeout"`
+			Name     string `yaml:"name"`     // 必填且不能重复
+			Address  string `yaml:"address"`  // 必填 host:port，同一个network下不能重复
+			Network  string `yaml:"network"`  // 默认 tcp
+			Protocol string `yaml:"protocol"` // 默认 rpc
+			Timeout  int64  `yaml:"timeout"`  // 默认 defaultServiceTimeout
+
+			Limiter *limiter.Config `yaml:"limiter"`
 		} `yaml:"services"`
 	} `yaml:"server"`
 
@@ -0,0 +1,24 @@
+package framework
+
+import "github.com/soulnov23/go-tool/pkg/errors"
+
+var (
+	ErrBadRequest = &errors.Error{
+		Code:    400,
+		Status:  "Bad Request",
+		Name:    "BadRequest",
+		Message: "{{.}}",
+	}
+	ErrRPCNotFound = &errors.Error{
+		Code:    404,
+		Status:  "Not Found",
+		Name:    "RPCNotFound",
+		Message: "rpc {{.}} not found",
+	}
+	ErrInternal = &errors.Error{
+		Code:    500,
+		Status:  "Internal Server Error",
+		Name:    "Internal",
+		Message: "{{.}}",
+	}
+)
@@ -0,0 +1,171 @@
+// Package expand 展开配置中的${...}引用
+//
+//	${VAR}           环境变量，未设置时报错
+//	${VAR:-default}  环境变量，未设置或为空时使用默认值
+//	${env:VAR}       同${VAR}
+//	${file:/path}    文件内容，去掉末尾的换行
+//	${scheme:name}   通过RegisterResolver注册的解析器，比如${secret:name}
+//	$$               字面量$，其它不在${...}中的$保持原样
+package expand
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound 引用不存在时Resolver返回该错误，配置了默认值时使用默认值
+var ErrNotFound = errors.New("reference not found")
+
+// Resolver 解析${scheme:name}中的name
+type Resolver func(name string) (string, error)
+
+var (
+	resolvers = map[string]Resolver{}
+	mutex     = sync.RWMutex{}
+)
+
+func init() {
+	RegisterResolver("env", resolveEnv)
+	RegisterResolver("file", resolveFile)
+}
+
+func RegisterResolver(scheme string, resolver Resolver) {
+	value := reflect.ValueOf(resolver)
+	if resolver == nil || value.Kind() == reflect.Pointer && value.IsNil() {
+		panic("register nil expand resolver")
+	}
+	if scheme == "" {
+		panic("register empty scheme of expand resolver")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	resolvers[scheme] = resolver
+}
+
+func resolveEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("env %s: %w", name, ErrNotFound)
+	}
+	return value, nil
+}
+
+func resolveFile(path string) (string, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("file %s: %w", path, ErrNotFound)
+		}
+		return "", fmt.Errorf("read file %s: %v", path, err)
+	}
+	return strings.TrimRight(string(buffer), "\r\n"), nil
+}
+
+// String 展开字符串中的所有引用
+func String(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	builder := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			builder.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			builder.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed reference %q", s[i:])
+			}
+			value, err := resolve(s[i+2 : i+2+end])
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(value)
+			i += 2 + end
+		default:
+			builder.WriteByte('$')
+		}
+	}
+	return builder.String(), nil
+}
+
+// resolve 解析${}中的内容，不带scheme时按环境变量解析
+func resolve(reference string) (string, error) {
+	body, defaultValue, hasDefault := strings.Cut(reference, ":-")
+	scheme, name, ok := strings.Cut(body, ":")
+	if !ok {
+		scheme, name = "env", body
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty reference ${%s}", reference)
+	}
+	mutex.RLock()
+	resolver, ok := resolvers[scheme]
+	mutex.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("resolver of ${%s} not found", reference)
+	}
+	value, err := resolver(name)
+	if hasDefault && (errors.Is(err, ErrNotFound) || err == nil && value == "") {
+		return defaultValue, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve ${%s}: %v", reference, err)
+	}
+	return value, nil
+}
+
+// Node 展开yaml中所有标量的值，出错时返回所在的key路径，比如server.services[0].address
+func Node(node *yaml.Node) error {
+	var errs []error
+	walk(node, "", &errs)
+	return errors.Join(errs...)
+}
+
+func walk(node *yaml.Node, path string, errs *[]error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walk(child, path, errs)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walk(child, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			walk(node.Content[i+1], key, errs)
+		}
+	case yaml.ScalarNode:
+		value, err := String(node.Value)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %v", path, err))
+			return
+		}
+		if value == node.Value {
+			return
+		}
+		node.Value = value
+		// 没有引号的标量按展开后的值重新推断类型，比如timeout: ${TIMEOUT:-3000}解析成整数
+		if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			node.Tag = ""
+		}
+	}
+	// 别名指向的锚点在定义处已经展开，不重复展开
+}
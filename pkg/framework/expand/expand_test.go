@@ -0,0 +1,97 @@
+package expand
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestString(t *testing.T) {
+	t.Setenv("EXPAND_HOST", "127.0.0.1")
+	t.Setenv("EXPAND_EMPTY", "")
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("p@ss$word\n"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	RegisterResolver("secret", func(name string) (string, error) {
+		if name == "db" {
+			return "s3cr3t", nil
+		}
+		return "", ErrNotFound
+	})
+
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", s: "0.0.0.0:6666", want: "0.0.0.0:6666"},
+		{name: "env", s: "${EXPAND_HOST}:6666", want: "127.0.0.1:6666"},
+		{name: "env scheme", s: "${env:EXPAND_HOST}", want: "127.0.0.1"},
+		{name: "default", s: "${EXPAND_UNSET:-3000}", want: "3000"},
+		{name: "default empty", s: "${EXPAND_EMPTY:-info}", want: "info"},
+		{name: "default not used", s: "${EXPAND_HOST:-localhost}", want: "127.0.0.1"},
+		{name: "file", s: "${file:" + path + "}", want: "p@ss$word"},
+		{name: "secret", s: "root:${secret:db}@tcp", want: "root:s3cr3t@tcp"},
+		{name: "escape", s: "$${EXPAND_HOST}", want: "${EXPAND_HOST}"},
+		{name: "bare dollar", s: "^a$|p$w", want: "^a$|p$w"},
+		{name: "unset", s: "${EXPAND_UNSET}", wantErr: true},
+		{name: "secret not found", s: "${secret:cache}", wantErr: true},
+		{name: "unknown scheme", s: "${vault:db}", wantErr: true},
+		{name: "unclosed", s: "${EXPAND_HOST", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := String(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("String() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode(t *testing.T) {
+	t.Setenv("EXPAND_TIMEOUT", "5000")
+	config := `
+server:
+    services:
+        - name: rpc_service
+          timeout: ${EXPAND_TIMEOUT}
+          protocol: "${EXPAND_TIMEOUT}"
+`
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(config), node); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if err := Node(node); err != nil {
+		t.Fatalf("Node: %v", err)
+	}
+	value := &struct {
+		Server struct {
+			Services []struct {
+				Timeout  int64  `yaml:"timeout"`
+				Protocol string `yaml:"protocol"`
+			} `yaml:"services"`
+		} `yaml:"server"`
+	}{}
+	if err := node.Decode(value); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if service := value.Server.Services[0]; service.Timeout != 5000 || service.Protocol != "5000" {
+		t.Errorf("Decode() = %+v", service)
+	}
+
+	if err := yaml.Unmarshal([]byte("server:\n    services:\n        - address: ${EXPAND_UNSET}\n"), node); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if err := Node(node); err == nil || !strings.HasPrefix(err.Error(), "server.services[0].address: ") {
+		t.Errorf("Node() error = %v, want key path", err)
+	}
+}
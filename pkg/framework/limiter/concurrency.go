@@ -0,0 +1,132 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Release 请求处理结束后调用，用处理耗时和结果调整并发上限
+type Release func(err error)
+
+// errDropped 请求没有被处理，只归还名额不调整上限
+var errDropped = errors.New("request dropped")
+
+type ConcurrencyLimiter interface {
+	Acquire() (Release, bool)
+	Limit() int
+	InFlight() int
+}
+
+type concurrencyAlgorithm interface {
+	// update 根据一次请求的耗时和是否过载返回新的并发上限
+	update(limit float64, latency time.Duration, overload bool) float64
+}
+
+// adaptiveLimiter 并发数达到上限时拒绝，上限由算法根据延迟动态调整
+type adaptiveLimiter struct {
+	minLimit  float64
+	maxLimit  float64
+	algorithm concurrencyAlgorithm
+
+	mutex    sync.Mutex
+	limit    float64
+	inFlight int
+}
+
+func newAdaptiveLimiter(config *ConcurrencyLimitConfig, algorithm concurrencyAlgorithm) *adaptiveLimiter {
+	return &adaptiveLimiter{
+		minLimit:  float64(config.MinLimit),
+		maxLimit:  float64(config.MaxLimit),
+		algorithm: algorithm,
+		limit:     float64(config.InitialLimit),
+	}
+}
+
+func (l *adaptiveLimiter) Acquire() (Release, bool) {
+	l.mutex.Lock()
+	if float64(l.inFlight) >= math.Floor(l.limit) {
+		l.mutex.Unlock()
+		return nil, false
+	}
+	l.inFlight++
+	l.mutex.Unlock()
+	begin := time.Now()
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			latency := time.Since(begin)
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			l.inFlight--
+			if err == errDropped {
+				return
+			}
+			limit := l.algorithm.update(l.limit, latency, isOverload(err))
+			l.limit = min(max(limit, l.minLimit), l.maxLimit)
+		})
+	}, true
+}
+
+func (l *adaptiveLimiter) Limit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int(l.limit)
+}
+
+func (l *adaptiveLimiter) InFlight() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.inFlight
+}
+
+// isOverload handler超时说明服务已经过载
+func isOverload(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// aimd 耗时超过阈值或者超时时乘性减小，否则加性增大，每个上限周期约增加1
+type aimd struct {
+	latencyThreshold time.Duration
+	backoffRatio     float64
+}
+
+func (a *aimd) update(limit float64, latency time.Duration, overload bool) float64 {
+	if overload || latency > a.latencyThreshold {
+		return limit * a.backoffRatio
+	}
+	return limit + 1/limit
+}
+
+// gradient 用最小耗时和当前耗时的比值作为梯度调整上限，额外保留sqrt(limit)的排队空间
+type gradient struct {
+	backoffRatio float64
+
+	minLatency time.Duration
+	samples    int
+}
+
+// 每隔这么多个样本重置一次最小耗时，适应下游耗时的长期变化
+const gradientResetSamples = 1000
+
+// 新上限和旧上限的平滑系数
+const gradientSmoothing = 0.2
+
+func (g *gradient) update(limit float64, latency time.Duration, overload bool) float64 {
+	if overload {
+		return limit * g.backoffRatio
+	}
+	g.samples++
+	if g.minLatency == 0 || latency < g.minLatency || g.samples >= gradientResetSamples {
+		g.minLatency = latency
+		g.samples = 0
+	}
+	if latency <= 0 {
+		return limit
+	}
+	ratio := min(max(float64(g.minLatency)/float64(latency), 0.5), 1)
+	newLimit := limit*ratio + math.Sqrt(limit)
+	return limit*(1-gradientSmoothing) + newLimit*gradientSmoothing
+}
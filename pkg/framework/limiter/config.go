@@ -0,0 +1,117 @@
+package limiter
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	TokenBucketType   = "token_bucket"
+	SlidingWindowType = "sliding_window"
+	AIMDType          = "aimd"
+	GradientType      = "gradient"
+)
+
+// Config 服务级别的限流配置，rpcs中的配置和服务级别的配置同时生效
+type Config struct {
+	Key              string                  `yaml:"key"`      // 按请求元数据中该字段的值分别限流，比如主调服务名，为空时不区分
+	MaxKeys          int                     `yaml:"max_keys"` // 每条规则最多保留多少个key的限流器，超过时淘汰最久没有请求的key，默认 defaultMaxKeys
+	RateLimit        *RateLimitConfig        `yaml:"rate_limit"`
+	ConcurrencyLimit *ConcurrencyLimitConfig `yaml:"concurrency_limit"`
+	RPCs             []*RPCConfig            `yaml:"rpcs"`
+}
+
+type RPCConfig struct {
+	Name             string                  `yaml:"name"`
+	RateLimit        *RateLimitConfig        `yaml:"rate_limit"`
+	ConcurrencyLimit *ConcurrencyLimitConfig `yaml:"concurrency_limit"`
+}
+
+type RateLimitConfig struct {
+	Type   string  `yaml:"type"`   // token_bucket sliding_window
+	Rate   float64 `yaml:"rate"`   // token_bucket每秒生成的令牌数，sliding_window每个窗口允许的请求数
+	Burst  int     `yaml:"burst"`  // token_bucket最多积攒的令牌数
+	Window int64   `yaml:"window"` // sliding_window窗口大小 单位 毫秒
+}
+
+type ConcurrencyLimitConfig struct {
+	Type             string  `yaml:"type"`              // aimd gradient
+	InitialLimit     int     `yaml:"initial_limit"`     // 初始并发上限
+	MinLimit         int     `yaml:"min_limit"`         // 最小并发上限
+	MaxLimit         int     `yaml:"max_limit"`         // 最大并发上限
+	LatencyThreshold int64   `yaml:"latency_threshold"` // aimd耗时超过该值时减小上限 单位 毫秒
+	BackoffRatio     float64 `yaml:"backoff_ratio"`     // 减小上限时乘以的比例 0~1
+}
+
+const (
+	defaultMaxKeys          = 10000
+	defaultWindow           = 1000
+	defaultInitialLimit     = 20
+	defaultMinLimit         = 1
+	defaultMaxLimit         = 1000
+	defaultLatencyThreshold = 1000
+	defaultBackoffRatio     = 0.9
+)
+
+func (c *RateLimitConfig) check() error {
+	if c.Rate <= 0 {
+		return fmt.Errorf("rate_limit rate[%v] must be positive", c.Rate)
+	}
+	switch c.Type {
+	case TokenBucketType:
+	case SlidingWindowType:
+		if c.Rate < 1 || c.Rate != math.Trunc(c.Rate) {
+			return fmt.Errorf("rate_limit rate[%v] of sliding_window must be an integer not less than 1", c.Rate)
+		}
+		if c.Window <= 0 {
+			c.Window = defaultWindow
+		}
+	default:
+		return fmt.Errorf("rate_limit type[%s] not support", c.Type)
+	}
+	return nil
+}
+
+func (c *RateLimitConfig) new() RateLimiter {
+	if c.Type == SlidingWindowType {
+		return NewSlidingWindow(int(c.Rate), time.Duration(c.Window)*time.Millisecond)
+	}
+	return NewTokenBucket(c.Rate, c.Burst)
+}
+
+func (c *ConcurrencyLimitConfig) check() error {
+	if c.Type != AIMDType && c.Type != GradientType {
+		return fmt.Errorf("concurrency_limit type[%s] not support", c.Type)
+	}
+	if c.MinLimit <= 0 {
+		c.MinLimit = defaultMinLimit
+	}
+	if c.MaxLimit <= 0 {
+		c.MaxLimit = defaultMaxLimit
+	}
+	if c.InitialLimit <= 0 {
+		c.InitialLimit = defaultInitialLimit
+	}
+	if c.MinLimit > c.MaxLimit {
+		return fmt.Errorf("concurrency_limit min_limit[%d] greater than max_limit[%d]", c.MinLimit, c.MaxLimit)
+	}
+	c.InitialLimit = min(max(c.InitialLimit, c.MinLimit), c.MaxLimit)
+	if c.LatencyThreshold <= 0 {
+		c.LatencyThreshold = defaultLatencyThreshold
+	}
+	if c.BackoffRatio <= 0 || c.BackoffRatio >= 1 {
+		c.BackoffRatio = defaultBackoffRatio
+	}
+	return nil
+}
+
+func (c *ConcurrencyLimitConfig) new() ConcurrencyLimiter {
+	if c.Type == GradientType {
+		return newAdaptiveLimiter(c, &gradient{backoffRatio: c.BackoffRatio})
+	}
+	return newAdaptiveLimiter(c, &aimd{
+		latencyThreshold: time.Duration(c.LatencyThreshold) * time.Millisecond,
+		backoffRatio:     c.BackoffRatio,
+	})
+}
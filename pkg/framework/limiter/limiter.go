@@ -0,0 +1,195 @@
+package limiter
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+)
+
+var (
+	ErrRateLimited = &errors.Error{
+		Code:    503,
+		Status:  "Service Unavailable",
+		Name:    "RateLimited",
+		Message: "rate limit of {{.}} exceeded",
+	}
+	ErrConcurrencyLimited = &errors.Error{
+		Code:    503,
+		Status:  "Service Unavailable",
+		Name:    "ConcurrencyLimited",
+		Message: "concurrency limit of {{.}} exceeded",
+	}
+)
+
+// Limiter 一个服务的限流器，在handler执行之前调用Allow
+type Limiter struct {
+	service string
+	key     string
+	rule    *rule
+	rpcs    map[string]*rule // k=rpc_name,v=rule
+}
+
+func New(service string, config *Config) (*Limiter, error) {
+	l := &Limiter{
+		service: service,
+		rpcs:    make(map[string]*rule),
+	}
+	if config == nil {
+		return l, nil
+	}
+	l.key = config.Key
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = defaultMaxKeys
+	}
+	serviceRule, err := newRule(config.RateLimit, config.ConcurrencyLimit, config.MaxKeys)
+	if err != nil {
+		return nil, fmt.Errorf("service[%s] %v", service, err)
+	}
+	l.rule = serviceRule
+	for _, rpcConfig := range config.RPCs {
+		rpcRule, err := newRule(rpcConfig.RateLimit, rpcConfig.ConcurrencyLimit, config.MaxKeys)
+		if err != nil {
+			return nil, fmt.Errorf("service[%s] rpc[%s] %v", service, rpcConfig.Name, err)
+		}
+		if rpcRule != nil {
+			l.rpcs[rpcConfig.Name] = rpcRule
+		}
+	}
+	return l, nil
+}
+
+// Allow 先检查服务和rpc的速率限制，再占用并发名额，任何一个不满足都返回503错误，
+// 被拒绝的请求已经占用的配额和名额都会归还，成功时返回的Release必须在handler结束后调用
+func (l *Limiter) Allow(rpcName string, md metadata.MD) (Release, error) {
+	if l == nil {
+		return func(error) {}, nil
+	}
+	var key string
+	if l.key != "" {
+		key = md.Get(l.key)
+	}
+	limiters := make([]*keyLimiters, 0, 2)
+	if l.rule != nil {
+		limiters = append(limiters, l.rule.limiters(key))
+	}
+	if rpcRule, ok := l.rpcs[rpcName]; ok {
+		limiters = append(limiters, rpcRule.limiters(key))
+	}
+	allowed := make([]RateLimiter, 0, len(limiters))
+	cancel := func() {
+		for _, rateLimiter := range allowed {
+			rateLimiter.Cancel()
+		}
+	}
+	for _, limiter := range limiters {
+		if limiter.rate == nil {
+			continue
+		}
+		if !limiter.rate.Allow() {
+			cancel()
+			l.reject(rpcName, key, "rate")
+			return nil, ErrRateLimited.Clone().WithMessageValues(l.target(rpcName, key))
+		}
+		allowed = append(allowed, limiter.rate)
+	}
+	releases := make([]Release, 0, len(limiters))
+	release := func(err error) {
+		for _, release := range releases {
+			release(err)
+		}
+	}
+	for _, limiter := range limiters {
+		if limiter.concurrency == nil {
+			continue
+		}
+		acquired, ok := limiter.concurrency.Acquire()
+		if !ok {
+			// 被拒绝的请求没有耗时，已经占用的名额直接归还，不参与上限调整
+			release(errDropped)
+			cancel()
+			l.reject(rpcName, key, "concurrency")
+			return nil, ErrConcurrencyLimited.Clone().WithMessageValues(l.target(rpcName, key))
+		}
+		releases = append(releases, acquired)
+	}
+	return release, nil
+}
+
+func (l *Limiter) target(rpcName, key string) string {
+	if key == "" {
+		return l.service + "/" + rpcName
+	}
+	return fmt.Sprintf("%s/%s from %s", l.service, rpcName, key)
+}
+
+func (l *Limiter) reject(rpcName, key, limit string) {
+	metrics.GetCounter(metrics.Name("server_limiter_rejected_total", "service", l.service, "rpc", rpcName, "key", key, "limit", limit)).Inc()
+}
+
+// rule 一组限流配置，按key分别创建限流器，key的数量超过maxKeys时淘汰最久没有请求的key
+type rule struct {
+	rateConfig        *RateLimitConfig
+	concurrencyConfig *ConcurrencyLimitConfig
+	maxKeys           int
+
+	mutex sync.Mutex
+	keys  map[string]*list.Element // k=key,v=lru中的*keyLimiters
+	lru   *list.List               // 最近有请求的key在前面
+}
+
+// keyLimiters 一个key的限流器，淘汰后同一个key再次请求时重新创建
+type keyLimiters struct {
+	key         string
+	rate        RateLimiter
+	concurrency ConcurrencyLimiter
+}
+
+func newRule(rateConfig *RateLimitConfig, concurrencyConfig *ConcurrencyLimitConfig, maxKeys int) (*rule, error) {
+	if rateConfig == nil && concurrencyConfig == nil {
+		return nil, nil
+	}
+	if rateConfig != nil {
+		if err := rateConfig.check(); err != nil {
+			return nil, err
+		}
+	}
+	if concurrencyConfig != nil {
+		if err := concurrencyConfig.check(); err != nil {
+			return nil, err
+		}
+	}
+	return &rule{
+		rateConfig:        rateConfig,
+		concurrencyConfig: concurrencyConfig,
+		maxKeys:           maxKeys,
+		keys:              make(map[string]*list.Element),
+		lru:               list.New(),
+	}, nil
+}
+
+func (r *rule) limiters(key string) *keyLimiters {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if element, ok := r.keys[key]; ok {
+		r.lru.MoveToFront(element)
+		return element.Value.(*keyLimiters)
+	}
+	limiters := &keyLimiters{key: key}
+	if r.rateConfig != nil {
+		limiters.rate = r.rateConfig.new()
+	}
+	if r.concurrencyConfig != nil {
+		limiters.concurrency = r.concurrencyConfig.new()
+	}
+	r.keys[key] = r.lru.PushFront(limiters)
+	// 淘汰时还在处理中的请求仍然归还到原来的限流器
+	for r.lru.Len() > r.maxKeys {
+		oldest := r.lru.Remove(r.lru.Back()).(*keyLimiters)
+		delete(r.keys, oldest.key)
+	}
+	return limiters
+}
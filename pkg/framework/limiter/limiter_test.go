@@ -0,0 +1,216 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := NewTokenBucket(1, 3)
+	for i := range 3 {
+		if !bucket.Allow() {
+			t.Fatalf("Allow() %d = false, want true", i)
+		}
+	}
+	if bucket.Allow() {
+		t.Errorf("Allow() after burst = true, want false")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	window := NewSlidingWindow(2, 50*time.Millisecond)
+	if !window.Allow() || !window.Allow() {
+		t.Fatalf("Allow() within limit = false, want true")
+	}
+	if window.Allow() {
+		t.Errorf("Allow() over limit = true, want false")
+	}
+	time.Sleep(120 * time.Millisecond)
+	if !window.Allow() {
+		t.Errorf("Allow() after window = false, want true")
+	}
+}
+
+func TestAIMD(t *testing.T) {
+	config := &ConcurrencyLimitConfig{Type: AIMDType, InitialLimit: 2, MinLimit: 1, MaxLimit: 4}
+	if err := config.check(); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	limiter := config.new()
+	release1, ok1 := limiter.Acquire()
+	_, ok2 := limiter.Acquire()
+	if !ok1 || !ok2 {
+		t.Fatalf("Acquire() within limit failed")
+	}
+	if _, ok := limiter.Acquire(); ok {
+		t.Fatalf("Acquire() over limit = true, want false")
+	}
+	release1(context.DeadlineExceeded)
+	if got := limiter.Limit(); got != 1 {
+		t.Errorf("Limit() after timeout = %d, want 1", got)
+	}
+	if got := limiter.InFlight(); got != 1 {
+		t.Errorf("InFlight() = %d, want 1", got)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	g := &gradient{backoffRatio: 0.5}
+	limit := g.update(100, 10*time.Millisecond, false)
+	if limit <= 100 {
+		t.Errorf("update() with min latency = %v, want > 100", limit)
+	}
+	if got := g.update(limit, 100*time.Millisecond, false); got >= limit {
+		t.Errorf("update() with high latency = %v, want < %v", got, limit)
+	}
+	if got := g.update(100, time.Millisecond, true); got != 50 {
+		t.Errorf("update() with overload = %v, want 50", got)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l, err := New("rpc_service", &Config{
+		Key: "caller",
+		RPCs: []*RPCConfig{
+			{
+				Name:      "Echo",
+				RateLimit: &RateLimitConfig{Type: TokenBucketType, Rate: 1, Burst: 1},
+			},
+			{
+				Name:             "Sleep",
+				ConcurrencyLimit: &ConcurrencyLimitConfig{Type: GradientType, InitialLimit: 1, MinLimit: 1, MaxLimit: 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	a := metadata.MD{"caller": "a"}
+	b := metadata.MD{"caller": "b"}
+	if _, err := l.Allow("Echo", a); err != nil {
+		t.Fatalf("Allow(Echo, a): %v", err)
+	}
+	if _, err := l.Allow("Echo", a); !errors.Equal(err, ErrRateLimited) {
+		t.Errorf("Allow(Echo, a) over limit = %v, want %v", err, ErrRateLimited)
+	}
+	if _, err := l.Allow("Echo", b); err != nil {
+		t.Errorf("Allow(Echo, b) = %v, want nil", err)
+	}
+	if _, err := l.Allow("Other", a); err != nil {
+		t.Errorf("Allow(Other, a) = %v, want nil", err)
+	}
+
+	release, err := l.Allow("Sleep", a)
+	if err != nil {
+		t.Fatalf("Allow(Sleep, a): %v", err)
+	}
+	if _, err := l.Allow("Sleep", a); !errors.Equal(err, ErrConcurrencyLimited) {
+		t.Errorf("Allow(Sleep, a) over limit = %v, want %v", err, ErrConcurrencyLimited)
+	}
+	release(nil)
+	if _, err := l.Allow("Sleep", a); err != nil {
+		t.Errorf("Allow(Sleep, a) after release = %v, want nil", err)
+	}
+}
+
+// TestLimiterCancel rpc规则拒绝的请求不占用服务规则的配额
+func TestLimiterCancel(t *testing.T) {
+	l, err := New("rpc_service", &Config{
+		RateLimit: &RateLimitConfig{Type: TokenBucketType, Rate: 0.001, Burst: 2},
+		RPCs: []*RPCConfig{
+			{
+				Name:      "Echo",
+				RateLimit: &RateLimitConfig{Type: TokenBucketType, Rate: 0.001, Burst: 1},
+			},
+			{
+				Name:             "Sleep",
+				ConcurrencyLimit: &ConcurrencyLimitConfig{Type: AIMDType, InitialLimit: 1, MinLimit: 1, MaxLimit: 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := l.Allow("Echo", nil); err != nil {
+		t.Fatalf("Allow(Echo): %v", err)
+	}
+	if _, err := l.Allow("Echo", nil); !errors.Equal(err, ErrRateLimited) {
+		t.Fatalf("Allow(Echo) over rpc limit = %v, want %v", err, ErrRateLimited)
+	}
+	// 服务规则还剩一个令牌
+	if _, err := l.Allow("Sleep", nil); err != nil {
+		t.Fatalf("Allow(Sleep): %v", err)
+	}
+	if _, err := l.Allow("Other", nil); !errors.Equal(err, ErrRateLimited) {
+		t.Errorf("Allow(Other) over service limit = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	l, err := New("rpc_service", &Config{
+		Key:     "caller",
+		MaxKeys: 2,
+		RPCs: []*RPCConfig{
+			{
+				Name:      "Echo",
+				RateLimit: &RateLimitConfig{Type: SlidingWindowType, Rate: 1, Window: 60000},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, caller := range []string{"a", "b", "a", "c"} {
+		_, _ = l.Allow("Echo", metadata.MD{"caller": caller})
+	}
+	if got := l.rpcs["Echo"].lru.Len(); got != 2 {
+		t.Errorf("keys = %d, want 2", got)
+	}
+	// b最久没有请求被淘汰，a还在
+	if _, err := l.Allow("Echo", metadata.MD{"caller": "b"}); err != nil {
+		t.Errorf("Allow(Echo, b) after evicted = %v, want nil", err)
+	}
+	if _, err := l.Allow("Echo", metadata.MD{"caller": "c"}); !errors.Equal(err, ErrRateLimited) {
+		t.Errorf("Allow(Echo, c) = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "unknown rate type",
+			config: &Config{RateLimit: &RateLimitConfig{Type: "leaky_bucket", Rate: 1}},
+		},
+		{
+			name:   "zero rate",
+			config: &Config{RateLimit: &RateLimitConfig{Type: TokenBucketType}},
+		},
+		{
+			name:   "fractional sliding window rate",
+			config: &Config{RateLimit: &RateLimitConfig{Type: SlidingWindowType, Rate: 1.5}},
+		},
+		{
+			name:   "sliding window rate less than 1",
+			config: &Config{RateLimit: &RateLimitConfig{Type: SlidingWindowType, Rate: 0.5}},
+		},
+		{
+			name:   "unknown concurrency type",
+			config: &Config{RPCs: []*RPCConfig{{Name: "Echo", ConcurrencyLimit: &ConcurrencyLimitConfig{Type: "vegas"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New("rpc_service", tt.config); err == nil {
+				t.Errorf("New() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+type RateLimiter interface {
+	Allow() bool
+	// Cancel 归还一次Allow占用的配额，后面的规则拒绝请求时调用
+	Cancel()
+}
+
+// TokenBucket 每秒生成rate个令牌，最多积攒burst个
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst <= 0 {
+		burst = max(int(rate), 1)
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *TokenBucket) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *TokenBucket) Cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// SlidingWindow 每个window最多limit个请求，用上一个窗口按剩余比例加权近似滑动窗口，避免固定窗口边界的突发
+type SlidingWindow struct {
+	limit  float64
+	window time.Duration
+
+	mutex    sync.Mutex
+	start    time.Time
+	previous float64
+	current  float64
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:  float64(limit),
+		window: window,
+		start:  time.Now(),
+	}
+}
+
+func (w *SlidingWindow) Allow() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	now := time.Now()
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		// 跨过一个窗口时当前窗口变成上一个窗口，跨过多个窗口时全部清零
+		if elapsed < 2*w.window {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.current = 0
+		w.start = w.start.Add(elapsed / w.window * w.window)
+	}
+	weight := 1 - float64(now.Sub(w.start))/float64(w.window)
+	if w.previous*weight+w.current+1 > w.limit {
+		return false
+	}
+	w.current++
+	return true
+}
+
+// Cancel 窗口已经切换时从上一个窗口中扣除
+func (w *SlidingWindow) Cancel() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.current > 0 {
+		w.current--
+	} else if w.previous > 0 {
+		w.previous--
+	}
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/soulnov23/go-tool/pkg/framework/plugin"
@@ -9,29 +10,52 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-const pluginName = "frame_log"
+const (
+	// PluginType 依赖日志的插件在DependsOn中返回该类型
+	PluginType = "log"
+	// FrameName 框架日志的实例名，初始化后设置DefaultLogger
+	FrameName = "frame"
+)
 
 var DefaultLogger log.Logger
 
 func init() {
-	plugin.Register(pluginName, &FrameLogPlugin{})
+	plugin.Register(PluginType, &LogPlugin{})
 }
 
-type FrameLogPlugin struct{}
+// LogPlugin frame实例设置框架日志DefaultLogger，default实例设置业务日志log.DefaultLogger，
+// 其它实例通过plugin.Get[log.Logger]("log", name)获取
+type LogPlugin struct{}
 
-func (p *FrameLogPlugin) Name() string {
-	return pluginName
+func (p *LogPlugin) Type() string {
+	return PluginType
 }
 
-func (p *FrameLogPlugin) Setup(node yaml.Node) error {
+func (p *LogPlugin) Setup(ctx context.Context, name string, node yaml.Node) (any, error) {
 	config := &log.Config{}
 	if err := node.Decode(config); err != nil {
-		return fmt.Errorf("plugin name[%s] invalid config: %v", pluginName, err)
+		return nil, fmt.Errorf("plugin type[%s] name[%s] invalid config: %v", PluginType, name, err)
 	}
 	logger, err := log.New(config)
 	if err != nil {
-		return fmt.Errorf("plugin name[%s] new logger: %v", pluginName, err)
+		return nil, fmt.Errorf("plugin type[%s] name[%s] new logger: %v", PluginType, name, err)
+	}
+	logger = logger.With(zap.String("name", name))
+	switch name {
+	case FrameName:
+		DefaultLogger = logger
+	case plugin.DefaultName:
+		log.DefaultLogger = logger
+	}
+	return logger, nil
+}
+
+// FrameLogger 依赖日志的插件通过plugin.Get获取frame实例，没有配置frame实例时使用log.DefaultLogger，
+// 不直接读DefaultLogger，插件初始化时它可能还没有设置
+func FrameLogger() log.Logger {
+	logger, err := plugin.Get[log.Logger](PluginType, FrameName)
+	if err != nil {
+		return log.DefaultLogger
 	}
-	DefaultLogger = logger.With(zap.String("name", "frame"))
-	return nil
+	return logger
 }
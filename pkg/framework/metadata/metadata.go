@@ -0,0 +1,40 @@
+package metadata
+
+import "context"
+
+// MD 请求元数据，跨服务透传，key统一使用小写
+type MD map[string]string
+
+type metadataKey struct{}
+
+func New(kvs map[string]string) MD {
+	md := make(MD, len(kvs))
+	for key, value := range kvs {
+		md[key] = value
+	}
+	return md
+}
+
+func (md MD) Get(key string) string {
+	return md[key]
+}
+
+func (md MD) Set(key, value string) {
+	md[key] = value
+}
+
+func (md MD) Clone() MD {
+	return New(md)
+}
+
+func NewContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+func FromContext(ctx context.Context) MD {
+	md, ok := ctx.Value(metadataKey{}).(MD)
+	if !ok {
+		return nil
+	}
+	return md
+}
@@ -0,0 +1,159 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"github.com/soulnov23/go-tool/pkg/mysql"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	pluginType = "mysql"
+
+	defaultPingTimeout = 3000 // 单位 毫秒
+	topSlowQueries     = 20   // admin接口展示的慢查询数量
+)
+
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+func init() {
+	plugin.Register(pluginType, &MySQLPlugin{})
+}
+
+type Config struct {
+	DSN                       string   `yaml:"dsn"`
+	MaxIdleConns              int      `yaml:"max_idle_conns"`                // 最大空闲连接数
+	MaxOpenConns              int      `yaml:"max_open_conns"`                // 最大打开连接数
+	ConnMaxLifetime           int64    `yaml:"conn_max_lifetime"`             // 连接重用最大时间 单位 毫秒
+	ConnMaxIdleTime           int64    `yaml:"conn_max_idle_time"`            // 连接空闲最大时间 单位 毫秒
+	PingTimeout               int64    `yaml:"ping_timeout"`                  // 初始化时ping的超时时间 单位 毫秒
+	SlowThreshold             int64    `yaml:"slow_threshold"`                // 慢查询日志阈值 单位 毫秒
+	IgnoreRecordNotFoundError bool     `yaml:"ignore_record_not_found_error"` // 错误日志是否忽略RecordNotFound
+	ParameterizedQueries      bool     `yaml:"parameterized_queries"`         // 日志中的SQL是否使用?占位符代替实际的参数
+	SensitiveColumns          []string `yaml:"sensitive_columns"`             // 日志中这些列的参数替换成******
+	LogLevel                  string   `yaml:"log_level"`                     // SQL日志级别 silent error warn info
+	SampleRate                float64  `yaml:"sample_rate"`                   // 非慢查询的SQL日志采样率 0到1
+	DryRun                    bool     `yaml:"dry_run"`                       // 生成SQL但不执行
+	Tracing                   bool     `yaml:"tracing"`                       // 每条SQL创建链路追踪span
+	Metrics                   bool     `yaml:"metrics"`                       // 记录SQL耗时、错误码和影响行数指标，按指纹聚合慢查询
+	MaxSlowQueries            int      `yaml:"max_slow_queries"`              // 慢查询报告最多保留的指纹数
+	Replicas                  []string `yaml:"replica_dsns"`                  // 从库的dsn，读请求路由到从库
+	ReplicaPolicy             string   `yaml:"replica_policy"`                // 从库选择策略 random round_robin least_in_use
+	HealthCheckInterval       int64    `yaml:"health_check_interval"`         // 从库健康检查间隔 单位 毫秒
+	MaxFailures               int      `yaml:"max_failures"`                  // 从库连续检查失败多少次后摘除
+}
+
+// MySQLPlugin 每个实例创建一个*gorm.DB，通过plugin.Get[*gorm.DB]("mysql", name)获取
+//
+//	plugins:
+//	    mysql:
+//	        orders:
+//	            dsn: root:${MYSQL_PASSWORD}@tcp(127.0.0.1:3306)/orders?charset=utf8mb4&parseTime=true
+type MySQLPlugin struct{}
+
+func (p *MySQLPlugin) Type() string {
+	return pluginType
+}
+
+func (p *MySQLPlugin) DependsOn() []string {
+	return []string{log.PluginType}
+}
+
+func (p *MySQLPlugin) Setup(ctx context.Context, name string, node yaml.Node) (any, error) {
+	config := &Config{}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] invalid config: %v", pluginType, name, err)
+	}
+	if config.DSN == "" {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] dsn is empty", pluginType, name)
+	}
+	opts := []mysql.Option{
+		mysql.WithMaxIdleConns(config.MaxIdleConns),
+		mysql.WithMaxOpenConns(config.MaxOpenConns),
+		mysql.WithConnMaxLifetime(time.Duration(config.ConnMaxLifetime) * time.Millisecond),
+		mysql.WithConnMaxIdleTime(time.Duration(config.ConnMaxIdleTime) * time.Millisecond),
+		mysql.WithIgnoreRecordNotFoundError(config.IgnoreRecordNotFoundError),
+		mysql.WithParameterizedQueries(config.ParameterizedQueries),
+		mysql.WithDryRun(config.DryRun),
+		mysql.WithTracing(config.Tracing),
+		mysql.WithMetrics(config.Metrics),
+		mysql.WithName(name),
+	}
+	if config.MaxSlowQueries > 0 {
+		opts = append(opts, mysql.WithMaxSlowQueries(config.MaxSlowQueries))
+	}
+	if len(config.Replicas) > 0 {
+		opts = append(opts, mysql.WithReplicas(config.Replicas...))
+	}
+	if config.ReplicaPolicy != "" {
+		opts = append(opts, mysql.WithReplicaPolicy(config.ReplicaPolicy))
+	}
+	if config.HealthCheckInterval > 0 || config.MaxFailures > 0 {
+		opts = append(opts, mysql.WithHealthCheck(time.Duration(config.HealthCheckInterval)*time.Millisecond, config.MaxFailures))
+	}
+	if len(config.SensitiveColumns) > 0 {
+		opts = append(opts, mysql.WithSensitiveColumns(config.SensitiveColumns...))
+	}
+	if config.LogLevel != "" {
+		level, ok := logLevels[config.LogLevel]
+		if !ok {
+			return nil, fmt.Errorf("plugin type[%s] name[%s] log_level[%s] not support", pluginType, name, config.LogLevel)
+		}
+		opts = append(opts, mysql.WithLogLevel(level))
+	}
+	if config.SampleRate > 0 {
+		opts = append(opts, mysql.WithSampleRate(config.SampleRate))
+	}
+	if config.SlowThreshold > 0 {
+		opts = append(opts, mysql.WithSlowThreshold(time.Duration(config.SlowThreshold)*time.Millisecond))
+	}
+	pingTimeout := config.PingTimeout
+	if pingTimeout <= 0 {
+		pingTimeout = defaultPingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(pingTimeout)*time.Millisecond)
+	defer cancel()
+	db, err := mysql.New(ctx, config.DSN, log.FrameLogger(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] new mysql: %v", pluginType, name, err)
+	}
+	return db, nil
+}
+
+// Stop 关闭主库和从库的连接池
+func (p *MySQLPlugin) Stop(ctx context.Context, name string) error {
+	db, err := plugin.Get[*gorm.DB](pluginType, name)
+	if err != nil {
+		return err
+	}
+	return mysql.Close(db)
+}
+
+type Stats struct {
+	Pool        *mysql.Stats       `json:"pool"`
+	SlowQueries []*mysql.SlowQuery `json:"slow_queries,omitempty"`
+}
+
+// Stats 主库和从库的连接池状态以及总耗时最大的慢查询，通过admin接口查看
+func (p *MySQLPlugin) Stats(name string) (any, error) {
+	db, err := plugin.Get[*gorm.DB](pluginType, name)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := mysql.GetStats(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Stats{Pool: pool, SlowQueries: mysql.SlowQueries(db, topSlowQueries)}, nil
+}
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetupInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "empty dsn",
+			config: "max_open_conns: 10",
+			want:   "dsn is empty",
+		},
+		{
+			name:   "invalid dsn",
+			config: "dsn: root@127.0.0.1:3306/orders",
+			want:   "new mysql",
+		},
+		{
+			name:   "invalid field",
+			config: "ping_timeout: abc",
+			want:   "invalid config",
+		},
+		{
+			name:   "invalid log level",
+			config: "{dsn: 'root@tcp(127.0.0.1:1)/orders', log_level: debug}",
+			want:   "log_level[debug] not support",
+		},
+		{
+			name:   "unreachable",
+			config: "{dsn: 'root@tcp(127.0.0.1:1)/orders', ping_timeout: 500}",
+			want:   "new mysql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := yaml.Node{}
+			if err := yaml.Unmarshal([]byte(tt.config), &node); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			_, err := (&MySQLPlugin{}).Setup(context.Background(), "orders", *node.Content[0])
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "plugin type[mysql] name[orders]") {
+				t.Errorf("Setup() error = %v, want contains %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,47 +1,301 @@
 package plugin
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultName 框架全局对象使用的实例名，比如registry.default设置registry.DefaultRegistry
+const DefaultName = "default"
+
 var (
-	plugins = map[string]Plugin{}
-	mutex   = sync.Mutex{}
+	plugins   = map[string]Plugin{}         // k=type,v=Plugin
+	instances = map[string]map[string]any{} // k=type,v=(k=name,v=instance)
+	mutex     = sync.Mutex{}
 )
 
+// Plugin 一种类型的插件，同一类型可以配置多个实例
+//
+//	plugins:
+//	    database:
+//	        orders: {...}
+//	        users: {...}
 type Plugin interface {
-	Name() string
-	Setup(node yaml.Node) error
+	Type() string
+	// Setup 根据实例的配置创建实例，返回的实例通过Get获取，ctx超时后应该尽快返回
+	Setup(ctx context.Context, name string, node yaml.Node) (any, error)
 }
 
-func Register(name string, plugin Plugin) {
+func Register(typ string, plugin Plugin) {
 	value := reflect.ValueOf(plugin)
 	if plugin == nil || value.Kind() == reflect.Pointer && value.IsNil() {
 		panic("register nil plugin")
 	}
-	if name == "" {
-		panic("register empty name of plugin")
+	if typ == "" {
+		panic("register empty type of plugin")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	plugins[typ] = plugin
+}
+
+// Types 返回已注册的插件类型，按字母序排列
+func Types() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return slices.Sorted(maps.Keys(plugins))
+}
+
+// Names 返回某个类型已经初始化的实例名，按字母序排列
+func Names(typ string) []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return slices.Sorted(maps.Keys(instances[typ]))
+}
+
+// Get 获取已经初始化的实例，比如plugin.Get[*gorm.DB]("database", "orders")
+func Get[T any](typ string, name string) (T, error) {
+	var zero T
+	mutex.Lock()
+	instance, ok := instances[typ][name]
+	mutex.Unlock()
+	if !ok {
+		return zero, fmt.Errorf("plugin type[%s] name[%s] not found", typ, name)
+	}
+	value, ok := instance.(T)
+	if !ok {
+		return zero, fmt.Errorf("plugin type[%s] name[%s] is %T, not %T", typ, name, instance, zero)
 	}
+	return value, nil
+}
+
+func set(typ string, name string, instance any) {
 	mutex.Lock()
 	defer mutex.Unlock()
-	plugins[name] = plugin
+	if instances[typ] == nil {
+		instances[typ] = map[string]any{}
+	}
+	instances[typ][name] = instance
+}
+
+func remove(typ string, name string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	delete(instances[typ], name)
+}
+
+func lookup(typ string) Plugin {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return plugins[typ]
+}
+
+// Depender 依赖其它类型插件的插件实现该接口，依赖的插件必须配置并且先初始化
+type Depender interface {
+	DependsOn() []string
+}
+
+// Starter Server.Serve时按初始化顺序对每个实例调用
+type Starter interface {
+	Start(ctx context.Context, name string) error
+}
+
+// Stopper 服务退出时按初始化的逆序对每个实例调用
+type Stopper interface {
+	Stop(ctx context.Context, name string) error
+}
+
+// Stater 输出实例的运行状态，比如连接池状态，通过admin接口查看
+type Stater interface {
+	Stats(name string) (any, error)
 }
 
-type Config map[string]yaml.Node
+// Stats 返回实例的运行状态，插件没有实现Stater时返回nil
+func Stats(typ string, name string) (any, error) {
+	stater, ok := lookup(typ).(Stater)
+	if !ok {
+		return nil, nil
+	}
+	return stater.Stats(name)
+}
+
+// Timeouts 每个实例Setup、Start和Stop的超时时间，Plugins中没有配置的类型使用Default
+type Timeouts struct {
+	Default time.Duration
+	Plugins map[string]time.Duration // k=type,v=timeout
+}
+
+func (t Timeouts) get(typ string) time.Duration {
+	if timeout, ok := t.Plugins[typ]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// Key 一个插件实例
+type Key struct {
+	Type string
+	Name string
+}
 
-func (c Config) Setup() error {
-	for name, cfg := range c {
-		plugin := plugins[name]
+func (k Key) String() string {
+	return k.Type + "." + k.Name
+}
+
+type Config map[string]map[string]yaml.Node // k=type,v=(k=name,v=config)
+
+// Order 返回按依赖拓扑排序后的插件类型，依赖不存在或者循环依赖时返回错误
+func (c Config) Order() ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	states := make(map[string]int, len(c))
+	order := make([]string, 0, len(c))
+	var visit func(typ string, path []string) error
+	visit = func(typ string, path []string) error {
+		switch states[typ] {
+		case visiting:
+			return fmt.Errorf("plugin type[%s] cyclic dependency: %s", typ, strings.Join(append(path, typ), " -> "))
+		case visited:
+			return nil
+		}
+		plugin := lookup(typ)
 		if plugin == nil {
-			return fmt.Errorf("plugin name[%s] not found", name)
+			return fmt.Errorf("plugin type[%s] not found", typ)
+		}
+		states[typ] = visiting
+		if depender, ok := plugin.(Depender); ok {
+			for _, dependency := range depender.DependsOn() {
+				if _, ok := c[dependency]; !ok {
+					return fmt.Errorf("plugin type[%s] depends on plugin type[%s] not configured", typ, dependency)
+				}
+				if err := visit(dependency, append(path, typ)); err != nil {
+					return err
+				}
+			}
 		}
-		if err := plugin.Setup(cfg); err != nil {
+		states[typ] = visited
+		order = append(order, typ)
+		return nil
+	}
+	for _, typ := range slices.Sorted(maps.Keys(c)) {
+		if err := visit(typ, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Setup 按依赖顺序初始化所有实例，同一类型的实例按名字排序，返回的顺序用于Start和Stop，
+// 某个实例失败时按逆序停止已经初始化的实例
+func (c Config) Setup(ctx context.Context, timeouts Timeouts) ([]Key, error) {
+	order, err := c.Order()
+	if err != nil {
+		return nil, err
+	}
+	var keys []Key
+	for _, typ := range order {
+		plugin := lookup(typ)
+		for _, name := range slices.Sorted(maps.Keys(c[typ])) {
+			key := Key{Type: typ, Name: name}
+			node := c[typ][name]
+			var instance any
+			if err := run(ctx, key, "setup", timeouts.get(typ), func(ctx context.Context) error {
+				var err error
+				instance, err = plugin.Setup(ctx, name, node)
+				return err
+			}, func() {
+				// 超时后才返回的实例没有人使用，停止它避免泄漏连接等资源
+				set(typ, name, instance)
+				_ = Stop(context.Background(), []Key{key}, timeouts)
+				remove(typ, name)
+			}); err != nil {
+				if stopErr := Stop(context.WithoutCancel(ctx), keys, timeouts); stopErr != nil {
+					err = errors.Join(err, stopErr)
+				}
+				for _, key := range keys {
+					remove(key.Type, key.Name)
+				}
+				return nil, err
+			}
+			set(typ, name, instance)
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Start 按顺序调用实现了Starter的插件，出错时停止
+func Start(ctx context.Context, keys []Key, timeouts Timeouts) error {
+	for _, key := range keys {
+		starter, ok := lookup(key.Type).(Starter)
+		if !ok {
+			continue
+		}
+		if err := run(ctx, key, "start", timeouts.get(key.Type), func(ctx context.Context) error {
+			if err := starter.Start(ctx, key.Name); err != nil {
+				return fmt.Errorf("plugin %s start: %v", key, err)
+			}
+			return nil
+		}, nil); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// Stop 按逆序调用实现了Stopper的插件，某个实例出错时继续停止其它实例
+func Stop(ctx context.Context, keys []Key, timeouts Timeouts) error {
+	var errs []error
+	for _, key := range slices.Backward(keys) {
+		stopper, ok := lookup(key.Type).(Stopper)
+		if !ok {
+			continue
+		}
+		if err := run(ctx, key, "stop", timeouts.get(key.Type), func(ctx context.Context) error {
+			if err := stopper.Stop(ctx, key.Name); err != nil {
+				return fmt.Errorf("plugin %s stop: %v", key, err)
+			}
+			return nil
+		}, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run 超时后不再等待fn返回，fn应该尽量响应ctx的取消，late不为nil时在超时的fn成功返回后调用
+func run(ctx context.Context, key Key, stage string, timeout time.Duration, fn func(ctx context.Context) error, late func()) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if late != nil {
+			go func() {
+				if err := <-done; err == nil {
+					late()
+				}
+			}()
+		}
+		return fmt.Errorf("plugin %s %s: %w", key, stage, ctx.Err())
+	}
+}
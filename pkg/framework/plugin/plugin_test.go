@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type testPlugin struct {
+	typ       string
+	dependsOn []string
+	delay     time.Duration
+	detached  bool // Setup不响应ctx的取消
+	events    *[]string
+}
+
+func (p *testPlugin) Type() string {
+	return p.typ
+}
+
+func (p *testPlugin) DependsOn() []string {
+	return p.dependsOn
+}
+
+func (p *testPlugin) Setup(ctx context.Context, name string, node yaml.Node) (any, error) {
+	if p.detached {
+		time.Sleep(p.delay)
+	} else {
+		select {
+		case <-time.After(p.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	*p.events = append(*p.events, "setup "+p.typ+"."+name)
+	config := map[string]string{}
+	if err := node.Decode(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (p *testPlugin) Start(ctx context.Context, name string) error {
+	*p.events = append(*p.events, "start "+p.typ+"."+name)
+	return nil
+}
+
+func (p *testPlugin) Stop(ctx context.Context, name string) error {
+	*p.events = append(*p.events, "stop "+p.typ+"."+name)
+	if p.typ == "test_log" {
+		return errors.New("sync failed")
+	}
+	return nil
+}
+
+func newConfig(t *testing.T, config string) Config {
+	t.Helper()
+	c := Config{}
+	if err := yaml.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	return c
+}
+
+func TestLifecycle(t *testing.T) {
+	var events []string
+	Register("test_log", &testPlugin{typ: "test_log", events: &events})
+	Register("test_database", &testPlugin{typ: "test_database", dependsOn: []string{"test_log", "test_registry"}, events: &events})
+	Register("test_registry", &testPlugin{typ: "test_registry", dependsOn: []string{"test_log"}, events: &events})
+
+	config := newConfig(t, `
+test_database:
+    users:
+        dsn: users
+    orders:
+        dsn: orders
+test_registry:
+    default:
+        type: static
+test_log:
+    default:
+        level: info
+`)
+	keys, err := config.Setup(context.Background(), Timeouts{Default: time.Second})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	want := []Key{{"test_log", "default"}, {"test_registry", "default"}, {"test_database", "orders"}, {"test_database", "users"}}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("Setup() keys = %v, want %v", keys, want)
+	}
+	if err := Start(context.Background(), keys, Timeouts{}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := Stop(context.Background(), keys, Timeouts{}); err == nil || !strings.Contains(err.Error(), "test_log.default") {
+		t.Errorf("Stop() error = %v, want test_log.default error", err)
+	}
+	wantEvents := []string{
+		"setup test_log.default", "setup test_registry.default", "setup test_database.orders", "setup test_database.users",
+		"start test_log.default", "start test_registry.default", "start test_database.orders", "start test_database.users",
+		"stop test_database.users", "stop test_database.orders", "stop test_registry.default", "stop test_log.default",
+	}
+	if !slices.Equal(events, wantEvents) {
+		t.Errorf("events = %v, want %v", events, wantEvents)
+	}
+
+	orders, err := Get[map[string]string]("test_database", "orders")
+	if err != nil || orders["dsn"] != "orders" {
+		t.Errorf("Get(test_database, orders) = %v, %v", orders, err)
+	}
+	if _, err := Get[string]("test_database", "orders"); err == nil {
+		t.Errorf("Get[string](test_database, orders) error = nil, want type error")
+	}
+	if _, err := Get[map[string]string]("test_database", "payments"); err == nil {
+		t.Errorf("Get(test_database, payments) error = nil, want not found")
+	}
+	if got := Names("test_database"); !slices.Equal(got, []string{"orders", "users"}) {
+		t.Errorf("Names(test_database) = %v", got)
+	}
+}
+
+func TestOrderInvalid(t *testing.T) {
+	var events []string
+	Register("test_a", &testPlugin{typ: "test_a", dependsOn: []string{"test_b"}, events: &events})
+	Register("test_b", &testPlugin{typ: "test_b", dependsOn: []string{"test_a"}, events: &events})
+	Register("test_c", &testPlugin{typ: "test_c", dependsOn: []string{"test_d"}, events: &events})
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "cycle", config: Config{"test_a": nil, "test_b": nil}, wantErr: "cyclic dependency: test_a -> test_b -> test_a"},
+		{name: "dependency not configured", config: Config{"test_c": nil}, wantErr: "depends on plugin type[test_d] not configured"},
+		{name: "not found", config: Config{"test_unknown": nil}, wantErr: "plugin type[test_unknown] not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.config.Order(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Order() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetupTimeout(t *testing.T) {
+	var events []string
+	Register("test_slow", &testPlugin{typ: "test_slow", delay: 100 * time.Millisecond, events: &events})
+	config := Config{"test_slow": {"default": yaml.Node{}}}
+	_, err := config.Setup(context.Background(), Timeouts{Default: time.Second, Plugins: map[string]time.Duration{"test_slow": 10 * time.Millisecond}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Setup() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// 超时后Setup收到ctx取消不再继续初始化
+	time.Sleep(200 * time.Millisecond)
+	if len(events) != 0 {
+		t.Errorf("events after timeout = %v, want none", events)
+	}
+}
+
+func TestSetupRollback(t *testing.T) {
+	var events []string
+	Register("test_first", &testPlugin{typ: "test_first", events: &events})
+	Register("test_second", &testPlugin{typ: "test_second", dependsOn: []string{"test_first"}, events: &events})
+	config := newConfig(t, `
+test_first:
+    a:
+        dsn: a
+    b:
+        dsn: b
+test_second:
+    default: invalid
+`)
+	if _, err := config.Setup(context.Background(), Timeouts{Default: time.Second}); err == nil {
+		t.Fatalf("Setup() error = nil, want decode error")
+	}
+	wantEvents := []string{"setup test_first.a", "setup test_first.b", "setup test_second.default", "stop test_first.b", "stop test_first.a"}
+	if !slices.Equal(events, wantEvents) {
+		t.Errorf("events = %v, want %v", events, wantEvents)
+	}
+	if got := Names("test_first"); len(got) != 0 {
+		t.Errorf("Names(test_first) = %v, want none", got)
+	}
+}
+
+func TestSetupLate(t *testing.T) {
+	var events []string
+	Register("test_late", &testPlugin{typ: "test_late", delay: 50 * time.Millisecond, detached: true, events: &events})
+	config := Config{"test_late": {"default": yaml.Node{}}}
+	_, err := config.Setup(context.Background(), Timeouts{Default: 10 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Setup() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// 超时后才返回的实例要被停止，并且不能通过Get获取
+	time.Sleep(200 * time.Millisecond)
+	wantEvents := []string{"setup test_late.default", "stop test_late.default"}
+	if !slices.Equal(events, wantEvents) {
+		t.Errorf("events = %v, want %v", events, wantEvents)
+	}
+	if _, err := Get[map[string]string]("test_late", "default"); err == nil {
+		t.Errorf("Get(test_late, default) error = nil, want not found")
+	}
+}
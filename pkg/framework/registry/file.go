@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v3"
+)
+
+const fileType = "file"
+
+func init() {
+	RegisterFactory(fileType, newFileFromConfig)
+}
+
+type FileConfig struct {
+	Path string `yaml:"path"`
+}
+
+// File 使用本地yaml文件保存服务地址，多个进程通过flock互斥修改，适合单机部署和离线测试
+//
+//	rpc_service:
+//	    - address: 127.0.0.1:6666
+//	      network: tcp
+type File struct {
+	path string
+
+	mutex    sync.Mutex
+	info     os.FileInfo
+	services map[string][]*Node
+}
+
+func NewFile(path string) *File {
+	return &File{
+		path: path,
+	}
+}
+
+func newFileFromConfig(node yaml.Node) (Backend, error) {
+	config := &FileConfig{}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid file config: %v", err)
+	}
+	if config.Path == "" {
+		return nil, errors.New("file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create registry directory: %v", err)
+	}
+	return NewFile(config.Path), nil
+}
+
+func (f *File) Register(ctx context.Context, node *Node) error {
+	return f.update(func(services map[string][]*Node) {
+		services[node.Name] = upsertNode(services[node.Name], node)
+	})
+}
+
+func (f *File) Deregister(ctx context.Context, node *Node) error {
+	return f.update(func(services map[string][]*Node) {
+		services[node.Name] = removeNode(services[node.Name], node)
+		if len(services[node.Name]) == 0 {
+			delete(services, node.Name)
+		}
+	})
+}
+
+// Resolve 文件被替换或者修改时间变化时才重新加载，每次修改都rename新文件，不依赖修改时间的精度
+func (f *File) Resolve(ctx context.Context, name string) ([]*Node, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("resolve %s: %w", name, ErrNotFound)
+		}
+		return nil, fmt.Errorf("stat registry file: %v", err)
+	}
+	if f.services == nil || !os.SameFile(info, f.info) || !info.ModTime().Equal(f.info.ModTime()) {
+		services, err := f.read()
+		if err != nil {
+			return nil, err
+		}
+		f.services = services
+		f.info = info
+	}
+	nodes := f.services[name]
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("resolve %s: %w", name, ErrNotFound)
+	}
+	return append([]*Node(nil), nodes...), nil
+}
+
+func (f *File) update(fn func(services map[string][]*Node)) error {
+	lockFile, err := os.OpenFile(f.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("open registry lock file: %v", err)
+	}
+	defer lockFile.Close()
+	if err := unix.Flock(int(lockFile.Fd()), unix.LOCK_EX); err != nil {
+		return fmt.Errorf("flock registry lock file: %v", err)
+	}
+	defer unix.Flock(int(lockFile.Fd()), unix.LOCK_UN)
+
+	services, err := f.read()
+	if err != nil {
+		return err
+	}
+	fn(services)
+	buffer, err := yaml.Marshal(services)
+	if err != nil {
+		return fmt.Errorf("yaml marshal registry: %v", err)
+	}
+	// 先写临时文件再rename，读方不会读到写了一半的文件
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buffer, 0o644); err != nil {
+		return fmt.Errorf("write registry file: %v", err)
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("rename registry file: %v", err)
+	}
+	return nil
+}
+
+func (f *File) read() (map[string][]*Node, error) {
+	services := map[string][]*Node{}
+	buffer, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return services, nil
+		}
+		return nil, fmt.Errorf("read registry file: %v", err)
+	}
+	if err := yaml.Unmarshal(buffer, &services); err != nil {
+		return nil, fmt.Errorf("yaml unmarshal registry file: %v", err)
+	}
+	if services == nil {
+		services = map[string][]*Node{}
+	}
+	for name, nodes := range services {
+		for _, node := range nodes {
+			node.Name = name
+		}
+	}
+	return services, nil
+}
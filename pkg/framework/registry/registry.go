@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+const pluginType = "registry"
+
+var (
+	// DefaultRegistry 由registry插件的default实例设置，服务启动时注册，退出时注销
+	DefaultRegistry Registry = noopBackend{}
+	// DefaultDiscovery 客户端通过服务名解析地址
+	DefaultDiscovery Discovery = noopBackend{}
+
+	ErrNotFound = errors.New("service not found")
+
+	factories = map[string]Factory{}
+	mutex     = sync.RWMutex{}
+)
+
+func init() {
+	plugin.Register(pluginType, &RegistryPlugin{})
+}
+
+// Node 一个服务实例
+type Node struct {
+	Name     string            `yaml:"name" json:"name"`
+	Address  string            `yaml:"address" json:"address"`
+	Network  string            `yaml:"network" json:"network"`
+	Weight   int               `yaml:"weight" json:"weight"`
+	Metadata map[string]string `yaml:"metadata" json:"metadata"`
+}
+
+type Registry interface {
+	Register(ctx context.Context, node *Node) error
+	Deregister(ctx context.Context, node *Node) error
+}
+
+type Discovery interface {
+	Resolve(ctx context.Context, name string) ([]*Node, error)
+}
+
+// Backend 同时实现注册和发现，etcd、consul等后端实现Backend并调用RegisterFactory即可接入
+type Backend interface {
+	Registry
+	Discovery
+}
+
+// Factory 根据registry插件的yaml配置创建后端
+type Factory func(node yaml.Node) (Backend, error)
+
+func RegisterFactory(name string, factory Factory) {
+	value := reflect.ValueOf(factory)
+	if factory == nil || value.Kind() == reflect.Pointer && value.IsNil() {
+		panic("register nil registry factory")
+	}
+	if name == "" {
+		panic("register empty name of registry factory")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	factories[name] = factory
+}
+
+type Config struct {
+	Type string `yaml:"type"` // file static
+}
+
+type RegistryPlugin struct{}
+
+func (p *RegistryPlugin) Type() string {
+	return pluginType
+}
+
+// Setup default实例设置DefaultRegistry和DefaultDiscovery，其它实例通过plugin.Get[registry.Backend]获取
+func (p *RegistryPlugin) Setup(ctx context.Context, name string, node yaml.Node) (any, error) {
+	config := &Config{}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] invalid config: %v", pluginType, name, err)
+	}
+	mutex.RLock()
+	factory, ok := factories[config.Type]
+	mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] registry type[%s] not support", pluginType, name, config.Type)
+	}
+	backend, err := factory(node)
+	if err != nil {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] new %s registry: %v", pluginType, name, config.Type, err)
+	}
+	if name == plugin.DefaultName {
+		DefaultRegistry = backend
+		DefaultDiscovery = backend
+	}
+	return backend, nil
+}
+
+type noopBackend struct{}
+
+func (noopBackend) Register(ctx context.Context, node *Node) error {
+	return nil
+}
+
+func (noopBackend) Deregister(ctx context.Context, node *Node) error {
+	return nil
+}
+
+func (noopBackend) Resolve(ctx context.Context, name string) ([]*Node, error) {
+	return nil, fmt.Errorf("resolve %s: %w", name, ErrNotFound)
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	a := NewFile(path)
+	b := NewFile(path)
+
+	if _, err := b.Resolve(ctx, "rpc_service"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Resolve() before register error = %v, want ErrNotFound", err)
+	}
+	nodes := []*Node{
+		{Name: "rpc_service", Address: "127.0.0.1:6666", Network: "tcp"},
+		{Name: "rpc_service", Address: "127.0.0.1:6667", Network: "tcp"},
+		{Name: "http_service", Address: "127.0.0.1:8888", Network: "tcp"},
+	}
+	for _, node := range nodes {
+		if err := a.Register(ctx, node); err != nil {
+			t.Fatalf("Register(%v): %v", node, err)
+		}
+	}
+	// 重复注册不会产生重复的实例
+	if err := a.Register(ctx, nodes[0]); err != nil {
+		t.Fatalf("Register(%v): %v", nodes[0], err)
+	}
+	got, err := b.Resolve(ctx, "rpc_service")
+	if err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "rpc_service" {
+		t.Fatalf("Resolve() = %v, want 2 rpc_service nodes", got)
+	}
+
+	if err := a.Deregister(ctx, nodes[0]); err != nil {
+		t.Fatalf("Deregister(): %v", err)
+	}
+	got, err = b.Resolve(ctx, "rpc_service")
+	if err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	if len(got) != 1 || got[0].Address != "127.0.0.1:6667" {
+		t.Fatalf("Resolve() after deregister = %v", got)
+	}
+}
+
+func TestStatic(t *testing.T) {
+	config := `
+type: static
+services:
+    rpc_service:
+        - address: 127.0.0.1:6666
+          network: tcp
+          weight: 100
+`
+	node := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(config), &node); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	backend, err := newStaticFromConfig(*node.Content[0])
+	if err != nil {
+		t.Fatalf("newStaticFromConfig: %v", err)
+	}
+	ctx := context.Background()
+	got, err := backend.Resolve(ctx, "rpc_service")
+	if err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "rpc_service" || got[0].Weight != 100 {
+		t.Fatalf("Resolve() = %v", got)
+	}
+
+	local := &Node{Name: "http_service", Address: "127.0.0.1:8888", Network: "tcp"}
+	if err := backend.Register(ctx, local); err != nil {
+		t.Fatalf("Register(): %v", err)
+	}
+	if got, err := backend.Resolve(ctx, "http_service"); err != nil || len(got) != 1 {
+		t.Fatalf("Resolve() = %v, %v", got, err)
+	}
+	if err := backend.Deregister(ctx, local); err != nil {
+		t.Fatalf("Deregister(): %v", err)
+	}
+	if _, err := backend.Resolve(ctx, "http_service"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Resolve() after deregister error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const staticType = "static"
+
+func init() {
+	RegisterFactory(staticType, newStaticFromConfig)
+}
+
+type StaticConfig struct {
+	Services map[string][]*Node `yaml:"services"` // k=service_name,v=nodes
+}
+
+// Static 使用配置中固定的地址列表，本进程注册的服务也能被解析到
+type Static struct {
+	mutex    sync.RWMutex
+	services map[string][]*Node
+}
+
+func NewStatic(services map[string][]*Node) *Static {
+	s := &Static{
+		services: make(map[string][]*Node, len(services)),
+	}
+	for name, nodes := range services {
+		for _, node := range nodes {
+			node.Name = name
+			s.services[name] = append(s.services[name], node)
+		}
+	}
+	return s
+}
+
+func newStaticFromConfig(node yaml.Node) (Backend, error) {
+	config := &StaticConfig{}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid static config: %v", err)
+	}
+	return NewStatic(config.Services), nil
+}
+
+func (s *Static) Register(ctx context.Context, node *Node) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.services[node.Name] = upsertNode(s.services[node.Name], node)
+	return nil
+}
+
+func (s *Static) Deregister(ctx context.Context, node *Node) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.services[node.Name] = removeNode(s.services[node.Name], node)
+	return nil
+}
+
+func (s *Static) Resolve(ctx context.Context, name string) ([]*Node, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	nodes := s.services[name]
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("resolve %s: %w", name, ErrNotFound)
+	}
+	return append([]*Node(nil), nodes...), nil
+}
+
+func upsertNode(nodes []*Node, node *Node) []*Node {
+	for i, n := range nodes {
+		if n.Address == node.Address && n.Network == node.Network {
+			nodes[i] = node
+			return nodes
+		}
+	}
+	return append(nodes, node)
+}
+
+func removeNode(nodes []*Node, node *Node) []*Node {
+	result := nodes[:0]
+	for _, n := range nodes {
+		if n.Address == node.Address && n.Network == node.Network {
+			continue
+		}
+		result = append(result, n)
+	}
+	return result
+}
@@ -1,13 +1,20 @@
 package framework
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	_ "github.com/soulnov23/go-tool/pkg/framework/client"
+	"github.com/soulnov23/go-tool/pkg/framework/expand"
+	"github.com/soulnov23/go-tool/pkg/framework/limiter"
 	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+	_ "github.com/soulnov23/go-tool/pkg/framework/tracing"
 	"github.com/soulnov23/go-tool/pkg/pprof"
 	"github.com/soulnov23/go-tool/pkg/utils"
 	"go.uber.org/zap"
@@ -24,24 +31,32 @@ type Server struct {
 	updateGOMAXPROCSInterval time.Duration
 	maxCloseWaitTime         time.Duration // max waiting time when closing server
 	*pprof.ProfileProfiler
+	adminToken string
 
 	services map[string]*service // k=service_name,v=Service
+	config   *Config
+	shutdown chan struct{} // admin接口触发退出
+
+	pluginKeys     []plugin.Key // 插件实例的初始化顺序，按该顺序Start，逆序Stop
+	pluginTimeouts plugin.Timeouts
 }
 
-func New(configPath string) *Server {
-	config, err := loadConfig(configPath)
+// New 加载configPath的配置，再按顺序合并sources，比如环境配置文件、环境变量和命令行参数
+func New(configPath string, sources ...ConfigSource) *Server {
+	config, err := loadConfig(configPath, sources...)
 	if err != nil {
 		panic(fmt.Sprintf("loadConfig: %v", err))
 	}
 
-	if config.Server == nil {
-		panic("server is empty")
+	pluginTimeouts := plugin.Timeouts{
+		Default: time.Duration(config.Server.PluginTimeout) * time.Millisecond,
+		Plugins: make(map[string]time.Duration, len(config.Server.PluginTimeouts)),
 	}
-
-	if config.Plugins == nil {
-		panic("plugins is empty")
+	for name, timeout := range config.Server.PluginTimeouts {
+		pluginTimeouts.Plugins[name] = time.Duration(timeout) * time.Millisecond
 	}
-	if err := config.Plugins.Setup(); err != nil {
+	pluginKeys, err := config.Plugins.Setup(context.Background(), pluginTimeouts)
+	if err != nil {
 		panic(fmt.Sprintf("config.Plugins.Setup: %v", err))
 	}
 
@@ -49,6 +64,10 @@ func New(configPath string) *Server {
 		updateGOMAXPROCSInterval: time.Duration(config.Server.UpdateGOMAXPROCSInterval) * time.Millisecond,
 		maxCloseWaitTime:         time.Duration(config.Server.MaxCloseWaitTime) * time.Millisecond,
 		services:                 make(map[string]*service),
+		config:                   config,
+		pluginKeys:               pluginKeys,
+		pluginTimeouts:           pluginTimeouts,
+		shutdown:                 make(chan struct{}, 1),
 	}
 
 	if config.ProfileProfiler != nil {
@@ -59,28 +78,57 @@ func New(configPath string) *Server {
 			pprof.WithIdleTimeout(time.Duration(config.ProfileProfiler.IdleTimeout) * time.Millisecond),
 		}
 		server.ProfileProfiler = pprof.New(opts...)
+		server.adminToken = config.ProfileProfiler.AdminToken
+		server.registerAdmin()
 	}
 
 	for _, serviceConfig := range config.Server.Services {
-		server.services[serviceConfig.Name] = newService(serviceConfig.Name, serviceConfig.Address, serviceConfig.Network, serviceConfig.Protocol, time.Duration(serviceConfig.Timeout)*time.Millisecond)
+		serviceLimiter, err := limiter.New(serviceConfig.Name, serviceConfig.Limiter)
+		if err != nil {
+			panic(fmt.Sprintf("limiter.New: %v", err))
+		}
+		server.services[serviceConfig.Name] = newService(serviceConfig.Name, serviceConfig.Address, serviceConfig.Network, serviceConfig.Protocol, time.Duration(serviceConfig.Timeout)*time.Millisecond, serviceLimiter)
 	}
 
 	return server
 }
 
-func loadConfig(configPath string) (*Config, error) {
-	buffer, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("read config path: %v", err)
+// loadConfig 依次合并配置文件和sources，展开引用后检查配置
+func loadConfig(configPath string, sources ...ConfigSource) (*Config, error) {
+	node := &yaml.Node{}
+	for _, source := range append([]ConfigSource{FileSource(configPath)}, sources...) {
+		if err := source.Merge(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := expand.Node(node); err != nil {
+		return nil, fmt.Errorf("expand config: %v", err)
 	}
-	buffer = utils.StringToBytes(os.ExpandEnv(utils.BytesToString(buffer)))
 	config := &Config{}
-	if err = yaml.Unmarshal(buffer, config); err != nil {
-		return nil, fmt.Errorf("yaml unmarshal config: %v", err)
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("yaml decode config: %v", err)
+	}
+	if err := config.validate(node); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%v", err)
 	}
 	return config, nil
 }
 
+// CheckConfig 加载并检查配置，不初始化插件和服务
+func CheckConfig(configPath string, sources ...ConfigSource) error {
+	_, err := loadConfig(configPath, sources...)
+	return err
+}
+
+// ConfigYAML 返回合并后生效的配置，敏感字段替换成******
+func ConfigYAML(configPath string, sources ...ConfigSource) ([]byte, error) {
+	config, err := loadConfig(configPath, sources...)
+	if err != nil {
+		return nil, err
+	}
+	return marshalConfig(config)
+}
+
 func (s *Server) Register(serviceName string, rpcName string, handler Handler) error {
 	service, ok := s.services[serviceName]
 	if !ok {
@@ -102,17 +150,49 @@ func (s *Server) Serve() error {
 		}()
 	}
 
+	if err := plugin.Start(context.Background(), s.pluginKeys, s.pluginTimeouts); err != nil {
+		log.DefaultLogger.FatalFields("plugin start", zap.Error(err))
+		return err
+	}
+
+	// 所有服务都启动成功后再注册，任何一步失败时注销已经注册的实例，关闭已经启动的服务
+	served := make([]*service, 0, len(s.services))
+	registered := make([]*service, 0, len(s.services))
+	defer func() {
+		// 先注销再关闭，避免客户端继续解析到正在退出的实例
+		for _, service := range registered {
+			if err := registry.DefaultRegistry.Deregister(context.Background(), service.node()); err != nil {
+				log.DefaultLogger.ErrorFields("registry deregister", zap.String("service_name", service.name), zap.Error(err))
+			}
+		}
+		for _, service := range served {
+			service.close()
+		}
+		ctx := context.Background()
+		if s.maxCloseWaitTime > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.maxCloseWaitTime)
+			defer cancel()
+		}
+		if err := plugin.Stop(ctx, s.pluginKeys, s.pluginTimeouts); err != nil {
+			log.DefaultLogger.ErrorFields("plugin stop", zap.Error(err))
+		}
+	}()
 	for name, service := range s.services {
 		if err := service.serve(); err != nil {
-			log.DefaultLogger.FatalFields("service serve", zap.String("service_name", name), zap.Error(err))
+			log.DefaultLogger.ErrorFields("service serve", zap.String("service_name", name), zap.Error(err))
 			return err
 		}
+		served = append(served, service)
 	}
-	defer func() {
-		for _, service := range s.services {
-			service.close()
+	for name, service := range s.services {
+		if err := registry.DefaultRegistry.Register(context.Background(), service.node()); err != nil {
+			log.DefaultLogger.ErrorFields("registry register", zap.String("service_name", name), zap.Error(err))
+			return err
 		}
-	}()
+		registered = append(registered, service)
+		log.DefaultLogger.InfoFields("registry register", zap.Reflect("node", service.node()))
+	}
 
 	signalClose := make(chan os.Signal, 1)
 	signal.Notify(signalClose, DefaultServerCloseSIG...)
@@ -127,6 +207,8 @@ func (s *Server) Serve() error {
 		log.DefaultLogger.InfoFields("signal hot restart", zap.String("sig", sig.String()))
 	case sig := <-signalTrigger:
 		log.DefaultLogger.InfoFields("signal trigger", zap.String("sig", sig.String()))
+	case <-s.shutdown:
+		log.DefaultLogger.InfoFields("admin shutdown")
 	}
 	return nil
 }
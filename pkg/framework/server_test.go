@@ -0,0 +1,62 @@
+package framework
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+	pkglog "github.com/soulnov23/go-tool/pkg/log"
+)
+
+type fakeRegistry struct {
+	mutex sync.Mutex
+	nodes map[string]*registry.Node // k=service_name
+}
+
+func (r *fakeRegistry) Register(ctx context.Context, node *registry.Node) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.nodes[node.Name] = node
+	return nil
+}
+
+func (r *fakeRegistry) Deregister(ctx context.Context, node *registry.Node) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.nodes, node.Name)
+	return nil
+}
+
+// TestServeFailure 一个服务启动失败时其它服务不注册，已经启动的服务关闭
+func TestServeFailure(t *testing.T) {
+	log.DefaultLogger = pkglog.DefaultLogger
+	fake := &fakeRegistry{nodes: make(map[string]*registry.Node)}
+	defaultRegistry := registry.DefaultRegistry
+	registry.DefaultRegistry = fake
+	defer func() { registry.DefaultRegistry = defaultRegistry }()
+
+	// 端口被占用，bind失败
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer listener.Close()
+	okService := newService("ok_service", freeAddress(t), "tcp", "rpc", time.Second, nil)
+	failService := newService("fail_service", listener.Addr().String(), "tcp", "rpc", time.Second, nil)
+	s := &Server{
+		updateGOMAXPROCSInterval: time.Minute,
+		services:                 map[string]*service{okService.name: okService, failService.name: failService},
+		config:                   &Config{},
+		shutdown:                 make(chan struct{}, 1),
+	}
+	if err := s.Serve(); err == nil {
+		t.Fatal("Serve() error = nil, want bind error")
+	}
+	if len(fake.nodes) != 0 {
+		t.Errorf("registered nodes = %v, want none", fake.nodes)
+	}
+}
@@ -3,32 +3,46 @@ package framework
 import (
 	"context"
 	"fmt"
+	"net"
+	"slices"
 	"time"
 
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/codec"
+	"github.com/soulnov23/go-tool/pkg/framework/limiter"
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/metadata"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
 	"github.com/soulnov23/go-tool/pkg/framework/transport"
+	"github.com/soulnov23/go-tool/pkg/tracing"
+	"github.com/soulnov23/go-tool/pkg/utils"
+	"go.uber.org/zap"
 )
 
 type Handler func(ctx context.Context, request string) (response string, err error)
 
 type service struct {
-	name     string
-	address  string
-	network  string
-	protocol string
-	timeout  time.Duration
+	name      string
+	address   string
+	network   string
+	protocol  string
+	timeout   time.Duration
+	limiter   *limiter.Limiter
+	advertise string // 注册到注册中心的地址
 
 	serverTransport transport.ServerTransport
 
 	handlers map[string]Handler // rpc_name => Handler
 }
 
-func newService(name, address, network, protocol string, timeout time.Duration) *service {
+func newService(name, address, network, protocol string, timeout time.Duration, limiter *limiter.Limiter) *service {
 	return &service{
 		name:     name,
 		address:  address,
 		network:  network,
 		protocol: protocol,
 		timeout:  timeout,
+		limiter:  limiter,
 		handlers: make(map[string]Handler),
 	}
 }
@@ -38,14 +52,119 @@ func (s *service) register(rpcName string, handler Handler) error {
 	return nil
 }
 
+func (s *service) rpcNames() []string {
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// handle 分发请求到rpc对应的handler，每个请求创建一个server span，超过限流的请求不执行handler
+func (s *service) handle(ctx context.Context, rpcName string, request string) (response string, err error) {
+	handler, ok := s.handlers[rpcName]
+	if !ok {
+		return "", ErrRPCNotFound.Clone().WithMessageValues(s.name + "/" + rpcName)
+	}
+	md := metadata.FromContext(ctx)
+	ctx, span := tracing.DefaultTracer.StartServerSpan(ctx, s.name+"/"+rpcName, md)
+	defer span.End()
+	span.SetAttribute("rpc.service", s.name)
+	span.SetAttribute("rpc.method", rpcName)
+	span.SetAttribute("rpc.system", s.protocol)
+	release, err := s.limiter.Allow(rpcName, md)
+	if err != nil {
+		span.SetError(err)
+		return "", err
+	}
+	// handler panic时由transport recover，这里也要归还并发名额，否则名额会一直被占用
+	defer func() {
+		if p := recover(); p != nil {
+			release(ErrInternal)
+			span.SetError(ErrInternal)
+			panic(p)
+		}
+		release(err)
+		span.SetError(err)
+		// handler的错误日志带上trace_id和span_id，方便按链路检索
+		if err != nil && log.DefaultLogger != nil {
+			tracing.Logger(ctx, log.DefaultLogger).ErrorFields("handler failed", zap.String("service", s.name), zap.String("rpc", rpcName), zap.Error(err))
+		}
+	}()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return handler(ctx, request)
+}
+
+// serveFrame 解码请求帧中的rpc名称和元数据后调用handle，handler返回的错误不是errors.Error时按500返回
+func (s *service) serveFrame(ctx context.Context, frame []byte) []byte {
+	request, err := codec.DecodeRequest(frame)
+	if err != nil {
+		return codec.EncodeResponse(&codec.Response{Error: ErrBadRequest.Clone().WithMessageValues(err.Error()).Error()})
+	}
+	ctx = metadata.NewContext(ctx, request.Metadata)
+	response, err := s.handle(ctx, request.RPCName, utils.BytesToString(request.Body))
+	if err != nil {
+		e := errors.FromError(err)
+		if e == nil {
+			e = ErrInternal.Clone().WithMessageValues(err.Error())
+		}
+		return codec.EncodeResponse(&codec.Response{Error: e.Error()})
+	}
+	return codec.EncodeResponse(&codec.Response{Body: utils.StringToBytes(response)})
+}
+
 func (s *service) serve() error {
-	s.serverTransport = transport.NewServerTransport(s.address, s.network, s.protocol)
+	advertise, err := advertiseAddress(s.address, s.network)
+	if err != nil {
+		return err
+	}
+	s.advertise = advertise
+	s.serverTransport = transport.NewServerTransport(s.address, s.network, s.protocol, transport.WithHandler(s.serveFrame))
 	if s.serverTransport == nil {
 		return fmt.Errorf("network[%s] not support", s.network)
 	}
 	return s.serverTransport.ListenAndServe()
 }
 
+func (s *service) node() *registry.Node {
+	return &registry.Node{
+		Name:    s.name,
+		Address: s.advertise,
+		Network: s.network,
+	}
+}
+
+// advertiseAddress 监听0.0.0.0或者::时换成本机第一个非回环的ip，否则客户端无法连接，tcp6时取ipv6
+func advertiseAddress(address string, network string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("net.SplitHostPort[%s]: %v", address, err)
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return address, nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", fmt.Errorf("net.InterfaceAddrs: %v", err)
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipnet.IP.To4() == nil) != (network == "tcp6") {
+			continue
+		}
+		return net.JoinHostPort(ipnet.IP.String(), port), nil
+	}
+	return "", fmt.Errorf("address[%s] not found routable ip", address)
+}
+
 func (s *service) close() {
 	if s.serverTransport == nil {
 		return
@@ -0,0 +1,205 @@
+package framework
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/framework/client"
+	"github.com/soulnov23/go-tool/pkg/framework/limiter"
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/registry"
+	pkglog "github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/tracing"
+)
+
+type spanRecorder struct {
+	mutex sync.Mutex
+	spans []*tracing.SpanData
+}
+
+func (r *spanRecorder) Export(ctx context.Context, spans []*tracing.SpanData) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (r *spanRecorder) span(kind string) *tracing.SpanData {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, span := range r.spans {
+		if span.Kind == kind {
+			return span
+		}
+	}
+	return nil
+}
+
+// freeAddress 返回一个没有监听的本地地址
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+// newServingService 启动rpc_service，Echo原样返回请求，Fail返回普通错误
+func newServingService(t *testing.T, l *limiter.Limiter) (*service, *client.Client) {
+	t.Helper()
+	log.DefaultLogger = pkglog.DefaultLogger
+	s := newService("rpc_service", freeAddress(t), "tcp", "rpc", time.Second, l)
+	_ = s.register("Echo", func(ctx context.Context, request string) (string, error) {
+		return request, nil
+	})
+	_ = s.register("Fail", func(ctx context.Context, request string) (string, error) {
+		return "", context.Canceled
+	})
+	if err := s.serve(); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	t.Cleanup(s.close)
+	c := client.New(client.WithDiscovery(registry.NewStatic(map[string][]*registry.Node{s.name: {s.node()}})), client.WithTimeout(time.Second))
+	t.Cleanup(c.Close)
+	return s, c
+}
+
+func TestServiceTrace(t *testing.T) {
+	recorder := &spanRecorder{}
+	defaultTracer := tracing.DefaultTracer
+	tracing.DefaultTracer = tracing.New(tracing.WithExporter(recorder))
+	defer func() { tracing.DefaultTracer = defaultTracer }()
+
+	_, c := newServingService(t, nil)
+	response, err := c.Invoke(context.Background(), "rpc_service", "Echo", "hello world")
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if response != "hello world" {
+		t.Errorf("Invoke() = %v, want hello world", response)
+	}
+
+	clientSpan, serverSpan := recorder.span("client"), recorder.span("server")
+	if clientSpan == nil || serverSpan == nil {
+		t.Fatalf("spans = %+v, want client and server span", recorder.spans)
+	}
+	if serverSpan.TraceID != clientSpan.TraceID {
+		t.Errorf("server trace_id = %s, want %s", serverSpan.TraceID, clientSpan.TraceID)
+	}
+	if serverSpan.ParentSpanID != clientSpan.SpanID {
+		t.Errorf("server parent_span_id = %s, want %s", serverSpan.ParentSpanID, clientSpan.SpanID)
+	}
+}
+
+func TestServiceError(t *testing.T) {
+	_, c := newServingService(t, nil)
+	tests := []struct {
+		rpcName string
+		want    *errors.Error
+	}{
+		{rpcName: "Fail", want: ErrInternal},
+		{rpcName: "Unknown", want: ErrRPCNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rpcName, func(t *testing.T) {
+			_, err := c.Invoke(context.Background(), "rpc_service", tt.rpcName, "")
+			if e := errors.FromError(err); e == nil || e.Name != tt.want.Name {
+				t.Errorf("Invoke() error = %v, want %s", err, tt.want.Name)
+			}
+		})
+	}
+}
+
+func TestServiceLimiter(t *testing.T) {
+	l, err := limiter.New("rpc_service", &limiter.Config{
+		RPCs: []*limiter.RPCConfig{
+			{
+				Name:      "Echo",
+				RateLimit: &limiter.RateLimitConfig{Type: limiter.SlidingWindowType, Rate: 1, Window: 60000},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("limiter.New: %v", err)
+	}
+	_, c := newServingService(t, l)
+	if _, err := c.Invoke(context.Background(), "rpc_service", "Echo", "hello"); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	_, err = c.Invoke(context.Background(), "rpc_service", "Echo", "hello")
+	if e := errors.FromError(err); e == nil || e.Name != limiter.ErrRateLimited.Name {
+		t.Errorf("Invoke() over limit error = %v, want %s", err, limiter.ErrRateLimited.Name)
+	}
+}
+
+func TestAdvertiseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		want    string // 为空时只检查不是未指定地址
+	}{
+		{address: "127.0.0.1:6666", network: "tcp", want: "127.0.0.1:6666"},
+		{address: "[::1]:6666", network: "tcp6", want: "[::1]:6666"},
+		{address: "localhost:6666", network: "tcp", want: "localhost:6666"},
+		{address: "0.0.0.0:6666", network: "tcp"},
+		{address: ":6666", network: "tcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := advertiseAddress(tt.address, tt.network)
+			if err != nil {
+				t.Skipf("advertiseAddress: %v", err)
+			}
+			if tt.want != "" {
+				if got != tt.want {
+					t.Errorf("advertiseAddress() = %s, want %s", got, tt.want)
+				}
+				return
+			}
+			host, port, _ := net.SplitHostPort(got)
+			if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() || ip.IsLoopback() || port != "6666" {
+				t.Errorf("advertiseAddress() = %s, want routable ip", got)
+			}
+		})
+	}
+}
+
+func TestServicePanicRelease(t *testing.T) {
+	l, err := limiter.New("rpc_service", &limiter.Config{
+		RPCs: []*limiter.RPCConfig{
+			{
+				Name:             "Panic",
+				ConcurrencyLimit: &limiter.ConcurrencyLimitConfig{Type: limiter.AIMDType, InitialLimit: 1, MinLimit: 1, MaxLimit: 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("limiter.New: %v", err)
+	}
+	s := newService("rpc_service", "127.0.0.1:0", "tcp", "rpc", time.Second, l)
+	_ = s.register("Panic", func(ctx context.Context, request string) (string, error) {
+		panic("handler panic")
+	})
+	// 并发上限是1，名额没有归还时第二次调用会被限流而不是panic
+	for i := range 2 {
+		func() {
+			defer func() {
+				if p := recover(); p == nil {
+					t.Errorf("handle() %d did not panic", i)
+				}
+			}()
+			_, err := s.handle(context.Background(), "Panic", "")
+			t.Errorf("handle() %d error = %v, want panic", i, err)
+		}()
+	}
+}
@@ -0,0 +1,391 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultEnvPrefix 环境变量覆盖配置的前缀，比如GOTOOL_SERVER_SERVICES_0_TIMEOUT=5000
+	DefaultEnvPrefix = "GOTOOL_"
+	// EnvNameKey 指定部署环境的环境变量，比如GOTOOL_ENV=prod时加载go_tool.prod.yaml
+	EnvNameKey = DefaultEnvPrefix + "ENV"
+)
+
+// ConfigSource 一层配置，按顺序合并到已有的配置上，后面的覆盖前面的
+//
+// map按key递归合并，元素都带name字段的数组按name合并，其它数组和标量整体替换
+type ConfigSource interface {
+	Merge(root *yaml.Node) error
+}
+
+type fileSource struct {
+	path     string
+	optional bool
+}
+
+// FileSource 从yaml文件加载一层配置
+func FileSource(path string) ConfigSource {
+	return &fileSource{path: path}
+}
+
+// OptionalFileSource 和FileSource一样，文件不存在时跳过，用于go_tool.<env>.yaml这种可以不提供的环境配置
+func OptionalFileSource(path string) ConfigSource {
+	return &fileSource{path: path, optional: true}
+}
+
+// EnvFilePath 返回部署环境对应的配置文件路径，比如go_tool.yaml和prod对应go_tool.prod.yaml
+func EnvFilePath(configPath string, env string) string {
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + "." + env + ext
+}
+
+func (s *fileSource) Merge(root *yaml.Node) error {
+	buffer, err := os.ReadFile(s.path)
+	if err != nil {
+		if s.optional && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read config path: %v", err)
+	}
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(buffer, node); err != nil {
+		return fmt.Errorf("yaml unmarshal config %s: %v", s.path, err)
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return nil
+	}
+	if root.Kind == 0 {
+		*root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{copyNode(node.Content[0])}}
+		return nil
+	}
+	mergeNode(documentContent(root), node.Content[0])
+	return nil
+}
+
+type envSource struct {
+	prefix string
+}
+
+// EnvSource 用带prefix的环境变量覆盖配置，变量名去掉前缀后按_拆分，优先匹配已有的key，再匹配Config的字段，
+// 比如GOTOOL_SERVER_MAX_CLOSE_WAIT_TIME对应server.max_close_wait_time，数字对应数组下标，
+// 都匹配不上的变量和部署环境变量EnvNameKey不覆盖配置，避免无关的同前缀变量写入配置
+func EnvSource(prefix string) ConfigSource {
+	return &envSource{prefix: prefix}
+}
+
+func (s *envSource) Merge(root *yaml.Node) error {
+	envs := os.Environ()
+	slices.Sort(envs)
+	var errs []error
+	for _, env := range envs {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, s.prefix) || name == EnvNameKey || name == s.prefix+"ENV" {
+			continue
+		}
+		tokens := strings.Split(strings.TrimPrefix(name, s.prefix), "_")
+		path, ok := envPath(documentContent(root), reflect.TypeFor[Config](), tokens)
+		if !ok {
+			continue
+		}
+		if err := setNode(root, path, value); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %v", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// envPath 把环境变量拆分出的单词按已有的key和typ的yaml字段组合成路径，
+// 只有值是标量的map才允许剩下的单词组成一个新的key，其它匹配不上的返回false
+func envPath(node *yaml.Node, typ reflect.Type, tokens []string) ([]string, bool) {
+	var path []string
+	for len(tokens) > 0 {
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ == reflect.TypeFor[yaml.Node]() {
+			// 插件配置由插件自己解析，只能覆盖已有的key
+			typ = nil
+		}
+		if node != nil && node.Kind == yaml.SequenceNode || typ != nil && typ.Kind() == reflect.Slice {
+			index, err := strconv.Atoi(tokens[0])
+			if err != nil || index < 0 {
+				return nil, false
+			}
+			if node != nil && node.Kind == yaml.SequenceNode && index < len(node.Content) {
+				node = node.Content[index]
+			} else {
+				node = nil
+			}
+			if typ != nil && typ.Kind() == reflect.Slice {
+				typ = typ.Elem()
+			} else {
+				typ = nil
+			}
+			path = append(path, tokens[0])
+			tokens = tokens[1:]
+			continue
+		}
+		var matchKey string
+		var matchValue *yaml.Node
+		var matchType reflect.Type
+		matchSize := 0
+		match := func(key string, value *yaml.Node, valueType reflect.Type) {
+			keyTokens := strings.Split(strings.ToUpper(key), "_")
+			if len(keyTokens) > matchSize && len(keyTokens) <= len(tokens) && slices.Equal(keyTokens, tokens[:len(keyTokens)]) {
+				matchKey, matchValue, matchType, matchSize = key, value, valueType, len(keyTokens)
+			}
+		}
+		var elemType reflect.Type
+		if typ != nil && typ.Kind() == reflect.Map {
+			elemType = typ.Elem()
+		}
+		if node != nil && node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				match(node.Content[i].Value, node.Content[i+1], fieldType(typ, node.Content[i].Value, elemType))
+			}
+		}
+		if typ != nil && typ.Kind() == reflect.Struct {
+			for field := range typ.Fields() {
+				if key := yamlName(field); key != "" {
+					match(key, mappingNode(node, key), field.Type)
+				}
+			}
+		}
+		if matchSize == 0 {
+			if elemType != nil && scalarKind(elemType) {
+				return append(path, strings.ToLower(strings.Join(tokens, "_"))), true
+			}
+			return nil, false
+		}
+		path = append(path, matchKey)
+		node, typ = matchValue, matchType
+		tokens = tokens[matchSize:]
+	}
+	return path, true
+}
+
+// fieldType 返回map或者struct中key对应的类型，不知道时返回nil，比如插件配置yaml.Node
+func fieldType(typ reflect.Type, key string, elemType reflect.Type) reflect.Type {
+	if elemType != nil {
+		return elemType
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+	for field := range typ.Fields() {
+		if yamlName(field) == key {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+func yamlName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func scalarKind(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return false
+	default:
+		return true
+	}
+}
+
+func mappingNode(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+type setSource struct {
+	overrides []string
+}
+
+// SetSource 用key=value覆盖配置，key的格式和配置检查报错中的路径一致，比如server.services[0].timeout=5000
+func SetSource(overrides ...string) ConfigSource {
+	return &setSource{overrides: overrides}
+}
+
+func (s *setSource) Merge(root *yaml.Node) error {
+	var errs []error
+	for _, override := range s.overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || key == "" {
+			errs = append(errs, fmt.Errorf("set %s: want key=value", override))
+			continue
+		}
+		if err := setNode(root, splitPath(key), value); err != nil {
+			errs = append(errs, fmt.Errorf("set %s: %v", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitPath server.services[0].timeout和server.services.0.timeout都拆分成server services 0 timeout
+func splitPath(key string) []string {
+	key = strings.ReplaceAll(strings.ReplaceAll(key, "[", "."), "]", "")
+	return strings.Split(key, ".")
+}
+
+// setNode 按路径设置标量的值，路径不存在时创建，数组下标等于长度时追加
+func setNode(root *yaml.Node, path []string, value string) error {
+	if root.Kind == 0 {
+		*root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	node := documentContent(root)
+	for i, key := range path {
+		last := i == len(path)-1
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == key {
+					next = node.Content[j+1]
+					break
+				}
+			}
+			if next == nil {
+				next = &yaml.Node{}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, next)
+			}
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index > len(node.Content) {
+				return fmt.Errorf("%s is not an index of %s", key, strings.Join(path[:i], "."))
+			}
+			if index == len(node.Content) {
+				node.Content = append(node.Content, &yaml.Node{})
+			}
+			next = node.Content[index]
+		default:
+			return fmt.Errorf("%s is not a map or list", strings.Join(path[:i], "."))
+		}
+		if last {
+			*next = yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return nil
+		}
+		if next.Kind == 0 || next.Kind == yaml.ScalarNode && next.Tag == "!!null" {
+			// 中间节点不存在时按下一个key是否是数字创建数组或者map
+			if _, err := strconv.Atoi(path[i+1]); err == nil {
+				*next = yaml.Node{Kind: yaml.SequenceNode}
+			} else {
+				*next = yaml.Node{Kind: yaml.MappingNode}
+			}
+		}
+		node = next
+	}
+	return nil
+}
+
+func documentContent(root *yaml.Node) *yaml.Node {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		return root.Content[0]
+	}
+	return root
+}
+
+func mergeNode(dst *yaml.Node, src *yaml.Node) {
+	if src.Kind == yaml.AliasNode {
+		src = src.Alias
+	}
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			found := false
+			for j := 0; j+1 < len(dst.Content); j += 2 {
+				if dst.Content[j].Value == key.Value {
+					mergeNode(dst.Content[j+1], value)
+					found = true
+					break
+				}
+			}
+			if !found {
+				dst.Content = append(dst.Content, copyNode(key), copyNode(value))
+			}
+		}
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode && namedSequence(dst) && namedSequence(src):
+		for _, value := range src.Content {
+			name := mappingValue(value, "name")
+			found := false
+			for _, item := range dst.Content {
+				if mappingValue(item, "name") == name {
+					mergeNode(item, value)
+					found = true
+					break
+				}
+			}
+			if !found {
+				dst.Content = append(dst.Content, copyNode(value))
+			}
+		}
+	default:
+		*dst = *copyNode(src)
+	}
+}
+
+// namedSequence 数组元素都是带name字段的map，比如server.services
+func namedSequence(node *yaml.Node) bool {
+	for _, item := range node.Content {
+		if mappingValue(item, "name") == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func mappingValue(node *yaml.Node, key string) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// copyNode 深拷贝节点并展开别名，合并时不会修改到锚点共用的节点
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return copyNode(node.Alias)
+	}
+	result := *node
+	result.Anchor = ""
+	result.Content = make([]*yaml.Node, 0, len(node.Content))
+	for _, child := range node.Content {
+		result.Content = append(result.Content, copyNode(child))
+	}
+	return &result
+}
@@ -0,0 +1,129 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigSources(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "go_tool.yaml")
+	config := `
+server:
+    max_close_wait_time: 3000
+    services:
+        - name: rpc_service
+          address: 0.0.0.0:6666
+          timeout: &timeout 3000
+        - name: http_service
+          address: 0.0.0.0:8888
+          protocol: http
+          timeout: *timeout
+plugins:
+    registry:
+        default:
+            type: static
+`
+	overlay := `
+server:
+    services:
+        - name: http_service
+          timeout: 1000
+        - name: admin_service
+          address: 0.0.0.0:9999
+`
+	if err := os.WriteFile(base, []byte(config), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	if err := os.WriteFile(EnvFilePath(base, "prod"), []byte(overlay), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	t.Setenv("GOTOOL_TEST_SERVER_MAX_CLOSE_WAIT_TIME", "5000")
+	t.Setenv("GOTOOL_TEST_SERVER_SERVICES_0_TIMEOUT", "2000")
+	t.Setenv("GOTOOL_TEST_SERVER_PLUGIN_TIMEOUT", "4000")
+	t.Setenv("GOTOOL_TEST_SERVER_PLUGIN_TIMEOUTS_REGISTRY", "6000")
+	t.Setenv("GOTOOL_TEST_PLUGINS_REGISTRY_DEFAULT_TYPE", "file")
+	t.Setenv("GOTOOL_TEST_PLUGINS_REGISTRY_DEFAULT_TAG", "x")
+
+	got, err := loadConfig(base,
+		FileSource(EnvFilePath(base, "prod")),
+		OptionalFileSource(EnvFilePath(base, "staging")),
+		EnvSource("GOTOOL_TEST_"),
+		SetSource("server.services[0].protocol=http", "server.services.2.timeout=500"),
+	)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if got.Server.MaxCloseWaitTime != 5000 {
+		t.Errorf("max_close_wait_time = %d, want 5000", got.Server.MaxCloseWaitTime)
+	}
+	if got.Server.PluginTimeout != 4000 || got.Server.PluginTimeouts["registry"] != 6000 {
+		t.Errorf("plugin_timeout = %d, plugin_timeouts = %v", got.Server.PluginTimeout, got.Server.PluginTimeouts)
+	}
+	// 插件配置只覆盖已有的key，和Config无关的同前缀变量不写入配置
+	registry := got.Plugins["registry"]["default"]
+	if buffer, _ := yaml.Marshal(&registry); string(buffer) != "type: file\n" {
+		t.Errorf("plugins.registry.default = %s, want type: file", buffer)
+	}
+	if _, err := loadConfig(base, FileSource(EnvFilePath(base, "staging"))); err == nil {
+		t.Errorf("loadConfig() with missing FileSource error = nil, want error")
+	}
+	services := got.Server.Services
+	if len(services) != 3 {
+		t.Fatalf("services = %d, want 3", len(services))
+	}
+	if services[0].Timeout != 2000 || services[0].Protocol != "http" {
+		t.Errorf("services[0] = %+v", services[0])
+	}
+	if services[1].Timeout != 1000 || services[1].Address != "0.0.0.0:8888" {
+		t.Errorf("services[1] = %+v", services[1])
+	}
+	if services[2].Name != "admin_service" || services[2].Timeout != 500 {
+		t.Errorf("services[2] = %+v", services[2])
+	}
+}
+
+func TestSetSourceInvalid(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "go_tool.yaml")
+	if err := os.WriteFile(base, []byte("server:\n    services:\n        - name: rpc_service\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	for _, override := range []string{"server", "server.services.5.timeout=1", "server.services.0.name.x=1"} {
+		if _, err := loadConfig(base, SetSource(override)); err == nil {
+			t.Errorf("loadConfig() with -set %s error = nil, want error", override)
+		}
+	}
+}
+
+func TestEnvPath(t *testing.T) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte("server:\n    services:\n        - name: rpc_service\nplugins:\n    log:\n        default:\n            level: info\n"), root); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	tests := []struct {
+		env  string
+		want string // 为空表示不覆盖配置
+	}{
+		{env: "SERVER_MAX_CLOSE_WAIT_TIME", want: "server.max_close_wait_time"},
+		{env: "SERVER_SERVICES_0_LIMITER_RATE_LIMIT_BURST", want: "server.services.0.limiter.rate_limit.burst"},
+		{env: "SERVER_PLUGIN_TIMEOUTS_LOG", want: "server.plugin_timeouts.log"},
+		{env: "PLUGINS_LOG_DEFAULT_LEVEL", want: "plugins.log.default.level"},
+		{env: "PLUGINS_LOG_DEFAULT_TAG"},
+		{env: "PLUGINS_MYSQL_DEFAULT_DSN"},
+		{env: "SERVER_SERVICES_X_TIMEOUT"},
+		{env: "HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			path, ok := envPath(documentContent(root), reflect.TypeFor[Config](), strings.Split(tt.env, "_"))
+			if got := strings.Join(path, "."); ok != (tt.want != "") || got != tt.want {
+				t.Errorf("envPath() = %s, %v, want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/framework/log"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"github.com/soulnov23/go-tool/pkg/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	pluginType = "tracing"
+
+	exporterStdout = "stdout"
+	exporterOTLP   = "otlp"
+)
+
+func init() {
+	plugin.Register(pluginType, &TracingPlugin{})
+}
+
+type Config struct {
+	ServiceName   string            `yaml:"service_name"`
+	Exporter      string            `yaml:"exporter"`       // stdout otlp
+	Endpoint      string            `yaml:"endpoint"`       // otlp http地址，如http://127.0.0.1:4318
+	Timeout       int64             `yaml:"timeout"`        // 单位 毫秒
+	BatchSize     int               `yaml:"batch_size"`     // 攒批上报的span数量
+	FlushInterval int64             `yaml:"flush_interval"` // 单位 毫秒
+	Headers       map[string]string `yaml:"headers"`
+}
+
+type TracingPlugin struct{}
+
+func (p *TracingPlugin) Type() string {
+	return pluginType
+}
+
+func (p *TracingPlugin) DependsOn() []string {
+	return []string{log.PluginType}
+}
+
+// Stop 上报剩余的span
+func (p *TracingPlugin) Stop(ctx context.Context, name string) error {
+	tracer, err := plugin.Get[*tracing.Tracer](pluginType, name)
+	if err != nil {
+		return err
+	}
+	return tracer.Shutdown(ctx)
+}
+
+// Setup default实例设置tracing.DefaultTracer
+func (p *TracingPlugin) Setup(ctx context.Context, name string, node yaml.Node) (any, error) {
+	config := &Config{}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("plugin type[%s] name[%s] invalid config: %v", pluginType, name, err)
+	}
+	opts := []tracing.Option{
+		tracing.WithErrorf(errorf),
+	}
+	if config.ServiceName != "" {
+		opts = append(opts, tracing.WithServiceName(config.ServiceName))
+	}
+	switch config.Exporter {
+	case "":
+	case exporterStdout:
+		opts = append(opts, tracing.WithExporter(tracing.NewStdoutExporter(os.Stdout)))
+	case exporterOTLP:
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("plugin type[%s] name[%s] otlp endpoint is empty", pluginType, name)
+		}
+		otlpOpts := []tracing.OTLPOption{
+			tracing.WithOTLPHeaders(config.Headers),
+			tracing.WithOTLPErrorf(errorf),
+		}
+		if config.Timeout > 0 {
+			otlpOpts = append(otlpOpts, tracing.WithOTLPTimeout(time.Duration(config.Timeout)*time.Millisecond))
+		}
+		if config.BatchSize > 0 {
+			otlpOpts = append(otlpOpts, tracing.WithOTLPBatchSize(config.BatchSize))
+		}
+		if config.FlushInterval > 0 {
+			otlpOpts = append(otlpOpts, tracing.WithOTLPFlushInterval(time.Duration(config.FlushInterval)*time.Millisecond))
+		}
+		opts = append(opts, tracing.WithExporter(tracing.NewOTLPExporter(config.Endpoint, otlpOpts...)))
+	default:
+		return nil, fmt.Errorf("plugin type[%s] name[%s] exporter[%s] not support", pluginType, name, config.Exporter)
+	}
+	tracer := tracing.New(opts...)
+	if name == plugin.DefaultName {
+		tracing.DefaultTracer = tracer
+	}
+	return tracer, nil
+}
+
+// 上报失败时才去取框架日志，单独使用tracing包时框架日志可能没有初始化
+func errorf(formatter string, args ...any) {
+	log.FrameLogger().Errorf(formatter, args...)
+}
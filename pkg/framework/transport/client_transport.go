@@ -0,0 +1,45 @@
+package transport
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+type clientTransportFunc func(network string, opts ...ClientTransportOption) ClientTransport
+
+var (
+	clientTransportFuncs = map[string]clientTransportFunc{}
+	cMutex               = sync.RWMutex{}
+)
+
+// ClientTransport 把请求发送到address并读取响应，协议编解码由上层完成
+type ClientTransport interface {
+	RoundTrip(ctx context.Context, address string, request []byte) ([]byte, error)
+	// Healthy 地址对应的连接池连续失败时返回false，负载均衡时跳过该地址
+	Healthy(address string) bool
+	Close()
+}
+
+func RegisterClientTransportFunc(network string, fn clientTransportFunc) {
+	value := reflect.ValueOf(fn)
+	if fn == nil || value.Kind() == reflect.Pointer && value.IsNil() {
+		panic("register nil client transport")
+	}
+	if network == "" {
+		panic("register empty network of client transport")
+	}
+	cMutex.Lock()
+	defer cMutex.Unlock()
+	clientTransportFuncs[network] = fn
+}
+
+func NewClientTransport(network string, opts ...ClientTransportOption) ClientTransport {
+	cMutex.RLock()
+	fn, ok := clientTransportFuncs[network]
+	cMutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	return fn(network, opts...)
+}
@@ -0,0 +1,43 @@
+package transport
+
+import "time"
+
+type ClientTransportOptions struct {
+	maxIdleConns     int
+	dialTimeout      time.Duration
+	maxFailures      int           // 连续失败多少次后标记为不健康
+	unhealthyTimeout time.Duration // 标记为不健康后多久允许再次尝试
+	maxFrameSize     int           // 响应帧的最大字节数
+}
+
+type ClientTransportOption func(*ClientTransportOptions)
+
+func WithMaxIdleConns(maxIdleConns int) ClientTransportOption {
+	return func(o *ClientTransportOptions) {
+		o.maxIdleConns = maxIdleConns
+	}
+}
+
+func WithDialTimeout(dialTimeout time.Duration) ClientTransportOption {
+	return func(o *ClientTransportOptions) {
+		o.dialTimeout = dialTimeout
+	}
+}
+
+func WithMaxFailures(maxFailures int) ClientTransportOption {
+	return func(o *ClientTransportOptions) {
+		o.maxFailures = maxFailures
+	}
+}
+
+func WithUnhealthyTimeout(unhealthyTimeout time.Duration) ClientTransportOption {
+	return func(o *ClientTransportOptions) {
+		o.unhealthyTimeout = unhealthyTimeout
+	}
+}
+
+func WithClientMaxFrameSize(maxFrameSize int) ClientTransportOption {
+	return func(o *ClientTransportOptions) {
+		o.maxFrameSize = maxFrameSize
+	}
+}
@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxIdleConns     = 16
+	defaultDialTimeout      = time.Second
+	defaultMaxFailures      = 3
+	defaultUnhealthyTimeout = 10 * time.Second
+)
+
+func init() {
+	RegisterClientTransportFunc("tcp", newClientTransportTCP)
+	RegisterClientTransportFunc("tcp4", newClientTransportTCP)
+	RegisterClientTransportFunc("tcp6", newClientTransportTCP)
+}
+
+type clientTransportTCP struct {
+	network string
+	opts    *ClientTransportOptions
+
+	mutex sync.Mutex
+	pools map[string]*connPool // k=address,v=connPool
+}
+
+func newClientTransportTCP(network string, opts ...ClientTransportOption) ClientTransport {
+	transport := &clientTransportTCP{
+		network: network,
+		opts: &ClientTransportOptions{
+			maxIdleConns:     defaultMaxIdleConns,
+			dialTimeout:      defaultDialTimeout,
+			maxFailures:      defaultMaxFailures,
+			unhealthyTimeout: defaultUnhealthyTimeout,
+			maxFrameSize:     DefaultMaxFrameSize,
+		},
+		pools: make(map[string]*connPool),
+	}
+	for _, opt := range opts {
+		opt(transport.opts)
+	}
+	return transport
+}
+
+// RoundTrip 写入请求帧后读取完整的响应帧，连接出错时关闭不再复用，避免残留的数据被下一个请求读到
+//
+// ctx取消时立即返回并关闭连接，对冲中落后的请求不会阻塞在Read上，取消不计入连续失败次数
+func (t *clientTransportTCP) RoundTrip(ctx context.Context, address string, request []byte) ([]byte, error) {
+	pool := t.pool(address)
+	conn, err := pool.get(ctx)
+	if err != nil {
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			pool.fail()
+		}
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Time{})
+	}
+	// 设置过期的deadline让阻塞的Write和Read立即返回
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+	response, err := t.roundTrip(conn, address, request)
+	// stop返回false说明deadline已经被修改，连接不能再复用
+	if !stop() || err != nil {
+		pool.discard(conn)
+		if ctx.Err() != nil {
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				pool.fail()
+			}
+			return nil, fmt.Errorf("address[%s]: %w", address, ctx.Err())
+		}
+		pool.fail()
+		return nil, err
+	}
+	pool.put(conn)
+	pool.succeed()
+	return response, nil
+}
+
+func (t *clientTransportTCP) roundTrip(conn net.Conn, address string, request []byte) ([]byte, error) {
+	if err := WriteFrame(conn, request); err != nil {
+		return nil, fmt.Errorf("write address[%s]: %v", address, err)
+	}
+	response, err := ReadFrame(conn, t.opts.maxFrameSize)
+	if err != nil {
+		return nil, fmt.Errorf("read address[%s]: %v", address, err)
+	}
+	return response, nil
+}
+
+func (t *clientTransportTCP) Healthy(address string) bool {
+	t.mutex.Lock()
+	pool, ok := t.pools[address]
+	t.mutex.Unlock()
+	if !ok {
+		return true
+	}
+	return pool.healthy()
+}
+
+func (t *clientTransportTCP) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for address, pool := range t.pools {
+		pool.close()
+		delete(t.pools, address)
+	}
+}
+
+func (t *clientTransportTCP) pool(address string) *connPool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	pool, ok := t.pools[address]
+	if !ok {
+		pool = &connPool{
+			network: t.network,
+			address: address,
+			opts:    t.opts,
+		}
+		t.pools[address] = pool
+	}
+	return pool
+}
+
+var errPoolClosed = errors.New("conn pool closed")
+
+// connPool 单个地址的空闲连接池
+type connPool struct {
+	network string
+	address string
+	opts    *ClientTransportOptions
+
+	mutex          sync.Mutex
+	idle           []net.Conn
+	closed         bool
+	failures       int
+	unhealthyUntil time.Time
+}
+
+func (p *connPool) get(ctx context.Context) (net.Conn, error) {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, errPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mutex.Unlock()
+		return conn, nil
+	}
+	p.mutex.Unlock()
+	dialer := &net.Dialer{Timeout: p.opts.dialTimeout}
+	conn, err := dialer.DialContext(ctx, p.network, p.address)
+	if err != nil {
+		return nil, fmt.Errorf("dial network[%s] address[%s]: %v", p.network, p.address, err)
+	}
+	return conn, nil
+}
+
+func (p *connPool) put(conn net.Conn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed || len(p.idle) >= p.opts.maxIdleConns {
+		conn.Close()
+		return
+	}
+	p.idle = append(p.idle, conn)
+}
+
+func (p *connPool) discard(conn net.Conn) {
+	conn.Close()
+}
+
+// fail 连续失败达到阈值后标记为不健康，同时关闭空闲连接
+func (p *connPool) fail() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.failures++
+	if p.opts.maxFailures > 0 && p.failures >= p.opts.maxFailures {
+		p.unhealthyUntil = time.Now().Add(p.opts.unhealthyTimeout)
+		for _, conn := range p.idle {
+			conn.Close()
+		}
+		p.idle = nil
+	}
+}
+
+func (p *connPool) succeed() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.failures = 0
+	p.unhealthyUntil = time.Time{}
+}
+
+// healthy 不健康状态超时后放行，由下一次调用的结果决定是否恢复
+func (p *connPool) healthy() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return !p.closed && time.Now().After(p.unhealthyUntil)
+}
+
+func (p *connPool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	for _, conn := range p.idle {
+		conn.Close()
+	}
+	p.idle = nil
+}
@@ -0,0 +1,125 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSplitServer 响应分多次写入，每次之间停顿，模拟一次Read读不完整个响应
+func newSplitServer(t *testing.T, conns *atomic.Int64) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer conn.Close()
+				for {
+					request, err := ReadFrame(conn, DefaultMaxFrameSize)
+					if err != nil {
+						return
+					}
+					buf := &bytes.Buffer{}
+					_ = WriteFrame(buf, bytes.Repeat(request, 4096))
+					for chunk := range slices.Chunk(buf.Bytes(), 3000) {
+						if _, err := conn.Write(chunk); err != nil {
+							return
+						}
+						time.Sleep(time.Millisecond)
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	var conns atomic.Int64
+	address := newSplitServer(t, &conns)
+	transport := newClientTransportTCP("tcp")
+	defer transport.Close()
+	for _, request := range []string{"a", "bc", "def"} {
+		response, err := transport.RoundTrip(context.Background(), address, []byte(request))
+		if err != nil {
+			t.Fatalf("RoundTrip(%s): %v", request, err)
+		}
+		if want := bytes.Repeat([]byte(request), 4096); !bytes.Equal(response, want) {
+			t.Fatalf("RoundTrip(%s) len = %d, want %d", request, len(response), len(want))
+		}
+	}
+	if got := conns.Load(); got != 1 {
+		t.Errorf("connections = %d, want 1", got)
+	}
+}
+
+func TestRoundTripFrameTooLarge(t *testing.T) {
+	var conns atomic.Int64
+	address := newSplitServer(t, &conns)
+	transport := newClientTransportTCP("tcp", WithClientMaxFrameSize(1024))
+	defer transport.Close()
+	if _, err := transport.RoundTrip(context.Background(), address, []byte("a")); err == nil {
+		t.Fatal("RoundTrip() error = nil, want frame too large")
+	}
+	// 读取失败的连接不能放回连接池
+	if _, err := transport.RoundTrip(context.Background(), address, []byte("a")); err == nil {
+		t.Fatal("RoundTrip() error = nil, want frame too large")
+	}
+	if got := conns.Load(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+}
+
+func TestRoundTripCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer listener.Close()
+	// 服务端只读不写，客户端关闭连接时Read返回
+	closed := make(chan struct{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	transport := newClientTransportTCP("tcp", WithMaxFailures(1))
+	defer transport.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	begin := time.Now()
+	if _, err := transport.RoundTrip(ctx, listener.Addr().String(), []byte("a")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RoundTrip() error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("RoundTrip() elapsed = %v after cancel", elapsed)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("conn not closed after cancel")
+	}
+	// 取消不是实例的问题
+	if !transport.Healthy(listener.Addr().String()) {
+		t.Error("Healthy() = false after cancel, want true")
+	}
+}
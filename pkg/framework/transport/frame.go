@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 请求和响应都按帧传输：4字节大端序的长度 + 内容，读完整个帧后连接才能复用
+const (
+	FrameHeaderSize     = 4
+	DefaultMaxFrameSize = 16 << 20
+)
+
+// WriteFrame 帧头和内容一次写入
+func WriteFrame(w io.Writer, payload []byte) error {
+	buf := make([]byte, FrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+	copy(buf[FrameHeaderSize:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadFrame 读取一个完整的帧，内容超过maxSize时返回错误，连接不能再复用
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	header := make([]byte, FrameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header)
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("frame size[%d] exceeds max frame size[%d]", size, maxSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"reflect"
 	"sync"
 )
@@ -12,11 +13,29 @@ var (
 	sMutex               = sync.RWMutex{}
 )
 
+// Handler 处理一个请求帧，返回响应帧的内容，协议编解码由上层完成
+type Handler func(ctx context.Context, request []byte) (response []byte)
+
 type ServerTransport interface {
 	ListenAndServe() error
+	// Epolls 返回每个epoll上的客户端连接，用于admin接口排查问题
+	Epolls() []*EpollInfo
 	Close()
 }
 
+type EpollInfo struct {
+	EpollFD     int               `json:"epoll_fd"`
+	Connections []*ConnectionInfo `json:"connections"`
+}
+
+type ConnectionInfo struct {
+	FD               int    `json:"fd"`
+	RemoteAddress    string `json:"remote_address"`
+	LocalAddress     string `json:"local_address"`
+	ReadBufferBytes  uint64 `json:"read_buffer_bytes"`  // 已读取还未处理的字节数
+	WriteBufferBytes uint64 `json:"write_buffer_bytes"` // 等待发送的字节数
+}
+
 func RegisterServerTransportFunc(network string, fn serverTransportFunc) {
 	value := reflect.ValueOf(fn)
 	if fn == nil || value.Kind() == reflect.Pointer && value.IsNil() {
@@ -30,6 +49,14 @@ func RegisterServerTransportFunc(network string, fn serverTransportFunc) {
 	serverTransportFuncs[network] = fn
 }
 
+// ServerTransportSupported network是否注册了ServerTransport
+func ServerTransportSupported(network string) bool {
+	sMutex.RLock()
+	defer sMutex.RUnlock()
+	_, ok := serverTransportFuncs[network]
+	return ok
+}
+
 func NewServerTransport(address, network, protocol string, opts ...ServerTransportOption) ServerTransport {
 	fn, ok := serverTransportFuncs[network]
 	if !ok {
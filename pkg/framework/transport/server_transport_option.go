@@ -1,7 +1,9 @@
 package transport
 
 type ServerTransportOptions struct {
-	coreSize int
+	coreSize     int
+	maxFrameSize int // 请求帧的最大字节数，超过时关闭连接
+	handler      Handler
 }
 
 type ServerTransportOption func(*ServerTransportOptions)
@@ -11,3 +13,15 @@ func WithCoreSize(coreSize int) ServerTransportOption {
 		o.coreSize = coreSize
 	}
 }
+
+func WithServerMaxFrameSize(maxFrameSize int) ServerTransportOption {
+	return func(o *ServerTransportOptions) {
+		o.maxFrameSize = maxFrameSize
+	}
+}
+
+func WithHandler(handler Handler) ServerTransportOption {
+	return func(o *ServerTransportOptions) {
+		o.handler = handler
+	}
+}
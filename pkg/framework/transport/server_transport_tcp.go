@@ -1,9 +1,14 @@
 package transport
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"runtime"
+	"slices"
+	"sync"
 
 	"github.com/soulnov23/go-tool/pkg/buffer"
 	"github.com/soulnov23/go-tool/pkg/cache"
@@ -27,6 +32,9 @@ type serverTransportTCP struct {
 	localAddr     net.Addr
 	localSockAddr unix.Sockaddr
 	opts          *ServerTransportOptions
+
+	mutex sync.Mutex
+	conns map[*netpoll.Epoll]map[int]*tcpConnection // k=epoll,v=(k=client_fd,v=tcpConnection)
 }
 
 func newServerTransportTCP(address, network, protocol string, opts ...ServerTransportOption) ServerTransport {
@@ -35,8 +43,10 @@ func newServerTransportTCP(address, network, protocol string, opts ...ServerTran
 		network:  network,
 		protocol: protocol,
 		opts: &ServerTransportOptions{
-			coreSize: runtime.GOMAXPROCS(0),
+			coreSize:     runtime.GOMAXPROCS(0),
+			maxFrameSize: DefaultMaxFrameSize,
 		},
+		conns: make(map[*netpoll.Epoll]map[int]*tcpConnection),
 	}
 	for _, opt := range opts {
 		opt(transport.opts)
@@ -45,12 +55,18 @@ func newServerTransportTCP(address, network, protocol string, opts ...ServerTran
 }
 
 func (t *serverTransportTCP) ListenAndServe() error {
+	if t.opts.handler == nil {
+		return fmt.Errorf("address[%s] network[%s] handler not set", t.address, t.network)
+	}
 	for i := 0; i < t.opts.coreSize; i++ {
 		epoll, err := netpoll.NewEpoll(log.DefaultLogger.InfoFields)
 		if err != nil {
 			return fmt.Errorf("netpoll.NewEpoll: %v", err)
 		}
 		t.epolls = append(t.epolls, epoll)
+		t.mutex.Lock()
+		t.conns[epoll] = make(map[int]*tcpConnection)
+		t.mutex.Unlock()
 
 		addr, err := netpoll.ResolveAddr(t.network, t.address)
 		if err != nil {
@@ -140,18 +156,23 @@ func (t *serverTransportTCP) accept(epoll *netpoll.Epoll, operator *netpoll.FDOp
 		clientOperator.OnRead = t.read
 		clientOperator.OnWrite = t.write
 		clientOperator.OnHup = t.hup
-		clientOperator.Data = &tcpConnection{
+		tcpConn := &tcpConnection{
 			fd:          clientFD,
 			localAddr:   t.localAddr,
 			remoteAddr:  remoteAddr,
 			readBuffer:  buffer.New(),
 			writeBuffer: buffer.New(),
+			operator:    clientOperator,
 		}
+		clientOperator.Data = tcpConn
 		if err := operator.Epoll.Control(clientOperator, netpoll.Readable); err != nil {
 			unix.Close(clientFD)
 			log.DefaultLogger.ErrorFields("epoll.Control", zap.Error(err), zap.Int("epoll_fd", epoll.FD()), zap.Int("client_fd", clientFD), zap.String("epoll_event", netpoll.EventString(netpoll.Readable)))
 			continue
 		}
+		t.mutex.Lock()
+		t.conns[epoll][clientFD] = tcpConn
+		t.mutex.Unlock()
 		log.DefaultLogger.InfoFields("accept success", zap.Int("epoll_fd", epoll.FD()), zap.Int("listen_fd", operator.FD), zap.Int("client_fd", clientOperator.FD), zap.String("remote_address", remoteAddr.String()), zap.String("local_address", t.localAddr.String()))
 	}
 }
@@ -162,7 +183,6 @@ func (t *serverTransportTCP) read(epoll *netpoll.Epoll, operator *netpoll.FDOper
 		log.DefaultLogger.ErrorFields("data is not tcpConnection", zap.Reflect("operator", operator))
 		return
 	}
-	tcpConn.readBuffer.GC()
 	buf := cache.New(buffer.Block8k)
 	offset := 0
 	for {
@@ -188,8 +208,73 @@ func (t *serverTransportTCP) read(epoll *netpoll.Epoll, operator *netpoll.FDOper
 			break
 		}
 	}
-	tcpConn.readBuffer.Write(buf[:offset])
 	log.DefaultLogger.InfoFields("read success", zap.Int("epoll_fd", operator.Epoll.FD()), zap.Int("client_fd", operator.FD), zap.ByteString("buffer", buf[:offset]))
+	// 读缓冲区和admin接口读取缓冲区大小并发，需要持有tcpConn.mutex
+	tcpConn.mutex.Lock()
+	defer tcpConn.mutex.Unlock()
+	if tcpConn.closed {
+		return
+	}
+	tcpConn.readBuffer.GC()
+	tcpConn.readBuffer.Write(buf[:offset])
+	t.dispatch(tcpConn)
+}
+
+// dispatch 取出读缓冲区中所有完整的请求帧，每个请求在单独的协程中调用handler，帧超过上限时关闭连接，调用方需要持有tcpConn.mutex
+//
+// 客户端在一个连接上收到响应后才发送下一个请求，不需要保证同一个连接上响应的顺序
+func (t *serverTransportTCP) dispatch(tcpConn *tcpConnection) {
+	for {
+		size := tcpConn.readBuffer.Size()
+		if size < FrameHeaderSize {
+			return
+		}
+		header, err := tcpConn.readBuffer.Peek(FrameHeaderSize)
+		if err != nil {
+			return
+		}
+		frameSize := binary.BigEndian.Uint32(header)
+		if uint64(frameSize) > uint64(t.opts.maxFrameSize) {
+			log.DefaultLogger.ErrorFields("frame too large", zap.Int("client_fd", tcpConn.fd), zap.Uint32("frame_size", frameSize), zap.Int("max_frame_size", t.opts.maxFrameSize))
+			_ = unix.Shutdown(tcpConn.fd, unix.SHUT_RDWR)
+			return
+		}
+		if size < FrameHeaderSize+uint64(frameSize) {
+			return
+		}
+		_ = tcpConn.readBuffer.Skip(FrameHeaderSize)
+		request := []byte{}
+		if frameSize > 0 {
+			buf, err := tcpConn.readBuffer.Read(int(frameSize))
+			if err != nil {
+				return
+			}
+			// Read返回的内存GC后会被复用，交给其它协程前先复制
+			request = bytes.Clone(buf)
+		}
+		go t.serve(tcpConn, request)
+	}
+}
+
+// serve 调用handler后把响应帧写到写缓冲区，连接已经关闭时丢弃响应
+func (t *serverTransportTCP) serve(tcpConn *tcpConnection, request []byte) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.DefaultLogger.ErrorFields("handler panic", zap.Any("error", err), zap.Int("client_fd", tcpConn.fd), zap.Stack("stack"))
+		}
+	}()
+	response := t.opts.handler(context.Background(), request)
+	buf := cache.New(FrameHeaderSize + len(response))
+	binary.BigEndian.PutUint32(buf, uint32(len(response)))
+	copy(buf[FrameHeaderSize:], response)
+	tcpConn.mutex.Lock()
+	defer tcpConn.mutex.Unlock()
+	if tcpConn.closed {
+		cache.Delete(buf)
+		return
+	}
+	tcpConn.writeBuffer.Write(buf)
+	t.flush(tcpConn)
 }
 
 func (t *serverTransportTCP) write(epoll *netpoll.Epoll, operator *netpoll.FDOperator) {
@@ -198,6 +283,32 @@ func (t *serverTransportTCP) write(epoll *netpoll.Epoll, operator *netpoll.FDOpe
 		log.DefaultLogger.ErrorFields("data is not tcpConnection", zap.Reflect("operator", operator))
 		return
 	}
+	tcpConn.mutex.Lock()
+	defer tcpConn.mutex.Unlock()
+	if tcpConn.closed {
+		return
+	}
+	t.flush(tcpConn)
+}
+
+// flush 把写缓冲区的数据写到socket，没写完时监听可写事件由write继续发送，写完后只监听可读事件，调用方需要持有tcpConn.mutex
+func (t *serverTransportTCP) flush(tcpConn *tcpConnection) {
+	operator := tcpConn.operator
+	defer func() {
+		writable := tcpConn.writeBuffer.Size() > 0
+		if writable == tcpConn.writable {
+			return
+		}
+		event := netpoll.ModReadable
+		if writable {
+			event = netpoll.ModReadWritable
+		}
+		if err := operator.Epoll.Control(operator, event); err != nil {
+			log.DefaultLogger.ErrorFields("epoll.Control", zap.Error(err), zap.Int("epoll_fd", operator.Epoll.FD()), zap.Int("client_fd", operator.FD))
+			return
+		}
+		tcpConn.writable = writable
+	}()
 	buf, err := tcpConn.writeBuffer.Peek(int(tcpConn.writeBuffer.Size()))
 	if err != nil {
 		// 数据发送完了返回
@@ -234,19 +345,57 @@ func (t *serverTransportTCP) write(epoll *netpoll.Epoll, operator *netpoll.FDOpe
 }
 
 func (t *serverTransportTCP) hup(epoll *netpoll.Epoll, operator *netpoll.FDOperator) {
-	unix.Close(operator.FD)
-
 	tcpConn, ok := operator.Data.(*tcpConnection)
 	if !ok || tcpConn == nil {
+		unix.Close(operator.FD)
 		log.DefaultLogger.ErrorFields("data is not tcpConnection", zap.Reflect("operator", operator))
 		return
 	}
+	// 先标记关闭再关闭fd，避免handler协程把响应写到被复用的fd
+	tcpConn.mutex.Lock()
+	tcpConn.closed = true
+	unix.Close(operator.FD)
 	tcpConn.readBuffer.Delete()
 	tcpConn.writeBuffer.Delete()
+	tcpConn.mutex.Unlock()
+	t.mutex.Lock()
+	delete(t.conns[epoll], tcpConn.fd)
+	t.mutex.Unlock()
 
 	log.DefaultLogger.InfoFields("close success", zap.Int("epoll_fd", epoll.FD()), zap.Int("client_fd", operator.FD), zap.String("remote_address", tcpConn.remoteAddr.String()), zap.String("local_address", tcpConn.localAddr.String()))
 }
 
+func (t *serverTransportTCP) Epolls() []*EpollInfo {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	infos := make([]*EpollInfo, 0, len(t.epolls))
+	for _, epoll := range t.epolls {
+		info := &EpollInfo{
+			EpollFD:     epoll.FD(),
+			Connections: make([]*ConnectionInfo, 0, len(t.conns[epoll])),
+		}
+		for _, conn := range t.conns[epoll] {
+			// hup关闭连接后会释放缓冲区，已经关闭的连接不再输出
+			conn.mutex.Lock()
+			if !conn.closed {
+				info.Connections = append(info.Connections, &ConnectionInfo{
+					FD:               conn.fd,
+					RemoteAddress:    conn.remoteAddr.String(),
+					LocalAddress:     conn.localAddr.String(),
+					ReadBufferBytes:  conn.readBuffer.Size(),
+					WriteBufferBytes: conn.writeBuffer.Size(),
+				})
+			}
+			conn.mutex.Unlock()
+		}
+		slices.SortFunc(info.Connections, func(a, b *ConnectionInfo) int {
+			return a.FD - b.FD
+		})
+		infos = append(infos, info)
+	}
+	return infos
+}
+
 func (t *serverTransportTCP) Close() {
 	for _, epoll := range t.epolls {
 		epoll.Close()
@@ -263,7 +412,12 @@ type tcpConnection struct {
 	remoteAddr  net.Addr
 	readBuffer  *buffer.Buffer
 	writeBuffer *buffer.Buffer
+	operator    *netpoll.FDOperator
 	Operator
+
+	mutex    sync.Mutex // 保护writeBuffer，handler协程和epoll协程都会写
+	closed   bool
+	writable bool // 是否在监听可写事件
 }
 
 func (conn *tcpConnection) LocalAddr() net.Addr {
@@ -0,0 +1,200 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/soulnov23/go-tool/pkg/framework/limiter"
+	"github.com/soulnov23/go-tool/pkg/framework/plugin"
+	"github.com/soulnov23/go-tool/pkg/framework/transport"
+	"gopkg.in/yaml.v3"
+)
+
+// problems 收集配置的所有问题，每个问题带上yaml中的行号
+type problems struct {
+	root *yaml.Node
+	errs []error
+}
+
+func (p *problems) add(path []any, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if line := findNode(p.root, path).Line; line > 0 {
+		p.errs = append(p.errs, fmt.Errorf("line %d: %s %s", line, formatPath(path), message))
+		return
+	}
+	p.errs = append(p.errs, fmt.Errorf("%s %s", formatPath(path), message))
+}
+
+// findNode 按路径查找节点，string对应map的key，int对应数组下标，找不到时返回最近的父节点
+func findNode(node *yaml.Node, path []any) *yaml.Node {
+	if node == nil {
+		return &yaml.Node{}
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, elem := range path {
+		var next *yaml.Node
+		switch key := elem.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == key {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+func formatPath(path []any) string {
+	builder := &strings.Builder{}
+	for _, elem := range path {
+		switch key := elem.(type) {
+		case string:
+			if builder.Len() > 0 {
+				builder.WriteByte('.')
+			}
+			builder.WriteString(key)
+		case int:
+			builder.WriteString("[" + strconv.Itoa(key) + "]")
+		}
+	}
+	return builder.String()
+}
+
+// validate 检查配置并填充默认值，一次返回所有问题
+func (c *Config) validate(root *yaml.Node) error {
+	p := &problems{root: root}
+
+	if pprof := c.ProfileProfiler; pprof != nil {
+		timeouts := []struct {
+			key   string
+			value int64
+		}{
+			{"read_timeout", pprof.ReadTimeout},
+			{"write_timeout", pprof.WriteTimeout},
+			{"idle_timeout", pprof.IdleTimeout},
+		}
+		for _, timeout := range timeouts {
+			if timeout.value < 0 {
+				p.add([]any{"pprof", timeout.key}, "must not be negative: %d", timeout.value)
+			}
+		}
+	}
+
+	if c.Server == nil {
+		p.add([]any{"server"}, "is empty")
+	} else {
+		c.validateServer(p)
+	}
+
+	if c.Plugins == nil {
+		p.add([]any{"plugins"}, "is empty")
+	}
+	registered := plugin.Types()
+	notFound := false
+	for _, typ := range slices.Sorted(maps.Keys(c.Plugins)) {
+		if !slices.Contains(registered, typ) {
+			p.add([]any{"plugins", typ}, "not found")
+			notFound = true
+		}
+	}
+	if !notFound {
+		if _, err := c.Plugins.Order(); err != nil {
+			p.add([]any{"plugins"}, "invalid: %v", err)
+		}
+	}
+	return errors.Join(p.errs...)
+}
+
+func (c *Config) validateServer(p *problems) {
+	server := c.Server
+	if server.UpdateGOMAXPROCSInterval < 0 {
+		p.add([]any{"server", "update_gomaxprocs_interval"}, "must not be negative: %d", server.UpdateGOMAXPROCSInterval)
+	} else if server.UpdateGOMAXPROCSInterval == 0 {
+		server.UpdateGOMAXPROCSInterval = defaultUpdateGOMAXPROCSInterval
+	}
+	if server.MaxCloseWaitTime < 0 {
+		p.add([]any{"server", "max_close_wait_time"}, "must not be negative: %d", server.MaxCloseWaitTime)
+	}
+	if server.PluginTimeout < 0 {
+		p.add([]any{"server", "plugin_timeout"}, "must not be negative: %d", server.PluginTimeout)
+	} else if server.PluginTimeout == 0 {
+		server.PluginTimeout = defaultPluginTimeout
+	}
+	for _, name := range slices.Sorted(maps.Keys(server.PluginTimeouts)) {
+		timeout := server.PluginTimeouts[name]
+		if _, ok := c.Plugins[name]; !ok {
+			p.add([]any{"server", "plugin_timeouts", name}, "plugin type not configured")
+		} else if timeout < 0 {
+			p.add([]any{"server", "plugin_timeouts", name}, "must not be negative: %d", timeout)
+		}
+	}
+
+	names := map[string]int{}     // k=service_name,v=index
+	addresses := map[string]int{} // k=network/address,v=index
+	for i, service := range server.Services {
+		path := []any{"server", "services", i}
+		if service == nil {
+			p.add(path, "is empty")
+			continue
+		}
+		if service.Name == "" {
+			p.add(append(path, "name"), "is empty")
+		} else if j, ok := names[service.Name]; ok {
+			p.add(append(path, "name"), "duplicate with server.services[%d]: %s", j, service.Name)
+		} else {
+			names[service.Name] = i
+		}
+
+		if service.Network == "" {
+			service.Network = defaultNetwork
+		}
+		if !transport.ServerTransportSupported(service.Network) {
+			p.add(append(path, "network"), "not support: %s", service.Network)
+		}
+		if service.Address == "" {
+			p.add(append(path, "address"), "is empty")
+		} else if _, _, err := net.SplitHostPort(service.Address); err != nil {
+			p.add(append(path, "address"), "invalid: %v", err)
+		} else if j, ok := addresses[service.Network+"/"+service.Address]; ok {
+			p.add(append(path, "address"), "duplicate with server.services[%d]: %s", j, service.Address)
+		} else {
+			addresses[service.Network+"/"+service.Address] = i
+		}
+
+		if service.Protocol == "" {
+			service.Protocol = defaultProtocol
+		}
+		if !slices.Contains(protocols, service.Protocol) {
+			p.add(append(path, "protocol"), "not support: %s", service.Protocol)
+		}
+
+		if service.Timeout < 0 {
+			p.add(append(path, "timeout"), "must not be negative: %d", service.Timeout)
+		} else if service.Timeout == 0 {
+			service.Timeout = defaultServiceTimeout
+		}
+
+		if _, err := limiter.New(service.Name, service.Limiter); err != nil {
+			p.add(append(path, "limiter"), "invalid: %v", err)
+		}
+	}
+}
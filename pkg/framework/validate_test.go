@@ -0,0 +1,95 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantErrs []string
+	}{
+		{
+			name: "defaults",
+			config: `
+server:
+    services:
+        - name: rpc_service
+          address: 0.0.0.0:6666
+plugins:
+    log:
+        frame:
+            core_config:
+                - level: info
+                  formatter: console
+                  writer: console
+    tracing:
+        default:
+`,
+		},
+		{
+			name:     "empty",
+			config:   "pprof:\n    read_timeout: -1\n",
+			wantErrs: []string{"line 2: pprof.read_timeout must not be negative: -1", "server is empty", "plugins is empty"},
+		},
+		{
+			name: "invalid services",
+			config: `
+server:
+    max_close_wait_time: -1
+    services:
+        - name: rpc_service
+          address: 0.0.0.0:6666
+          protocol: grpc
+        - name: rpc_service
+          address: 0.0.0.0:6666
+          timeout: -1
+        - address: 6666
+          network: quic
+plugins:
+    unknown:
+`,
+			wantErrs: []string{
+				"line 3: server.max_close_wait_time must not be negative: -1",
+				"line 7: server.services[0].protocol not support: grpc",
+				"line 8: server.services[1].name duplicate with server.services[0]: rpc_service",
+				"line 9: server.services[1].address duplicate with server.services[0]: 0.0.0.0:6666",
+				"line 10: server.services[1].timeout must not be negative: -1",
+				"line 11: server.services[2].name is empty",
+				"line 12: server.services[2].network not support: quic",
+				"line 14: plugins.unknown not found",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "go_tool.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatalf("os.WriteFile: %v", err)
+			}
+			config, err := loadConfig(path)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("loadConfig() error = %v", err)
+				}
+				service := config.Server.Services[0]
+				if config.Server.UpdateGOMAXPROCSInterval != defaultUpdateGOMAXPROCSInterval || service.Network != defaultNetwork || service.Protocol != defaultProtocol || service.Timeout != defaultServiceTimeout {
+					t.Errorf("loadConfig() defaults = %+v %+v", config.Server, service)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("loadConfig() error = nil, want %v", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("loadConfig() error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
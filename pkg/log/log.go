@@ -21,3 +21,9 @@ type Logger interface {
 	FatalFields(msg string, fields ...zap.Field)
 	Sync() error
 }
+
+// LevelSetter 支持运行时查看和修改日志级别
+type LevelSetter interface {
+	Levels() []string
+	SetLevel(level string) error
+}
@@ -64,15 +64,38 @@ const (
 )
 
 type ZapLogger struct {
-	l *zap.Logger
+	l      *zap.Logger
+	levels []zap.AtomicLevel // 每个core的日志级别，With派生的logger共用
 }
 
 func (z *ZapLogger) With(fields ...zap.Field) Logger {
 	return &ZapLogger{
-		l: z.l.With(fields...),
+		l:      z.l.With(fields...),
+		levels: z.levels,
 	}
 }
 
+// Levels 返回每个core当前的日志级别
+func (z *ZapLogger) Levels() []string {
+	levels := make([]string, 0, len(z.levels))
+	for _, level := range z.levels {
+		levels = append(levels, level.String())
+	}
+	return levels
+}
+
+// SetLevel 运行时修改所有core的日志级别
+func (z *ZapLogger) SetLevel(level string) error {
+	zapLevel, ok := zapCoreLevelMap[level]
+	if !ok {
+		return fmt.Errorf("level[%s] not support", level)
+	}
+	for _, l := range z.levels {
+		l.SetLevel(zapLevel)
+	}
+	return nil
+}
+
 func (z *ZapLogger) Debug(args ...any) {
 	z.l.Debug(fmt.Sprint(args...))
 }
@@ -149,6 +172,7 @@ var zapCoreLevelMap = map[string]zapcore.Level{
 
 func New(c *Config) (Logger, error) {
 	var cores []zapcore.Core
+	var levels []zap.AtomicLevel
 	for _, cfg := range c.CoreConfig {
 		if cfg == nil {
 			return nil, errors.New("core config is nil")
@@ -159,12 +183,13 @@ func New(c *Config) (Logger, error) {
 		if cfg.Writer == logTypeFile && cfg.WriteConfig == nil {
 			return nil, errors.New("write config is nil")
 		}
+		level := zap.NewAtomicLevelAt(zapCoreLevelMap[cfg.Level])
 		switch cfg.Writer {
 		case logTypeConsole:
-			core := newConsoleCore(cfg)
+			core := newConsoleCore(cfg, level)
 			cores = append(cores, core)
 		case logTypeFile:
-			core, err := newFileCore(cfg)
+			core, err := newFileCore(cfg, level)
 			if err != nil {
 				return nil, errors.New("new file core: " + err.Error())
 			}
@@ -172,17 +197,19 @@ func New(c *Config) (Logger, error) {
 		default:
 			return nil, fmt.Errorf("writer type[%s] not support", cfg.Writer)
 		}
+		levels = append(levels, level)
 	}
 	return &ZapLogger{
-		l: zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddCallerSkip(c.CallerSkip), zap.AddStacktrace(zapcore.FatalLevel)),
+		l:      zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddCallerSkip(c.CallerSkip), zap.AddStacktrace(zapcore.FatalLevel)),
+		levels: levels,
 	}, nil
 }
 
-func newConsoleCore(c *CoreConfig) zapcore.Core {
-	return zapcore.NewCore(newEncoder(c), zapcore.Lock(os.Stdout), zap.NewAtomicLevelAt(zapCoreLevelMap[c.Level]))
+func newConsoleCore(c *CoreConfig, level zap.AtomicLevel) zapcore.Core {
+	return zapcore.NewCore(newEncoder(c), zapcore.Lock(os.Stdout), level)
 }
 
-func newFileCore(c *CoreConfig) (zapcore.Core, error) {
+func newFileCore(c *CoreConfig, level zap.AtomicLevel) (zapcore.Core, error) {
 	if err := os.MkdirAll(filepath.Dir(c.WriteConfig.FileName), 0o755); err != nil {
 		return nil, fmt.Errorf("create log directory: %v", err)
 	}
@@ -194,7 +221,7 @@ func newFileCore(c *CoreConfig) (zapcore.Core, error) {
 	if err != nil {
 		return nil, fmt.Errorf("open log file: %v", err)
 	}
-	return zapcore.NewCore(newEncoder(c), zapcore.Lock(file), zap.NewAtomicLevelAt(zapCoreLevelMap[c.Level])), nil
+	return zapcore.NewCore(newEncoder(c), zapcore.Lock(file), level), nil
 }
 
 func newEncoder(c *CoreConfig) zapcore.Encoder {
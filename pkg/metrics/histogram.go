@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBounds 耗时分桶上界，单位 毫秒
+var DefaultLatencyBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+
+type Histogram struct {
+	bounds []float64
+
+	mutex  sync.Mutex
+	counts []uint64 // 最后一个桶统计大于所有上界的值
+	count  uint64
+	sum    float64
+}
+
+func NewHistogram(bounds []float64) *Histogram {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+func (h *Histogram) Observe(value float64) {
+	index, _ := slices.BinarySearch(h.bounds, value)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.counts[index]++
+	h.count++
+	h.sum += value
+}
+
+// Quantile q取值0~1，在命中的桶内线性插值估算
+func (h *Histogram) Quantile(q float64) float64 {
+	return h.Snapshot().Quantile(q)
+}
+
+func (h *Histogram) Snapshot() *HistogramSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return &HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: slices.Clone(h.counts),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
+
+type HistogramSnapshot struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Count  uint64    `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+func (s *HistogramSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := q * float64(s.Count)
+	var cumulative uint64
+	for i, count := range s.Counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		// 落在最后一个桶时没有上界，直接返回最大的上界
+		if i == len(s.Bounds) {
+			if len(s.Bounds) == 0 {
+				return s.Sum / float64(s.Count)
+			}
+			return s.Bounds[len(s.Bounds)-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = s.Bounds[i-1]
+		}
+		upper := s.Bounds[i]
+		return lower + (upper-lower)*(rank-float64(cumulative))/float64(count)
+	}
+	return s.Bounds[len(s.Bounds)-1]
+}
+
+func (s *HistogramSnapshot) writeText(w io.Writer, name string) error {
+	base, labels, _ := strings.Cut(name, "{")
+	labels = strings.TrimSuffix(labels, "}")
+	withLabels := func(suffix string, extra string) string {
+		all := labels
+		if extra != "" {
+			if all != "" {
+				all += ","
+			}
+			all += extra
+		}
+		if all == "" {
+			return base + suffix
+		}
+		return base + suffix + "{" + all + "}"
+	}
+	var cumulative uint64
+	for i, count := range s.Counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(s.Bounds) {
+			le = strconv.FormatFloat(s.Bounds[i], 'f', -1, 64)
+		}
+		if _, err := fmt.Fprintf(w, "%s %d\n", withLabels("_bucket", fmt.Sprintf("le=%q", le)), cumulative); err != nil {
+			return err
+		}
+	}
+	sum := s.Sum
+	if math.IsNaN(sum) {
+		sum = 0
+	}
+	if _, err := fmt.Fprintf(w, "%s %s\n", withLabels("_sum", ""), strconv.FormatFloat(sum, 'f', -1, 64)); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %d\n", withLabels("_count", ""), s.Count)
+	return err
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	counters   sync.Map // k=name,v=*Counter
+	histograms sync.Map // k=name,v=*Histogram
+)
+
+// Name 生成带标签的指标名，如client_retry_total{service="rpc_service"}，labels按key、value成对传入
+func Name(name string, labels ...string) string {
+	if len(labels) < 2 {
+		return name
+	}
+	builder := &strings.Builder{}
+	builder.WriteString(name)
+	builder.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		fmt.Fprintf(builder, "%s=%q", labels[i], labels[i+1])
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
+
+// GetCounter 同名指标只创建一次
+func GetCounter(name string) *Counter {
+	if value, ok := counters.Load(name); ok {
+		return value.(*Counter)
+	}
+	value, _ := counters.LoadOrStore(name, &Counter{})
+	return value.(*Counter)
+}
+
+// GetHistogram 同名指标只创建一次，bounds只在第一次创建时生效
+func GetHistogram(name string, bounds []float64) *Histogram {
+	if value, ok := histograms.Load(name); ok {
+		return value.(*Histogram)
+	}
+	value, _ := histograms.LoadOrStore(name, NewHistogram(bounds))
+	return value.(*Histogram)
+}
+
+// Counters 返回所有计数器的当前值
+func Counters() map[string]int64 {
+	result := map[string]int64{}
+	counters.Range(func(key, value any) bool {
+		result[key.(string)] = value.(*Counter).Value()
+		return true
+	})
+	return result
+}
+
+// Histograms 返回所有直方图的快照
+func Histograms() map[string]*HistogramSnapshot {
+	result := map[string]*HistogramSnapshot{}
+	histograms.Range(func(key, value any) bool {
+		result[key.(string)] = value.(*Histogram).Snapshot()
+		return true
+	})
+	return result
+}
+
+// WriteText 以Prometheus文本格式输出所有指标
+func WriteText(w io.Writer) error {
+	values := Counters()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s %d\n", name, values[name]); err != nil {
+			return err
+		}
+	}
+	snapshots := Histograms()
+	names = names[:0]
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := snapshots[name].writeText(w, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
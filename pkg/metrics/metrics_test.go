@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels []string
+		want   string
+	}{
+		{"client_retry_total", nil, "client_retry_total"},
+		{"client_retry_total", []string{"service"}, "client_retry_total"},
+		{"client_retry_total", []string{"service", "rpc_service"}, `client_retry_total{service="rpc_service"}`},
+		{"client_retry_total", []string{"service", "rpc_service", "rpc", "Get"}, `client_retry_total{service="rpc_service",rpc="Get"}`},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.name, tt.labels...); got != tt.want {
+			t.Errorf("Name() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := NewHistogram([]float64{10, 20, 50, 100})
+	for i := 1; i <= 100; i++ {
+		h.Observe(float64(i))
+	}
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.1, 10},
+		{0.5, 50},
+		{0.95, 95},
+		{1, 100},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := NewHistogram(DefaultLatencyBounds).Quantile(0.99); got != 0 {
+		t.Errorf("empty Quantile() = %v, want 0", got)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	GetCounter(Name("test_total", "service", "a")).Add(3)
+	GetHistogram(Name("test_latency", "service", "a"), []float64{10}).Observe(5)
+	buffer := &bytes.Buffer{}
+	if err := WriteText(buffer); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	for _, line := range []string{
+		`test_total{service="a"} 3`,
+		`test_latency_bucket{service="a",le="10"} 1`,
+		`test_latency_bucket{service="a",le="+Inf"} 1`,
+		`test_latency_count{service="a"} 1`,
+	} {
+		if !strings.Contains(buffer.String(), line+"\n") {
+			t.Errorf("WriteText() missing %s in\n%s", line, buffer.String())
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/coroutine"
+	"github.com/soulnov23/go-tool/pkg/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPlaceholders = 65535 // 服务端预处理语句最多支持的参数个数
+	rowOverhead     = 8     // 每行的括号、逗号和每个值的长度前缀
+)
+
+type BulkOptions struct {
+	BatchRows       int                // 每批最多行数
+	MaxPacketBytes  int                // 每条SQL最大字节数，为0时使用max_allowed_packet的90%
+	Parallelism     int                // 同时执行的批次数
+	UpdateColumns   []string           // 唯一键冲突时更新的列，ON DUPLICATE KEY UPDATE col=VALUES(col)
+	ContinueOnError bool               // 某批失败后是否继续执行后面的批次
+	Progress        func(*BatchResult) // 每批执行后回调，不会并发调用
+}
+
+type BulkOption func(*BulkOptions)
+
+func WithBatchRows(n int) BulkOption {
+	return func(o *BulkOptions) {
+		o.BatchRows = n
+	}
+}
+
+func WithMaxPacketBytes(n int) BulkOption {
+	return func(o *BulkOptions) {
+		o.MaxPacketBytes = n
+	}
+}
+
+func WithParallelism(n int) BulkOption {
+	return func(o *BulkOptions) {
+		o.Parallelism = n
+	}
+}
+
+func WithUpdateColumns(columns ...string) BulkOption {
+	return func(o *BulkOptions) {
+		o.UpdateColumns = columns
+	}
+}
+
+func WithContinueOnError(b bool) BulkOption {
+	return func(o *BulkOptions) {
+		o.ContinueOnError = b
+	}
+}
+
+func WithProgress(fn func(*BatchResult)) BulkOption {
+	return func(o *BulkOptions) {
+		o.Progress = fn
+	}
+}
+
+type BatchResult struct {
+	Index        int           // 批次序号，从0开始
+	Rows         int           // 本批的行数
+	RowsAffected int64         // 唯一键冲突更新的行计为2
+	Elapsed      time.Duration // 本批执行耗时
+	Err          error
+}
+
+type BulkResult struct {
+	Batches       int   // 执行的批次数
+	FailedBatches int   // 失败的批次数
+	Rows          int64 // 成功写入的行数
+	RowsAffected  int64
+}
+
+// ChanSource 把channel转换成BulkInsert的数据源，channel关闭后结束
+func ChanSource(ch <-chan []any) iter.Seq[[]any] {
+	return func(yield func([]any) bool) {
+		for row := range ch {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// BulkInsert 把rows按行数和max_allowed_packet拆分成多条多行insert语句，并发执行
+//
+// rows可以是slices.Values(rows)或者ChanSource(ch)，每行的值和columns一一对应。
+// 返回第一个失败批次的错误，ContinueOnError为false时不再执行后面的批次
+func BulkInsert(ctx context.Context, db *gorm.DB, table string, columns []string, rows iter.Seq[[]any], opts ...BulkOption) (*BulkResult, error) {
+	defaultOpts := &BulkOptions{
+		BatchRows:   1000,
+		Parallelism: 4,
+	}
+	for _, opt := range opts {
+		opt(defaultOpts)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("bulk insert %s: columns is empty", table)
+	}
+	prefix, suffix := bulkStatement(table, columns, defaultOpts.UpdateColumns)
+	maxBytes := defaultOpts.MaxPacketBytes
+	if maxBytes <= 0 {
+		var packet int64
+		if err := db.WithContext(ctx).Raw("SELECT @@max_allowed_packet").Scan(&packet).Error; err != nil {
+			return nil, fmt.Errorf("bulk insert %s: query max_allowed_packet: %v", table, err)
+		}
+		maxBytes = int(packet * 9 / 10)
+	}
+	maxBytes -= len(prefix) + len(suffix)
+	maxRows := min(max(defaultOpts.BatchRows, 1), maxPlaceholders/len(columns))
+
+	var (
+		result   = &BulkResult{}
+		mutex    sync.Mutex
+		firstErr error
+		failed   atomic.Bool
+		batch    [][]any
+		bytes    int
+	)
+	// 数据源出错或者ctx取消时不再提交新的批次，已经提交的批次执行完再返回
+	pool := coroutine.NewPool(max(defaultOpts.Parallelism, 1), func(formatter string, args ...any) {
+		db.Logger.Error(ctx, formatter, args...)
+	})
+	defer pool.Close()
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		rows, index := batch, result.Batches
+		result.Batches++
+		batch, bytes = nil, 0
+		pool.Go(func(...any) {
+			batchResult := &BatchResult{Index: index, Rows: len(rows)}
+			begin := time.Now()
+			func() {
+				defer func() {
+					if err := recover(); err != nil {
+						batchResult.Err = fmt.Errorf("[PANIC] %v\n%s", err, utils.BytesToString(debug.Stack()))
+					}
+				}()
+				batchResult.RowsAffected, batchResult.Err = execBatch(ctx, db, prefix, suffix, len(columns), rows)
+			}()
+			batchResult.Elapsed = time.Since(begin)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if batchResult.Err != nil {
+				result.FailedBatches++
+				failed.Store(true)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("bulk insert %s batch %d: %w", table, index, batchResult.Err)
+				}
+			} else {
+				result.Rows += int64(len(rows))
+				result.RowsAffected += batchResult.RowsAffected
+			}
+			if defaultOpts.Progress != nil {
+				defaultOpts.Progress(batchResult)
+			}
+		})
+	}
+	var sourceErr error
+	line := 0
+	for row := range rows {
+		line++
+		if len(row) != len(columns) {
+			sourceErr = fmt.Errorf("bulk insert %s row %d: %d values, want %d", table, line, len(row), len(columns))
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			sourceErr = fmt.Errorf("bulk insert %s: %w", table, err)
+			break
+		}
+		if failed.Load() && !defaultOpts.ContinueOnError {
+			break
+		}
+		size := rowSize(row)
+		if len(batch) > 0 && (len(batch) >= maxRows || bytes+size > maxBytes) {
+			flush()
+		}
+		batch = append(batch, row)
+		bytes += size
+	}
+	if sourceErr == nil && (!failed.Load() || defaultOpts.ContinueOnError) {
+		flush()
+	}
+	pool.Wait()
+	mutex.Lock()
+	defer mutex.Unlock()
+	if sourceErr != nil {
+		return result, sourceErr
+	}
+	return result, firstErr
+}
+
+// bulkStatement 返回values之前和之后的SQL
+func bulkStatement(table string, columns []string, updateColumns []string) (string, string) {
+	builder := &strings.Builder{}
+	builder.WriteString("INSERT INTO ")
+	writeIdentifier(builder, table)
+	builder.WriteString(" (")
+	for i, column := range columns {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		writeIdentifier(builder, column)
+	}
+	builder.WriteString(") VALUES ")
+	prefix := builder.String()
+	if len(updateColumns) == 0 {
+		return prefix, ""
+	}
+	builder.Reset()
+	builder.WriteString(" ON DUPLICATE KEY UPDATE ")
+	for i, column := range updateColumns {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		writeIdentifier(builder, column)
+		builder.WriteString("=VALUES(")
+		writeIdentifier(builder, column)
+		builder.WriteByte(')')
+	}
+	return prefix, builder.String()
+}
+
+// writeIdentifier database.table写成`database`.`table`
+func writeIdentifier(builder *strings.Builder, name string) {
+	for i, part := range strings.Split(name, ".") {
+		if i > 0 {
+			builder.WriteByte('.')
+		}
+		builder.WriteByte('`')
+		builder.WriteString(strings.ReplaceAll(part, "`", "``"))
+		builder.WriteByte('`')
+	}
+}
+
+// rowSize 估算一行在SQL中占用的字节数
+func rowSize(row []any) int {
+	size := rowOverhead
+	for _, value := range row {
+		switch v := value.(type) {
+		case string:
+			size += len(v) + rowOverhead
+		case []byte:
+			size += len(v) + rowOverhead
+		default:
+			size += 2 * rowOverhead
+		}
+	}
+	return size
+}
+
+func execBatch(ctx context.Context, db *gorm.DB, prefix string, suffix string, columns int, rows [][]any) (int64, error) {
+	builder := &strings.Builder{}
+	builder.Grow(len(prefix) + len(suffix) + len(rows)*(columns*2+2))
+	builder.WriteString(prefix)
+	args := make([]any, 0, len(rows)*columns)
+	for i, row := range rows {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteByte('(')
+		for j := range row {
+			if j > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteByte('?')
+		}
+		builder.WriteByte(')')
+		args = append(args, row...)
+	}
+	builder.WriteString(suffix)
+	tx := db.WithContext(ctx).Exec(builder.String(), args...)
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,158 @@
+package mysql
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"gorm.io/gorm/logger"
+)
+
+func TestBulkInsert(t *testing.T) {
+	rows := make([][]any, 10)
+	for i := range rows {
+		rows[i] = []any{i, strings.Repeat("x", 50)}
+	}
+	tests := []struct {
+		name    string
+		table   string
+		opts    []BulkOption
+		batches []int // 每批的行数
+		suffix  string
+	}{
+		{
+			name:    "batch rows",
+			table:   "user",
+			opts:    []BulkOption{WithBatchRows(4)},
+			batches: []int{4, 4, 2},
+		},
+		{
+			// max_allowed_packet为1024，90%减去SQL前缀后每行82字节最多可以放10行
+			name:    "max allowed packet",
+			table:   "db.user",
+			batches: []int{10},
+		},
+		{
+			name:    "max packet bytes",
+			table:   "user",
+			opts:    []BulkOption{WithMaxPacketBytes(300), WithParallelism(1)},
+			batches: []int{3, 3, 3, 1},
+		},
+		{
+			name:    "update columns",
+			table:   "user",
+			opts:    []BulkOption{WithBatchRows(5), WithUpdateColumns("name")},
+			batches: []int{5, 5},
+			suffix:  " ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+		},
+	}
+	db, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger, WithDriverName("fake_mysql"), WithLogLevel(logger.Silent))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(db)
+	server := getFakeServer(t.Name() + "/primary")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.take()
+			var mutex sync.Mutex
+			var progress []int
+			opts := append(tt.opts, WithProgress(func(batch *BatchResult) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				progress = append(progress, batch.Index)
+				if batch.Err != nil || batch.Rows != tt.batches[batch.Index] {
+					t.Errorf("batch %d = %+v, want %d rows", batch.Index, batch, tt.batches[batch.Index])
+				}
+			}))
+			result, err := BulkInsert(context.Background(), db, tt.table, []string{"id", "name"}, slices.Values(rows), opts...)
+			if err != nil {
+				t.Fatalf("BulkInsert: %v", err)
+			}
+			if result.Batches != len(tt.batches) || result.Rows != 10 || result.FailedBatches != 0 {
+				t.Errorf("BulkInsert() = %+v, want %d batches", result, len(tt.batches))
+			}
+			slices.Sort(progress)
+			if len(progress) != len(tt.batches) || progress[len(progress)-1] != len(tt.batches)-1 {
+				t.Errorf("progress = %v", progress)
+			}
+			var got []int
+			for _, query := range server.take() {
+				if query == "SELECT @@max_allowed_packet" {
+					continue
+				}
+				prefix := "INSERT INTO `user` (`id`,`name`) VALUES "
+				if tt.table == "db.user" {
+					prefix = "INSERT INTO `db`.`user` (`id`,`name`) VALUES "
+				}
+				if !strings.HasPrefix(query, prefix) || !strings.HasSuffix(query, "(?,?)"+tt.suffix) {
+					t.Errorf("query = %q", query)
+				}
+				got = append(got, strings.Count(query, "(?,?)"))
+			}
+			slices.Sort(got)
+			want := slices.Sorted(slices.Values(tt.batches))
+			if !slices.Equal(got, want) {
+				t.Errorf("batches = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBulkInsertChan(t *testing.T) {
+	db, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger, WithDriverName("fake_mysql"), WithLogLevel(logger.Silent))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(db)
+	ch := make(chan []any)
+	go func() {
+		defer close(ch)
+		for i := range 25 {
+			ch <- []any{i}
+		}
+	}()
+	result, err := BulkInsert(context.Background(), db, "user", []string{"id"}, ChanSource(ch), WithBatchRows(10), WithMaxPacketBytes(1<<20))
+	if err != nil || result.Batches != 3 || result.Rows != 25 || result.RowsAffected != 3 {
+		t.Errorf("BulkInsert() = %+v, %v", result, err)
+	}
+}
+
+func TestBulkInsertError(t *testing.T) {
+	db, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger, WithDriverName("fake_mysql"), WithLogLevel(logger.Silent))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(db)
+	rows := make([][]any, 100)
+	for i := range rows {
+		rows[i] = []any{i}
+	}
+	opts := []BulkOption{WithBatchRows(10), WithMaxPacketBytes(1 << 20), WithParallelism(1)}
+
+	// 表名包含duplicate时fake driver返回1062错误
+	result, err := BulkInsert(context.Background(), db, "duplicate", []string{"id"}, slices.Values(rows), opts...)
+	if err == nil || !IsDuplicateKey(err) || !strings.Contains(err.Error(), "bulk insert duplicate batch 0") {
+		t.Errorf("BulkInsert() error = %v, want duplicate key of batch 0", err)
+	}
+	if result.FailedBatches == 0 || result.Batches >= 10 || result.Rows != 0 {
+		t.Errorf("BulkInsert() = %+v, want stop after failed batch", result)
+	}
+
+	result, err = BulkInsert(context.Background(), db, "duplicate", []string{"id"}, slices.Values(rows), append(opts, WithContinueOnError(true))...)
+	if err == nil || result.Batches != 10 || result.FailedBatches != 10 {
+		t.Errorf("BulkInsert() with ContinueOnError = %+v, %v, want 10 failed batches", result, err)
+	}
+
+	rows[15] = []any{15, "extra"}
+	if _, err := BulkInsert(context.Background(), db, "user", []string{"id"}, slices.Values(rows), opts...); err == nil ||
+		!strings.Contains(err.Error(), "row 16: 2 values, want 1") {
+		t.Errorf("BulkInsert() error = %v, want values mismatch", err)
+	}
+	if _, err := BulkInsert(context.Background(), db, "user", nil, slices.Values(rows), opts...); err == nil {
+		t.Errorf("BulkInsert() without columns error = nil")
+	}
+}
@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// *mysql.MySQLError.Number参考https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+const (
+	errTooManyConnections     = 1040
+	errUserTooManyConnections = 1203
+	errLockWaitTimeout        = 1205
+	errDeadlock               = 1213
+	errDuplicateEntry         = 1062
+)
+
+var (
+	ErrRecordNotFound = &errors.Error{
+		Code:    404,
+		Status:  "Not Found",
+		Name:    "RecordNotFound",
+		Message: "record not found: {{.}}",
+	}
+	ErrDuplicateKey = &errors.Error{
+		Code:    409,
+		Status:  "Conflict",
+		Name:    "DuplicateKey",
+		Message: "duplicate key: {{.}}",
+	}
+	ErrTxConflict = &errors.Error{
+		Code:    409,
+		Status:  "Conflict",
+		Name:    "TxConflict",
+		Message: "transaction conflict: {{.}}",
+	}
+	ErrInternalDB = &errors.Error{
+		Code:    500,
+		Status:  "Internal Server Error",
+		Name:    "InternalDB",
+		Message: "db internal error: {{.}}",
+	}
+	ErrUnavailableDB = &errors.Error{
+		Code:    503,
+		Status:  "Service Unavailable",
+		Name:    "UnavailableDB",
+		Message: "db system busy: {{.}}",
+	}
+	ErrTimeoutDB = &errors.Error{
+		Code:    504,
+		Status:  "Gateway Timeout",
+		Name:    "TimeoutDB",
+		Message: "db timeout: {{.}}",
+	}
+)
+
+func errorNumberOf(err error) uint16 {
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) {
+		return mysqlErr.Number
+	}
+	return 0
+}
+
+// IsDuplicateKey 唯一键冲突，比如重复插入，err可以是驱动的错误或者Classify转换后的错误
+func IsDuplicateKey(err error) bool {
+	var e *errors.Error
+	if stderrors.As(err, &e) {
+		return errors.Equal(e, ErrDuplicateKey)
+	}
+	return errorNumberOf(err) == errDuplicateEntry
+}
+
+// IsRetryable 死锁和锁等待超时时MySQL已经回滚了事务，重新执行整个事务通常可以成功
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *errors.Error
+	if stderrors.As(err, &e) {
+		return errors.Equal(e, ErrTxConflict)
+	}
+	switch errorNumberOf(err) {
+	case errLockWaitTimeout, errDeadlock:
+		return true
+	}
+	return false
+}
+
+// Classify 把数据库错误转换成errors.Error，handler可以直接返回，已经是errors.Error和业务自己的错误原样返回
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*errors.Error); ok {
+		return err
+	}
+	switch {
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		return ErrRecordNotFound.Clone().WithMessageValues(err.Error())
+	case IsDuplicateKey(err):
+		return ErrDuplicateKey.Clone().WithMessageValues(err.Error())
+	case IsRetryable(err):
+		return ErrTxConflict.Clone().WithMessageValues(err.Error())
+	}
+	switch errorNumberOf(err) {
+	case 0:
+	case errTooManyConnections, errUserTooManyConnections:
+		return ErrUnavailableDB.Clone().WithMessageValues(err.Error())
+	default:
+		return ErrInternalDB.Clone().WithMessageValues(err.Error())
+	}
+	var netErr net.Error
+	if stderrors.Is(err, context.DeadlineExceeded) || stderrors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeoutDB.Clone().WithMessageValues(err.Error())
+	}
+	// 连接被拒绝、连接断开和连接池已关闭
+	var opErr *net.OpError
+	if stderrors.As(err, &opErr) || stderrors.Is(err, driver.ErrBadConn) || stderrors.Is(err, mysql.ErrInvalidConn) || stderrors.Is(err, sql.ErrConnDone) {
+		return ErrUnavailableDB.Clone().WithMessageValues(err.Error())
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func TestClassify(t *testing.T) {
+	errBusiness := stderrors.New("balance not enough")
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name         string
+		err          error
+		want         error
+		wantMessage  string
+		duplicateKey bool
+		retryable    bool
+	}{
+		{name: "nil"},
+		{name: "not found", err: gorm.ErrRecordNotFound, want: ErrRecordNotFound, wantMessage: "record not found: record not found"},
+		{name: "wrapped not found", err: fmt.Errorf("get user: %w", gorm.ErrRecordNotFound), want: ErrRecordNotFound},
+		{
+			name:         "duplicate key",
+			err:          &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'name'"},
+			want:         ErrDuplicateKey,
+			wantMessage:  "duplicate key: Error 1062: Duplicate entry 'a' for key 'name'",
+			duplicateKey: true,
+		},
+		{name: "deadlock", err: &mysql.MySQLError{Number: 1213}, want: ErrTxConflict, retryable: true},
+		{name: "lock wait timeout", err: &mysql.MySQLError{Number: 1205}, want: ErrTxConflict, retryable: true},
+		{name: "too many connections", err: &mysql.MySQLError{Number: 1040}, want: ErrUnavailableDB},
+		{name: "syntax error", err: &mysql.MySQLError{Number: 1064}, want: ErrInternalDB},
+		{name: "connection refused", err: refused, want: ErrUnavailableDB},
+		{name: "bad conn", err: driver.ErrBadConn, want: ErrUnavailableDB},
+		{name: "invalid conn", err: mysql.ErrInvalidConn, want: ErrUnavailableDB},
+		{name: "timeout", err: context.DeadlineExceeded, want: ErrTimeoutDB},
+		{name: "catalog error", err: ErrTxConflict, want: ErrTxConflict, retryable: true},
+		{name: "business error", err: errBusiness, want: errBusiness},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if !errors.Equal(got, tt.want) {
+				t.Fatalf("Classify() = %v, want %v", got, tt.want)
+			}
+			if tt.wantMessage != "" && got.(*errors.Error).Message != tt.wantMessage {
+				t.Errorf("Classify() message = %q, want %q", got.(*errors.Error).Message, tt.wantMessage)
+			}
+			if IsDuplicateKey(tt.err) != tt.duplicateKey || IsDuplicateKey(got) != tt.duplicateKey {
+				t.Errorf("IsDuplicateKey() = %v, want %v", IsDuplicateKey(tt.err), tt.duplicateKey)
+			}
+			if IsRetryable(tt.err) != tt.retryable || IsRetryable(got) != tt.retryable {
+				t.Errorf("IsRetryable() = %v, want %v", IsRetryable(tt.err), tt.retryable)
+			}
+		})
+	}
+	// 业务代码包装过的Classify结果
+	if !IsDuplicateKey(fmt.Errorf("create user: %w", Classify(&mysql.MySQLError{Number: 1062}))) {
+		t.Errorf("IsDuplicateKey(wrapped) = false, want true")
+	}
+	if !IsRetryable(fmt.Errorf("transfer: %w", ErrTxConflict)) {
+		t.Errorf("IsRetryable(wrapped) = false, want true")
+	}
+	if ErrDuplicateKey.Message != "duplicate key: {{.}}" {
+		t.Errorf("Classify() modified the catalog message to %q", ErrDuplicateKey.Message)
+	}
+}
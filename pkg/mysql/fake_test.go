@@ -0,0 +1,205 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// fakeDriver 按dsn记录执行过的SQL，用于验证路由到了哪个库
+type fakeDriver struct{}
+
+type fakeServer struct {
+	mutex     sync.Mutex
+	queries   []string
+	down      atomic.Bool
+	deadlocks atomic.Int32 // 接下来多少次包含deadlock的SQL返回死锁错误
+	users     atomic.Int64 // fake_user表的行数，id从1开始
+}
+
+var fakeServers sync.Map // k=dsn,v=*fakeServer
+
+func init() {
+	sql.Register("fake_mysql", &fakeDriver{})
+}
+
+func getFakeServer(dsn string) *fakeServer {
+	server, _ := fakeServers.LoadOrStore(dsn, &fakeServer{})
+	return server.(*fakeServer)
+}
+
+func (s *fakeServer) record(query string) error {
+	if s.down.Load() {
+		return driver.ErrBadConn
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queries = append(s.queries, query)
+	return nil
+}
+
+// take 返回并清空执行过的SQL，忽略初始化时的SELECT VERSION()
+func (s *fakeServer) take() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	queries := slices.DeleteFunc(s.queries, func(query string) bool { return query == "SELECT VERSION()" })
+	s.queries = nil
+	return queries
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	server := getFakeServer(dsn)
+	if server.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeConn{server: server}, nil
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not support")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if err := c.server.record("BEGIN"); err != nil {
+		return nil, err
+	}
+	return &fakeTx{server: c.server}, nil
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	query := "BEGIN"
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		query += " " + sql.IsolationLevel(opts.Isolation).String()
+	}
+	if opts.ReadOnly {
+		query += " READ ONLY"
+	}
+	if err := c.server.record(query); err != nil {
+		return nil, err
+	}
+	return &fakeTx{server: c.server}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	if c.server.down.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.server.record(query); err != nil {
+		return nil, err
+	}
+	if strings.Contains(query, "deadlock") && c.server.deadlocks.Add(-1) >= 0 {
+		return nil, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	}
+	if strings.Contains(query, "duplicate") {
+		return nil, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	}
+	return &fakeResult{}, nil
+}
+
+type fakeResult struct{}
+
+func (r *fakeResult) LastInsertId() (int64, error) {
+	return 1, nil
+}
+
+func (r *fakeResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.server.record(query); err != nil {
+		return nil, err
+	}
+	if query == "SELECT @@max_allowed_packet" {
+		return &fakeRows{columns: []string{"@@max_allowed_packet"}, values: [][]driver.Value{{int64(1024)}}}, nil
+	}
+	if query == "SELECT VERSION()" {
+		return &fakeRows{columns: []string{"VERSION()"}, values: [][]driver.Value{{"8.0.36"}}}, nil
+	}
+	if strings.Contains(query, "FROM `fake_user`") {
+		return c.server.queryUsers(query, args), nil
+	}
+	return &fakeRows{columns: []string{"id"}, values: [][]driver.Value{{int64(1)}}}, nil
+}
+
+// queryUsers 支持keyset分页的WHERE `id` > ?、WHERE `id` < ?、ORDER BY `id` DESC和LIMIT ?
+func (s *fakeServer) queryUsers(query string, args []driver.NamedValue) *fakeRows {
+	var ids []int64
+	for id := int64(1); id <= s.users.Load(); id++ {
+		ids = append(ids, id)
+	}
+	if strings.Contains(query, "`id` > ?") {
+		ids = slices.DeleteFunc(ids, func(id int64) bool { return id <= args[0].Value.(int64) })
+	}
+	if strings.Contains(query, "`id` < ?") {
+		ids = slices.DeleteFunc(ids, func(id int64) bool { return id >= args[0].Value.(int64) })
+	}
+	if strings.Contains(query, "`id` DESC") {
+		slices.Reverse(ids)
+	}
+	if strings.HasSuffix(query, "LIMIT ?") {
+		limit := args[len(args)-1].Value.(int64)
+		ids = ids[:min(int(limit), len(ids))]
+	}
+	rows := &fakeRows{columns: []string{"id", "name"}}
+	for _, id := range ids {
+		rows.values = append(rows.values, []driver.Value{id, "user" + strconv.FormatInt(id, 10)})
+	}
+	return rows
+}
+
+type fakeTx struct {
+	server *fakeServer
+}
+
+func (t *fakeTx) Commit() error {
+	return t.server.record("COMMIT")
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.server.record("ROLLBACK")
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	index   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.index])
+	r.index++
+	return nil
+}
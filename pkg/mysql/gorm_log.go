@@ -3,23 +3,25 @@ package mysql
 import (
 	"context"
 	"errors"
-	"fmt"
+	"math/rand/v2"
+	"strings"
 	"time"
 
 	"github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/tracing"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
 )
 
-const (
-	infoFormatter  = "[%.3fms] [rows:%v] %s"
-	warnFormatter  = "[%s] [%.3fms] [rows:%v] %s"
-	errorFormatter = "[%s] [%.3fms] [rows:%v] %s"
-)
+const redactedValue = "******"
 
 // New initialize gormLogger
-func new(logger log.Logger, opts ...Option) logger.Interface {
+func new(l log.Logger, opts ...Option) logger.Interface {
 	defaultOpts := &Options{
+		LogLevel:                  logger.Info,            // 打印所有SQL
+		SampleRate:                1,                      // 不采样，非慢查询的SQL都打印
 		SlowThreshold:             200 * time.Millisecond, // TraceLog打印慢查询日志时的阈值设为200毫秒
 		IgnoreRecordNotFoundError: false,                  // TraceLog打印错误日志时RecordNotFound错误也打印
 		ParameterizedQueries:      false,                  // TraceLog打印日志时SQL语句使用实际的参数
@@ -27,72 +29,216 @@ func new(logger log.Logger, opts ...Option) logger.Interface {
 	for _, opt := range opts {
 		opt(defaultOpts)
 	}
+	sensitiveColumns := make(map[string]struct{}, len(defaultOpts.SensitiveColumns))
+	for _, column := range defaultOpts.SensitiveColumns {
+		sensitiveColumns[strings.ToLower(column)] = struct{}{}
+	}
 	return &gormLogger{
-		Logger:  logger,
-		Options: defaultOpts,
+		Logger:           l,
+		Options:          defaultOpts,
+		level:            defaultOpts.LogLevel,
+		sensitiveColumns: sensitiveColumns,
 	}
 }
 
 type gormLogger struct {
 	log.Logger
 	*Options
+	level            logger.LogLevel
+	sensitiveColumns map[string]struct{} // k=小写的列名
 }
 
-// LogMode log mode
+// LogMode 返回指定级别的logger，db.Debug()等会调用
 func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
-	return l
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
 }
 
 // Info print info
 func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
-	l.Infof(msg, data...)
+	if l.level >= logger.Info {
+		tracing.Logger(ctx, l.Logger).Infof(msg, data...)
+	}
 }
 
 // Warn print warn messages
 func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
-	l.Warnf(msg, data...)
+	if l.level >= logger.Warn {
+		tracing.Logger(ctx, l.Logger).Warnf(msg, data...)
+	}
 }
 
 // Error print error messages
 func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
-	l.Errorf(msg, data...)
+	if l.level >= logger.Error {
+		tracing.Logger(ctx, l.Logger).Errorf(msg, data...)
+	}
 }
 
-// Trace print sql message
-//
-//nolint:cyclop
+// Trace 每条SQL执行后调用，出错打印error，慢查询打印warn，其它按SampleRate采样打印info
 func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	elapsed := time.Since(begin)
-	sql, rows := fc()
-	if err != nil && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.IgnoreRecordNotFoundError) {
-		if rows == -1 {
-			l.Errorf(errorFormatter, err, float64(elapsed.Nanoseconds())/1e6, "-", sql)
-		} else {
-			l.Errorf(errorFormatter, err, float64(elapsed.Nanoseconds())/1e6, rows, sql)
-		}
+	if l.level <= logger.Silent {
 		return
 	}
-
-	if elapsed > l.SlowThreshold && l.SlowThreshold != 0 {
-		if rows == -1 {
-			l.Warnf(warnFormatter, fmt.Sprintf("SLOW SQL >= %v", l.SlowThreshold), float64(elapsed.Nanoseconds())/1e6, "-", sql)
-		} else {
-			l.Warnf(warnFormatter, fmt.Sprintf("SLOW SQL >= %v", l.SlowThreshold), float64(elapsed.Nanoseconds())/1e6, rows, sql)
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.IgnoreRecordNotFoundError):
+		l.ErrorFields("sql error", l.fields(ctx, elapsed, fc, zap.Error(err))...)
+	case l.SlowThreshold != 0 && elapsed > l.SlowThreshold && l.level >= logger.Warn:
+		l.WarnFields("slow sql", l.fields(ctx, elapsed, fc, zap.Duration("slow_threshold", l.SlowThreshold))...)
+	case err == nil && l.level >= logger.Info:
+		if l.SampleRate < 1 && rand.Float64() >= l.SampleRate {
+			return
 		}
-		return
+		l.InfoFields("sql", l.fields(ctx, elapsed, fc)...)
 	}
+}
 
-	if rows == -1 {
-		l.Infof(infoFormatter, float64(elapsed.Nanoseconds())/1e6, "-", sql)
-	} else {
-		l.Infof(infoFormatter, float64(elapsed.Nanoseconds())/1e6, rows, sql)
+func (l *gormLogger) fields(ctx context.Context, elapsed time.Duration, fc func() (string, int64), extra ...zap.Field) []zap.Field {
+	sql, rows := fc()
+	fields := []zap.Field{
+		zap.String("sql", sql),
+		zap.Float64("elapsed_ms", float64(elapsed.Nanoseconds())/1e6),
+		zap.String("source", utils.FileWithLineNum()), // 业务代码中执行SQL的位置，日志自身的caller固定是本文件
+	}
+	if rows != -1 {
+		fields = append(fields, zap.Int64("rows", rows))
 	}
+	fields = append(fields, extra...)
+	return append(fields, tracing.Fields(ctx)...)
 }
 
-// ParamsFilter filter params
+// ParamsFilter 打印实际参数时把敏感列的参数替换成******
 func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
 	if l.ParameterizedQueries {
 		return sql, nil
 	}
-	return sql, params
+	if len(l.sensitiveColumns) == 0 || len(params) == 0 {
+		return sql, params
+	}
+	columns := placeholderColumns(sql)
+	var redacted []interface{}
+	for i, column := range columns {
+		if i >= len(params) {
+			break
+		}
+		if _, ok := l.sensitiveColumns[strings.ToLower(column)]; !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]interface{}{}, params...)
+		}
+		redacted[i] = redactedValue
+	}
+	if redacted == nil {
+		return sql, params
+	}
+	return sql, redacted
+}
+
+// placeholderColumns 返回SQL中每个?对应的列名，无法确定时为空
+//
+// where和set中取?之前最近的列名，比如`name` = ?、`id` IN (?,?)，insert按列的位置对应values中的?
+func placeholderColumns(sql string) []string {
+	var (
+		columns       []string
+		lastColumn    string
+		insert        bool
+		insertColumns []string
+		values        bool // 正在解析insert的values
+		depth         int
+		position      int // values中当前?在括号内的位置
+	)
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"':
+			// 字符串中的?不是占位符
+			i = skipQuoted(sql, i, c)
+		case c == '`':
+			end := skipQuoted(sql, i, c)
+			lastColumn = strings.Trim(sql[i:end], "`")
+			if insert && !values && depth == 1 {
+				insertColumns = append(insertColumns, lastColumn)
+			}
+			i = end
+		case c == '?':
+			column := lastColumn
+			if values {
+				column = ""
+				if position < len(insertColumns) {
+					column = insertColumns[position]
+				}
+			}
+			columns = append(columns, column)
+			i++
+		case c == '(':
+			depth++
+			if values && depth == 1 {
+				position = 0
+			}
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == ',':
+			if values && depth == 1 {
+				position++
+			}
+			i++
+		case isWordByte(c):
+			end := i
+			for end < len(sql) && (isWordByte(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			word := sql[i:end]
+			switch upper := strings.ToUpper(word); upper {
+			case "INSERT", "REPLACE":
+				insert = true
+			case "VALUES":
+				values = insert && depth == 0
+			case "LIMIT", "OFFSET":
+				lastColumn = ""
+			default:
+				if values && depth == 0 {
+					values = false // values之后的on duplicate key update
+				}
+				if _, ok := sqlKeywords[upper]; !ok && (c < '0' || c > '9') {
+					lastColumn = word[strings.LastIndexByte(word, '.')+1:]
+					if insert && !values && depth == 1 {
+						insertColumns = append(insertColumns, lastColumn)
+					}
+				}
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return columns
+}
+
+func skipQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+var sqlKeywords = map[string]struct{}{
+	"SELECT": {}, "FROM": {}, "WHERE": {}, "AND": {}, "OR": {}, "NOT": {}, "IN": {}, "LIKE": {}, "BETWEEN": {},
+	"IS": {}, "NULL": {}, "SET": {}, "UPDATE": {}, "DELETE": {}, "INTO": {}, "ON": {}, "DUPLICATE": {}, "KEY": {},
+	"ORDER": {}, "GROUP": {}, "BY": {}, "HAVING": {}, "ASC": {}, "DESC": {}, "AS": {}, "JOIN": {}, "LEFT": {},
+	"RIGHT": {}, "INNER": {}, "OUTER": {}, "FOR": {}, "SHARE": {}, "DISTINCT": {}, "CASE": {}, "WHEN": {},
+	"THEN": {}, "ELSE": {}, "END": {}, "EXISTS": {}, "UNION": {}, "ALL": {},
 }
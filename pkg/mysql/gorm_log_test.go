@@ -0,0 +1,150 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type entry struct {
+	level  string
+	msg    string
+	fields map[string]any
+}
+
+// recordLogger 记录*Fields打印的日志
+type recordLogger struct {
+	log.Logger
+	entries []*entry
+}
+
+func (l *recordLogger) record(level string, msg string, fields []zap.Field) {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	l.entries = append(l.entries, &entry{level: level, msg: msg, fields: encoder.Fields})
+}
+
+func (l *recordLogger) InfoFields(msg string, fields ...zap.Field) {
+	l.record("info", msg, fields)
+}
+
+func (l *recordLogger) WarnFields(msg string, fields ...zap.Field) {
+	l.record("warn", msg, fields)
+}
+
+func (l *recordLogger) ErrorFields(msg string, fields ...zap.Field) {
+	l.record("error", msg, fields)
+}
+
+func TestTrace(t *testing.T) {
+	fc := func() (string, int64) { return "SELECT * FROM `user`", 2 }
+	tests := []struct {
+		name    string
+		opts    []Option
+		level   logger.LogLevel
+		elapsed time.Duration
+		err     error
+		want    string // 空表示不打印
+	}{
+		{"info", nil, logger.Info, 0, nil, "info sql"},
+		{"slow", nil, logger.Info, time.Second, nil, "warn slow sql"},
+		{"error", nil, logger.Info, 0, errors.New("bad connection"), "error sql error"},
+		{"not found", nil, logger.Info, 0, gorm.ErrRecordNotFound, "error sql error"},
+		{"ignore not found", []Option{WithIgnoreRecordNotFoundError(true)}, logger.Info, 0, gorm.ErrRecordNotFound, ""},
+		{"warn level skips info", nil, logger.Warn, 0, nil, ""},
+		{"warn level prints slow", nil, logger.Warn, time.Second, nil, "warn slow sql"},
+		{"error level skips slow", nil, logger.Error, time.Second, nil, ""},
+		{"silent", nil, logger.Silent, 0, errors.New("bad connection"), ""},
+		{"sample none", []Option{WithSampleRate(0)}, logger.Info, 0, nil, ""},
+		{"sample keeps slow", []Option{WithSampleRate(0)}, logger.Info, time.Second, nil, "warn slow sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &recordLogger{Logger: log.DefaultLogger}
+			l := new(record, tt.opts...).LogMode(tt.level)
+			l.Trace(context.Background(), time.Now().Add(-tt.elapsed), fc, tt.err)
+			var got string
+			if len(record.entries) > 0 {
+				got = record.entries[0].level + " " + record.entries[0].msg
+			}
+			if got != tt.want {
+				t.Fatalf("Trace() = %q, want %q", got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+			fields := record.entries[0].fields
+			if fields["sql"] != "SELECT * FROM `user`" || fields["rows"] != int64(2) || fields["elapsed_ms"] == nil || fields["source"] == nil {
+				t.Errorf("Trace() fields = %v", fields)
+			}
+		})
+	}
+}
+
+func TestParamsFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		params []any
+		want   []any
+	}{
+		{
+			name:   "where",
+			sql:    "SELECT * FROM `user` WHERE `user`.`name` = ? AND password = ? LIMIT ?",
+			params: []any{"tom", "123456", 1},
+			want:   []any{"tom", redactedValue, 1},
+		},
+		{
+			name:   "in",
+			sql:    "SELECT * FROM `user` WHERE `phone` IN (?,?) AND `id` > ?",
+			params: []any{"13800000000", "13900000000", 10},
+			want:   []any{redactedValue, redactedValue, 10},
+		},
+		{
+			name:   "update",
+			sql:    "UPDATE `user` SET `password`=?,`updated_at`=? WHERE `id` = ? AND `note` = 'a ? b'",
+			params: []any{"123456", "2026-01-01", 1},
+			want:   []any{redactedValue, "2026-01-01", 1},
+		},
+		{
+			name:   "insert",
+			sql:    "INSERT INTO `user` (`name`,`password`,`phone`) VALUES (?,?,?),(?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+			params: []any{"tom", "1", "138", "jerry", "2", "139"},
+			want:   []any{"tom", redactedValue, redactedValue, "jerry", redactedValue, redactedValue},
+		},
+		{
+			name:   "no sensitive",
+			sql:    "SELECT * FROM `user` WHERE `id` = ?",
+			params: []any{1},
+			want:   []any{1},
+		},
+	}
+	l := new(log.DefaultLogger, WithSensitiveColumns("Password", "phone")).(*gormLogger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := slices.Clone(tt.params)
+			_, got := l.ParamsFilter(context.Background(), tt.sql, params...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParamsFilter() = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(params, tt.params) {
+				t.Errorf("ParamsFilter() modified params to %v", params)
+			}
+		})
+	}
+
+	parameterized := new(log.DefaultLogger, WithSensitiveColumns("password"), WithParameterizedQueries(true)).(*gormLogger)
+	if _, got := parameterized.ParamsFilter(context.Background(), "SELECT 1", "x"); got != nil {
+		t.Errorf("ParamsFilter() with ParameterizedQueries = %v, want nil", got)
+	}
+}
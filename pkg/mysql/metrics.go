@@ -0,0 +1,188 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/metrics"
+	"gorm.io/gorm"
+)
+
+const (
+	metricsName     = "mysql:metrics"
+	metricsStartKey = "metrics:start"
+)
+
+var (
+	stringPattern = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	numberPattern = regexp.MustCompile(`\b-?\d+(?:\.\d+)?\b`)
+	listPattern   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	tuplesPattern = regexp.MustCompile(`\(\?\+\)(?:\s*,\s*\(\?\+\))+`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint 去掉SQL中的字面量，参数个数不同的同一类SQL得到相同的指纹，比如
+// select * from t where id in (1,2,3) and name = 'a'得到select * from t where id in (?+) and name = ?
+func Fingerprint(sql string) string {
+	sql = stringPattern.ReplaceAllString(sql, "?")
+	sql = numberPattern.ReplaceAllString(sql, "?")
+	sql = listPattern.ReplaceAllString(sql, "(?+)")
+	sql = tuplesPattern.ReplaceAllString(sql, "(?+)")
+	sql = spacePattern.ReplaceAllString(sql, " ")
+	return strings.TrimSpace(sql)
+}
+
+type SlowQuery struct {
+	Fingerprint string  `json:"fingerprint"`
+	Operation   string  `json:"operation"`
+	Table       string  `json:"table"`
+	Count       int64   `json:"count"`
+	TotalMs     float64 `json:"total_ms"`
+	MaxMs       float64 `json:"max_ms"`
+}
+
+func (q *SlowQuery) AvgMs() float64 {
+	return q.TotalMs / float64(q.Count)
+}
+
+// metricsRecorder gorm插件，每条SQL执行后记录耗时、错误码和影响行数，超过SlowThreshold的SQL按指纹聚合
+type metricsRecorder struct {
+	name          string
+	slowThreshold time.Duration
+	maxSlow       int
+	mutex         sync.Mutex
+	slowQueries   map[string]*SlowQuery // k=fingerprint
+}
+
+func newMetricsRecorder(opts *Options) *metricsRecorder {
+	return &metricsRecorder{
+		name:          opts.Name,
+		slowThreshold: opts.SlowThreshold,
+		maxSlow:       opts.MaxSlowQueries,
+		slowQueries:   map[string]*SlowQuery{},
+	}
+}
+
+func (r *metricsRecorder) Name() string {
+	return metricsName
+}
+
+func (r *metricsRecorder) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callback.Create().Before("*").Register, callback.Create().After("*").Register},
+		{"query", callback.Query().Before("*").Register, callback.Query().After("*").Register},
+		{"update", callback.Update().Before("*").Register, callback.Update().After("*").Register},
+		{"delete", callback.Delete().Before("*").Register, callback.Delete().After("*").Register},
+		{"row", callback.Row().Before("*").Register, callback.Row().After("*").Register},
+		{"raw", callback.Raw().Before("*").Register, callback.Raw().After("*").Register},
+	}
+	for _, hook := range hooks {
+		if err := hook.before(metricsName+"_before_"+hook.name, beforeMetrics); err != nil {
+			return fmt.Errorf("register %s_before_%s: %v", metricsName, hook.name, err)
+		}
+		if err := hook.after(metricsName+"_after_"+hook.name, r.after(hook.name)); err != nil {
+			return fmt.Errorf("register %s_after_%s: %v", metricsName, hook.name, err)
+		}
+	}
+	return nil
+}
+
+func beforeMetrics(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func (r *metricsRecorder) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		elapsed := float64(time.Since(start).Nanoseconds()) / 1e6
+		table := db.Statement.Table
+		metrics.GetHistogram(metrics.Name("mysql_latency_ms", "db", r.name, "operation", operation, "table", table), metrics.DefaultLatencyBounds).Observe(elapsed)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			metrics.GetCounter(metrics.Name("mysql_errors_total", "db", r.name, "operation", operation, "table", table, "number", errorNumber(db.Error))).Inc()
+		}
+		if db.RowsAffected > 0 {
+			metrics.GetCounter(metrics.Name("mysql_rows_affected_total", "db", r.name, "operation", operation, "table", table)).Add(db.RowsAffected)
+		}
+		if r.slowThreshold > 0 && elapsed >= float64(r.slowThreshold.Nanoseconds())/1e6 {
+			r.recordSlow(Fingerprint(db.Statement.SQL.String()), operation, table, elapsed)
+		}
+	}
+}
+
+// errorNumber 返回*mysql.MySQLError.Number，其它错误比如连接断开返回0
+func errorNumber(err error) string {
+	return strconv.Itoa(int(errorNumberOf(err)))
+}
+
+// recordSlow 指纹数量达到上限时淘汰总耗时最小的指纹
+func (r *metricsRecorder) recordSlow(fingerprint string, operation string, table string, elapsed float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	query, ok := r.slowQueries[fingerprint]
+	if !ok {
+		if r.maxSlow > 0 && len(r.slowQueries) >= r.maxSlow {
+			var minKey string
+			for key, value := range r.slowQueries {
+				if minKey == "" || value.TotalMs < r.slowQueries[minKey].TotalMs {
+					minKey = key
+				}
+			}
+			delete(r.slowQueries, minKey)
+		}
+		query = &SlowQuery{Fingerprint: fingerprint, Operation: operation, Table: table}
+		r.slowQueries[fingerprint] = query
+	}
+	query.Count++
+	query.TotalMs += elapsed
+	query.MaxMs = max(query.MaxMs, elapsed)
+}
+
+func (r *metricsRecorder) top(n int) []*SlowQuery {
+	r.mutex.Lock()
+	result := make([]*SlowQuery, 0, len(r.slowQueries))
+	for _, query := range r.slowQueries {
+		copied := *query
+		result = append(result, &copied)
+	}
+	r.mutex.Unlock()
+	slices.SortFunc(result, func(a, b *SlowQuery) int {
+		if a.TotalMs != b.TotalMs {
+			if a.TotalMs > b.TotalMs {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// SlowQueries 返回总耗时最大的n个慢查询指纹，n小于等于0时返回全部，没有开启WithMetrics时返回nil
+func SlowQueries(db *gorm.DB, n int) []*SlowQuery {
+	recorder, ok := db.Config.Plugins[metricsName].(*metricsRecorder)
+	if !ok {
+		return nil
+	}
+	return recorder.top(n)
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/metrics"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM `t1` WHERE `id` = 10 AND name = 'a''b'", "SELECT * FROM `t1` WHERE `id` = ? AND name = ?"},
+		{"SELECT * FROM t WHERE id IN (1, 2,3)", "SELECT * FROM t WHERE id IN (?+)"},
+		{"SELECT * FROM t WHERE id IN (?,?)", "SELECT * FROM t WHERE id IN (?+)"},
+		{"INSERT INTO `t` (`a`,`b`) VALUES (?,?),(?,?),\n(?,?)", "INSERT INTO `t` (`a`,`b`) VALUES (?+)"},
+		{"UPDATE t SET price = 1.5, note = \"x\"   WHERE id = ?", "UPDATE t SET price = ?, note = ? WHERE id = ?"},
+	}
+	for _, tt := range tests {
+		if got := Fingerprint(tt.sql); got != tt.want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	db, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger, WithDriverName("fake_mysql"),
+		WithMetrics(true), WithName(t.Name()), WithSlowThreshold(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(db)
+
+	latencyName := metrics.Name("mysql_latency_ms", "db", t.Name(), "operation", "query", "table", "user")
+	errorsTotal := metrics.GetCounter(metrics.Name("mysql_errors_total", "db", t.Name(), "operation", "raw", "table", "", "number", "1062"))
+	rowsTotal := metrics.GetCounter(metrics.Name("mysql_rows_affected_total", "db", t.Name(), "operation", "raw", "table", ""))
+	latencyBefore, errorsBefore, rowsBefore := uint64(0), errorsTotal.Value(), rowsTotal.Value()
+	if histogram := metrics.Histograms()[latencyName]; histogram != nil {
+		latencyBefore = histogram.Count
+	}
+	for _, id := range []int{1, 2, 3} {
+		if err := db.Table("user").Where("id = ?", id).Find(&[]*fakeTable{}).Error; err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+	}
+	if err := db.Exec("UPDATE user SET name = 'a' WHERE id = 1").Error; err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if err := db.Exec("INSERT INTO user VALUES (1) -- duplicate").Error; err == nil {
+		t.Fatalf("Exec duplicate: want error")
+	}
+
+	if histogram := metrics.Histograms()[latencyName]; histogram == nil || histogram.Count-latencyBefore != 3 {
+		t.Errorf("mysql_latency_ms = %+v, want 3 more observations", histogram)
+	}
+	if got := errorsTotal.Value() - errorsBefore; got != 1 {
+		t.Errorf("mysql_errors_total = %d, want 1", got)
+	}
+	if got := rowsTotal.Value() - rowsBefore; got != 1 {
+		t.Errorf("mysql_rows_affected_total = %d, want 1", got)
+	}
+
+	queries := SlowQueries(db, 0)
+	if len(queries) != 3 {
+		t.Fatalf("SlowQueries() = %d fingerprints, want 3", len(queries))
+	}
+	var found bool
+	for _, query := range queries {
+		if query.Fingerprint == "SELECT `user`.`id` FROM `user` WHERE id = ?" {
+			found = query.Count == 3 && query.Operation == "query" && query.Table == "user"
+		}
+	}
+	if !found {
+		t.Errorf("SlowQueries() = %+v, want select fingerprint with count 3", queries[0])
+	}
+	if top := SlowQueries(db, 1); len(top) != 1 || top[0].TotalMs < queries[1].TotalMs {
+		t.Errorf("SlowQueries(1) = %+v", top)
+	}
+}
+
+func TestSlowQueriesEvict(t *testing.T) {
+	recorder := newMetricsRecorder(&Options{MaxSlowQueries: 2})
+	recorder.recordSlow("a", "query", "t", 120)
+	recorder.recordSlow("b", "query", "t", 100)
+	recorder.recordSlow("b", "query", "t", 150)
+	recorder.recordSlow("c", "query", "t", 200) // 淘汰总耗时最小的a
+	var got []string
+	for _, query := range recorder.top(0) {
+		got = append(got, fmt.Sprintf("%s:%d:%.0f:%.0f", query.Fingerprint, query.Count, query.TotalMs, query.MaxMs))
+	}
+	if want := []string{"b:2:250:150", "c:1:200:200"}; !slices.Equal(got, want) {
+		t.Errorf("top() = %v, want %v", got, want)
+	}
+}
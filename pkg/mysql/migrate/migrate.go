@@ -0,0 +1,297 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Options struct {
+	Table       string        // 记录已执行迁移的表
+	LockName    string        // GET_LOCK的锁名，同一时间只有一个实例执行迁移
+	LockTimeout time.Duration // 等待锁的时间，GET_LOCK按秒计，不足1秒的部分向上取整
+	DryRun      bool          // 只输出要执行的SQL，db使用mysql.WithDryRun创建时也会打开
+	Output      io.Writer     // 输出执行的SQL
+}
+
+type Option func(*Options)
+
+func WithTable(table string) Option {
+	return func(o *Options) {
+		o.Table = table
+	}
+}
+
+func WithLockName(name string) Option {
+	return func(o *Options) {
+		o.LockName = name
+	}
+}
+
+func WithLockTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.LockTimeout = t
+	}
+}
+
+func WithDryRun(b bool) Option {
+	return func(o *Options) {
+		o.DryRun = b
+	}
+}
+
+func WithOutput(w io.Writer) Option {
+	return func(o *Options) {
+		o.Output = w
+	}
+}
+
+type Status struct {
+	Version   uint64    `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at,omitzero"`
+	Modified  bool      `json:"modified"` // 执行后迁移文件被修改
+	Missing   bool      `json:"missing"`  // 已执行但是找不到迁移文件
+}
+
+type applied struct {
+	version   uint64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+	opts       *Options
+}
+
+func New(db *gorm.DB, migrations []*Migration, opts ...Option) *Migrator {
+	defaultOpts := &Options{
+		Table:       "schema_migrations",
+		LockTimeout: 10 * time.Second,
+		DryRun:      db.DryRun,
+		Output:      io.Discard,
+	}
+	for _, opt := range opts {
+		opt(defaultOpts)
+	}
+	if defaultOpts.LockName == "" {
+		defaultOpts.LockName = defaultOpts.Table
+	}
+	return &Migrator{db: db, migrations: migrations, opts: defaultOpts}
+}
+
+// Status 返回所有迁移文件和已执行记录的状态，按版本号升序
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var result []*Status
+	err := m.withConn(ctx, false, func(conn *sql.Conn) error {
+		applieds, err := m.applied(ctx, conn, false)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := &Status{Version: migration.Version, Name: migration.Name}
+			if record, ok := applieds[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = record.appliedAt
+				status.Modified = record.checksum != migration.Checksum
+				delete(applieds, migration.Version)
+			}
+			result = append(result, status)
+		}
+		for _, record := range applieds {
+			result = append(result, &Status{Version: record.version, Name: record.name, Applied: true, AppliedAt: record.appliedAt, Missing: true})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortStatus(result)
+	return result, nil
+}
+
+// Up 按版本号升序执行未执行的迁移，target为0时执行全部，否则执行到target版本为止
+func (m *Migrator) Up(ctx context.Context, target uint64) ([]*Migration, error) {
+	var result []*Migration
+	err := m.withConn(ctx, true, func(conn *sql.Conn) error {
+		applieds, err := m.applied(ctx, conn, true)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applieds); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if target != 0 && migration.Version > target {
+				break
+			}
+			if _, ok := applieds[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, migration.Version, migration.Up); err != nil {
+				return err
+			}
+			if err := m.exec(ctx, conn, "INSERT INTO `"+m.opts.Table+"` (version, name, checksum, applied_at) VALUES (?, ?, ?, NOW())",
+				migration.Version, migration.Name, migration.Checksum); err != nil {
+				return fmt.Errorf("record migration %d: %v", migration.Version, err)
+			}
+			result = append(result, migration)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Down 按版本号降序回滚最近执行的steps个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var result []*Migration
+	err := m.withConn(ctx, true, func(conn *sql.Conn) error {
+		applieds, err := m.applied(ctx, conn, true)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applieds); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(result) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applieds[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d has no down file", migration.Version)
+			}
+			if err := m.run(ctx, conn, migration.Version, migration.Down); err != nil {
+				return err
+			}
+			if err := m.exec(ctx, conn, "DELETE FROM `"+m.opts.Table+"` WHERE version = ?", migration.Version); err != nil {
+				return fmt.Errorf("delete migration record %d: %v", migration.Version, err)
+			}
+			result = append(result, migration)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// verify 已执行的迁移文件不能被修改或者删除，否则不同环境的表结构会不一致
+func (m *Migrator) verify(applieds map[uint64]*applied) error {
+	var errs []error
+	versions := make(map[uint64]*Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		versions[migration.Version] = migration
+	}
+	for version, record := range applieds {
+		migration, ok := versions[version]
+		if !ok {
+			errs = append(errs, fmt.Errorf("migration %d_%s applied but file not found", version, record.name))
+			continue
+		}
+		if migration.Checksum != record.checksum {
+			errs = append(errs, fmt.Errorf("migration %d_%s checksum mismatch, file modified after applied", version, migration.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, version uint64, content string) error {
+	for _, statement := range splitStatements(content) {
+		if err := m.exec(ctx, conn, statement); err != nil {
+			// MySQL的DDL会隐式提交，出错时之前的语句已经生效，需要人工处理后再执行
+			return fmt.Errorf("migration %d: %s: %v", version, statement, err)
+		}
+	}
+	return nil
+}
+
+// exec 输出SQL，DryRun时不执行
+func (m *Migrator) exec(ctx context.Context, conn *sql.Conn, query string, args ...any) error {
+	fmt.Fprintf(m.opts.Output, "%s;\n", m.db.Dialector.Explain(query, args...))
+	if m.opts.DryRun {
+		return nil
+	}
+	_, err := conn.ExecContext(ctx, query, args...)
+	return err
+}
+
+// withConn 在同一个连接上加锁、执行迁移、释放锁，GET_LOCK的锁属于连接
+func (m *Migrator) withConn(ctx context.Context, lock bool, fn func(conn *sql.Conn) error) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return fmt.Errorf("get sql.DB: %v", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get conn: %v", err)
+	}
+	defer conn.Close()
+	if lock && !m.opts.DryRun {
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.opts.LockName, lockSeconds(m.opts.LockTimeout)).Scan(&locked); err != nil {
+			return fmt.Errorf("get lock %s: %v", m.opts.LockName, err)
+		}
+		if !locked.Valid || locked.Int64 != 1 {
+			return fmt.Errorf("get lock %s timeout after %v, another instance is migrating", m.opts.LockName, m.opts.LockTimeout)
+		}
+		defer func() {
+			var released sql.NullInt64
+			_ = conn.QueryRowContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.opts.LockName).Scan(&released)
+		}()
+	}
+	return fn(conn)
+}
+
+// lockSeconds GET_LOCK的超时时间只支持秒，向上取整避免500ms这种超时变成0秒不等待
+func lockSeconds(timeout time.Duration) int64 {
+	return int64(math.Ceil(timeout.Seconds()))
+}
+
+// applied 返回已执行的迁移，表不存在时create为true则创建
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn, create bool) (map[uint64]*applied, error) {
+	var count int64
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		m.opts.Table).Scan(&count); err != nil {
+		return nil, fmt.Errorf("check table %s: %v", m.opts.Table, err)
+	}
+	result := map[uint64]*applied{}
+	if count == 0 {
+		if !create {
+			return result, nil
+		}
+		return result, m.exec(ctx, conn, "CREATE TABLE IF NOT EXISTS `"+m.opts.Table+"` ("+
+			"version BIGINT UNSIGNED NOT NULL, "+
+			"name VARCHAR(255) NOT NULL, "+
+			"checksum CHAR(64) NOT NULL, "+
+			"applied_at DATETIME NOT NULL, "+
+			"PRIMARY KEY (version)"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, UNIX_TIMESTAMP(applied_at) FROM `"+m.opts.Table+"` ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("query table %s: %v", m.opts.Table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record := &applied{}
+		var appliedAt int64 // 不依赖dsn中的parseTime参数
+		if err := rows.Scan(&record.version, &record.name, &record.checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan table %s: %v", m.opts.Table, err)
+		}
+		record.appliedAt = time.Unix(appliedAt, 0)
+		result[record.version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate table %s: %v", m.opts.Table, err)
+	}
+	return result, nil
+}
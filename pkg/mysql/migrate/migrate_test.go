@@ -0,0 +1,360 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+// fakeDriver 在内存中模拟schema_migrations表和GET_LOCK，其它SQL只记录不执行
+type fakeDriver struct{}
+
+type fakeServer struct {
+	mutex    sync.Mutex
+	created  bool
+	records  map[int64][2]string // k=version,v=(name,checksum)
+	executed []string
+	locker   *fakeConn
+	failOn   string
+}
+
+var fakeServers sync.Map // k=dsn,v=*fakeServer
+
+func init() {
+	sql.Register("fake_migrate", &fakeDriver{})
+}
+
+func getFakeServer(dsn string) *fakeServer {
+	server, _ := fakeServers.LoadOrStore(dsn, &fakeServer{records: map[int64][2]string{}})
+	return server.(*fakeServer)
+}
+
+func (s *fakeServer) take() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	executed := s.executed
+	s.executed = nil
+	return executed
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{server: getFakeServer(dsn)}, nil
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not support")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transaction not support")
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS `schema_migrations`"):
+		s.created = true
+	case strings.HasPrefix(query, "INSERT INTO `schema_migrations`"):
+		s.records[args[0].Value.(int64)] = [2]string{args[1].Value.(string), args[2].Value.(string)}
+	case strings.HasPrefix(query, "DELETE FROM `schema_migrations`"):
+		delete(s.records, args[0].Value.(int64))
+	default:
+		if s.failOn != "" && strings.Contains(query, s.failOn) {
+			return nil, errors.New("syntax error")
+		}
+		s.executed = append(s.executed, query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	rows := &fakeRows{}
+	switch {
+	case query == "SELECT VERSION()":
+		rows.values = [][]driver.Value{{"8.0.36"}}
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		locked := int64(0)
+		if s.locker == nil || s.locker == c {
+			s.locker, locked = c, 1
+		}
+		rows.values = [][]driver.Value{{locked}}
+	case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+		if s.locker == c {
+			s.locker = nil
+		}
+		rows.values = [][]driver.Value{{int64(1)}}
+	case strings.Contains(query, "information_schema.tables"):
+		count := int64(0)
+		if s.created {
+			count = 1
+		}
+		rows.values = [][]driver.Value{{count}}
+	case strings.HasPrefix(query, "SELECT version, name, checksum"):
+		for version, record := range s.records {
+			rows.values = append(rows.values, []driver.Value{version, record[0], record[1], int64(1700000000)})
+		}
+		slices.SortFunc(rows.values, func(a, b []driver.Value) int { return int(a[0].(int64) - b[0].(int64)) })
+	default:
+		return nil, errors.New("unexpected query: " + query)
+	}
+	if len(rows.values) > 0 {
+		rows.columns = make([]string, len(rows.values[0]))
+	} else {
+		rows.columns = make([]string, 4)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var testFS = fstest.MapFS{
+	"migrations/0001_create_user.up.sql":    {Data: []byte("CREATE TABLE user (id BIGINT, name VARCHAR(64));\n-- comment; not a statement\nCREATE INDEX idx_name ON user (name);\n")},
+	"migrations/0001_create_user.down.sql":  {Data: []byte("DROP TABLE user;")},
+	"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE user ADD COLUMN email VARCHAR(128) DEFAULT 'a;b';")},
+	"migrations/0002_add_email.down.sql":    {Data: []byte("ALTER TABLE user DROP COLUMN email;")},
+	"migrations/0003_seed.up.sql":           {Data: []byte("INSERT INTO user (id, name) VALUES (1, 'admin');")},
+	"migrations/README.md":                  {Data: []byte("not a migration")},
+	"invalid/0001_create_user.down.sql":     {Data: []byte("DROP TABLE user;")},
+	"conflict/0001_create_user.up.sql":      {Data: []byte("SELECT 1;")},
+	"conflict/0001_create_account.down.sql": {Data: []byte("SELECT 1;")},
+	"duplicate/0001_create_user.up.sql":     {Data: []byte("SELECT 1;")},
+	"duplicate/00001_create_user.up.sql":    {Data: []byte("SELECT 2;")},
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFS, "migrations")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, migration := range migrations {
+		got = append(got, migration.Name)
+		if len(migration.Checksum) != 64 {
+			t.Errorf("migration %d checksum = %q", migration.Version, migration.Checksum)
+		}
+	}
+	if want := []string{"create_user", "add_email", "seed"}; !slices.Equal(got, want) {
+		t.Errorf("Load() = %v, want %v", got, want)
+	}
+	if migrations[2].Down != "" {
+		t.Errorf("migration 3 down = %q, want empty", migrations[2].Down)
+	}
+	for dir, want := range map[string]string{
+		"invalid":   "has no up file",
+		"conflict":  "has different names",
+		"duplicate": "duplicate up files",
+		"missing":   "read migration dir",
+	} {
+		if _, err := Load(testFS, dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%s) error = %v, want %q", dir, err, want)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := "CREATE TABLE t (a VARCHAR(8) DEFAULT ';', b TEXT COMMENT \"x;y\");\n" +
+		"# comment;\n/* block; comment */INSERT INTO t VALUES ('it\\'s;', 'b');\n\n  ;\n`odd;name`"
+	want := []string{
+		"CREATE TABLE t (a VARCHAR(8) DEFAULT ';', b TEXT COMMENT \"x;y\")",
+		"INSERT INTO t VALUES ('it\\'s;', 'b')",
+		"`odd;name`",
+	}
+	if got := splitStatements(sql); !slices.Equal(got, want) {
+		t.Errorf("splitStatements() = %q, want %q", got, want)
+	}
+}
+
+func newTestDB(t *testing.T, opts ...mysql.Option) (*gorm.DB, *fakeServer) {
+	t.Helper()
+	db, err := mysql.New(context.Background(), t.Name(), log.DefaultLogger, append([]mysql.Option{mysql.WithDriverName("fake_migrate")}, opts...)...)
+	if err != nil {
+		t.Fatalf("mysql.New: %v", err)
+	}
+	t.Cleanup(func() { _ = mysql.Close(db) })
+	return db, getFakeServer(t.Name())
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, server := newTestDB(t)
+	migrations, err := Load(testFS, "migrations")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	migrator := New(db, migrations)
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil || len(statuses) != 3 || statuses[0].Applied || server.created {
+		t.Fatalf("Status() before up = %+v, %v, created %v", statuses, err, server.created)
+	}
+
+	applied, err := migrator.Up(ctx, 2)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("Up(2) = %d, %v, want 2 migrations", len(applied), err)
+	}
+	want := []string{
+		"CREATE TABLE user (id BIGINT, name VARCHAR(64))",
+		"CREATE INDEX idx_name ON user (name)",
+		"ALTER TABLE user ADD COLUMN email VARCHAR(128) DEFAULT 'a;b'",
+	}
+	if got := server.take(); !slices.Equal(got, want) {
+		t.Errorf("Up(2) executed %q, want %q", got, want)
+	}
+
+	applied, err = migrator.Up(ctx, 0)
+	if err != nil || len(applied) != 1 || applied[0].Version != 3 {
+		t.Fatalf("Up(0) = %v, %v, want migration 3", applied, err)
+	}
+	server.take()
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.Modified || status.Missing || status.AppliedAt.IsZero() {
+			t.Errorf("Status() = %+v, want applied", status)
+		}
+	}
+
+	if _, err := migrator.Down(ctx, 2); err == nil || !strings.Contains(err.Error(), "migration 3 has no down file") {
+		t.Errorf("Down(2) error = %v, want no down file", err)
+	}
+	server.take()
+
+	modified := slices.Clone(migrations)
+	copied := *modified[0]
+	copied.Checksum = "modified"
+	modified[0] = &copied
+	if _, err := New(db, modified[:2]).Up(ctx, 0); err == nil ||
+		!strings.Contains(err.Error(), "migration 1_create_user checksum mismatch") || !strings.Contains(err.Error(), "migration 3_seed applied but file not found") {
+		t.Errorf("Up() with modified files error = %v", err)
+	}
+	statuses, err = New(db, modified[:2]).Status(ctx)
+	if err != nil || len(statuses) != 3 || !statuses[0].Modified || !statuses[2].Missing {
+		t.Errorf("Status() with modified files = %+v, %v", statuses, err)
+	}
+
+	server.mutex.Lock()
+	delete(server.records, 3)
+	server.mutex.Unlock()
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("Down(1) = %v, %v, want migration 2", reverted, err)
+	}
+	if got := server.take(); !slices.Equal(got, []string{"ALTER TABLE user DROP COLUMN email"}) {
+		t.Errorf("Down(1) executed %q", got)
+	}
+	if server.locker != nil {
+		t.Errorf("lock not released")
+	}
+}
+
+func TestMigratorFailed(t *testing.T) {
+	db, server := newTestDB(t)
+	migrations, _ := Load(testFS, "migrations")
+	server.failOn = "CREATE INDEX"
+	applied, err := New(db, migrations).Up(context.Background(), 0)
+	if len(applied) != 0 || err == nil || !strings.Contains(err.Error(), "migration 1: CREATE INDEX idx_name ON user (name): syntax error") {
+		t.Errorf("Up() = %v, %v, want migration 1 failed", applied, err)
+	}
+	if len(server.records) != 0 {
+		t.Errorf("records = %v, want failed migration not recorded", server.records)
+	}
+}
+
+func TestMigratorLocked(t *testing.T) {
+	db, server := newTestDB(t)
+	migrations, _ := Load(testFS, "migrations")
+	server.locker = &fakeConn{}
+	_, err := New(db, migrations, WithLockTimeout(time.Second)).Up(context.Background(), 0)
+	if err == nil || !strings.Contains(err.Error(), "another instance is migrating") {
+		t.Errorf("Up() error = %v, want lock timeout", err)
+	}
+	if got := server.take(); len(got) != 0 {
+		t.Errorf("executed %q while locked", got)
+	}
+}
+
+func TestLockSeconds(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    int64
+	}{
+		{timeout: 0, want: 0},
+		{timeout: 500 * time.Millisecond, want: 1},
+		{timeout: time.Second, want: 1},
+		{timeout: 1500 * time.Millisecond, want: 2},
+	}
+	for _, tt := range tests {
+		if got := lockSeconds(tt.timeout); got != tt.want {
+			t.Errorf("lockSeconds(%v) = %d, want %d", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	db, server := newTestDB(t, mysql.WithDryRun(true))
+	migrations, _ := Load(testFS, "migrations")
+	output := &strings.Builder{}
+	applied, err := New(db, migrations, WithOutput(output)).Up(context.Background(), 1)
+	if err != nil || len(applied) != 1 {
+		t.Fatalf("Up() = %v, %v", applied, err)
+	}
+	if got := server.take(); len(got) != 0 || server.created || len(server.records) != 0 {
+		t.Errorf("dry run executed %q", got)
+	}
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS `schema_migrations`",
+		"CREATE TABLE user (id BIGINT, name VARCHAR(64));\nCREATE INDEX idx_name ON user (name);\n",
+		"INSERT INTO `schema_migrations` (version, name, checksum, applied_at) VALUES (1, 'create_user', '",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("dry run output = %s, want contains %q", output.String(), want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fileNamePattern 迁移文件名，比如0001_create_user.up.sql和0001_create_user.down.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  uint64
+	Name     string
+	Up       string
+	Down     string // 为空时不能回滚
+	Checksum string // up文件内容的sha256，已执行的迁移文件被修改时报错
+}
+
+// Load 读取dir目录下的迁移文件，按版本号升序返回，fsys可以是embed.FS或者os.DirFS
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migration dir %s: %v", dir, err)
+	}
+	migrations := map[uint64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: invalid version: %v", entry.Name(), err)
+		}
+		buffer, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration file %s: %v", entry.Name(), err)
+		}
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d has different names %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			if migration.Up != "" {
+				return nil, fmt.Errorf("migration version %d has duplicate up files", version)
+			}
+			migration.Up = string(buffer)
+			sum := sha256.Sum256(buffer)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			if migration.Down != "" {
+				return nil, fmt.Errorf("migration version %d has duplicate down files", version)
+			}
+			migration.Down = string(buffer)
+		}
+	}
+	result := make([]*Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration version %d has no up file", migration.Version)
+		}
+		result = append(result, migration)
+	}
+	slices.SortFunc(result, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return result, nil
+}
+
+// splitStatements 按;拆分多条SQL，go-sql-driver/mysql默认不支持一次执行多条语句
+func splitStatements(sql string) []string {
+	var statements []string
+	builder := &strings.Builder{}
+	flush := func() {
+		if statement := strings.TrimSpace(builder.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		builder.Reset()
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(sql) && sql[end] != c {
+				if sql[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(sql))
+			builder.WriteString(sql[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(sql[i:], "-- ") || c == '#':
+			// 单行注释
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+				builder.WriteByte('\n')
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+		case c == ';':
+			flush()
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+func sortStatus(statuses []*Status) {
+	slices.SortFunc(statuses, func(a, b *Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+}
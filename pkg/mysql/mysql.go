@@ -4,34 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/soulnov23/go-tool/pkg/log"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+// New 创建主库的连接，配置了WithReplicas时读请求自动路由到从库
 func New(ctx context.Context, dsn string, logger log.Logger, opts ...Option) (*gorm.DB, error) {
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("sql.Open: %v", err)
-	}
-	// sql.Open无法检测连接是否有效，需要Ping一下
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("db.PingContext: %v", err)
-	}
 	defaultOpts := &Options{
-		MaxIdleConns:    0, // 不保留空闲连接
-		MaxOpenConns:    0, // 不限制打开连接
-		ConnMaxLifetime: 0, // 不限制连接可以重用时间
-		ConnMaxIdleTime: 0, // 不限制连接可以空闲时间
+		MaxIdleConns:        0,                      // 不保留空闲连接
+		MaxOpenConns:        0,                      // 不限制打开连接
+		ConnMaxLifetime:     0,                      // 不限制连接可以重用时间
+		ConnMaxIdleTime:     0,                      // 不限制连接可以空闲时间
+		DriverName:          "mysql",                // go-sql-driver/mysql
+		ReplicaPolicy:       PolicyRandom,           // 随机选择从库
+		HealthCheckInterval: 5 * time.Second,        // 每5秒检查一次从库
+		MaxFailures:         3,                      // 连续失败3次摘除从库
+		Name:                "default",              // 指标的db标签
+		SlowThreshold:       200 * time.Millisecond, // 和gormLogger一致
+		MaxSlowQueries:      100,                    // 最多保留100个慢查询指纹
 	}
 	for _, opt := range opts {
 		opt(defaultOpts)
 	}
-	db.SetMaxIdleConns(defaultOpts.MaxIdleConns)
-	db.SetMaxOpenConns(defaultOpts.MaxOpenConns)
-	db.SetConnMaxLifetime(defaultOpts.ConnMaxLifetime)
-	db.SetConnMaxIdleTime(defaultOpts.ConnMaxIdleTime)
+	db, err := open(ctx, dsn, defaultOpts)
+	if err != nil {
+		return nil, err
+	}
 
 	gormLogger := new(logger, opts...)
 
@@ -45,11 +46,66 @@ func New(ctx context.Context, dsn string, logger log.Logger, opts ...Option) (*g
 		config.DryRun = defaultOpts.DryRun
 	}
 
-	// First、Last、Take等方法未找到记录时，GORM会返回gorm.ErrRecordNotFound，其它错误需要使用.(*mysql.MySQLError)转换去判断
-	// *mysql.MySQLError.Number参考https://dev.mysql.com/doc/mysql-errors/8.0/en/error-reference-introduction.html
-	orm, err := gorm.Open(mysql.New(mysql.Config{Conn: db}), config)
+	// First、Last、Take等方法未找到记录时，GORM会返回gorm.ErrRecordNotFound，其它错误是驱动的*mysql.MySQLError
+	// 使用Classify转换成errors.Error，IsDuplicateKey、IsRetryable判断常见的错误
+	orm, err := gorm.Open(mysql.New(mysql.Config{DriverName: defaultOpts.DriverName, Conn: db}), config)
 	if err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("gorm.Open: %v", err)
 	}
+	if len(defaultOpts.Replicas) > 0 {
+		resolver, err := newResolver(ctx, db, logger, defaultOpts)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("newResolver: %v", err)
+		}
+		if err := orm.Use(resolver); err != nil {
+			resolver.close()
+			_ = db.Close()
+			return nil, fmt.Errorf("use resolver: %v", err)
+		}
+	}
+	if defaultOpts.Metrics {
+		if err := orm.Use(newMetricsRecorder(defaultOpts)); err != nil {
+			_ = Close(orm)
+			return nil, fmt.Errorf("use metrics: %v", err)
+		}
+	}
+	if defaultOpts.Tracing {
+		if err := registerTracing(orm); err != nil {
+			_ = Close(orm)
+			return nil, fmt.Errorf("registerTracing: %v", err)
+		}
+	}
 	return orm, nil
 }
+
+// open 打开连接池并按Options设置参数
+func open(ctx context.Context, dsn string, opts *Options) (*sql.DB, error) {
+	db, err := sql.Open(opts.DriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %v", err)
+	}
+	// sql.Open无法检测连接是否有效，需要Ping一下
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("db.PingContext: %v", err)
+	}
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	return db, nil
+}
+
+// Close 关闭主库和从库的连接池，停止从库健康检查
+func Close(db *gorm.DB) error {
+	if resolver, ok := db.Config.Plugins[resolverName].(*resolver); ok {
+		resolver.close()
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("get sql.DB: %v", err)
+	}
+	return sqlDB.Close()
+}
@@ -1,16 +1,32 @@
 package mysql
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm/logger"
+)
 
 type Options struct {
-	MaxIdleConns              int           // 最大空闲连接数
-	MaxOpenConns              int           // 最大打开连接数
-	ConnMaxLifetime           time.Duration // 连接重用最大时间
-	ConnMaxIdleTime           time.Duration // 连接空闲最大时间
-	SlowThreshold             time.Duration // TraceLog打印慢查询日志时的阈值
-	IgnoreRecordNotFoundError bool          // TraceLog打印错误日志时是否忽略RecordNotFound错误
-	ParameterizedQueries      bool          // TraceLog打印日志时SQL语句是否使用?占位符代替实际的参数
-	DryRun                    bool          // 生成SQL但不执行
+	MaxIdleConns              int             // 最大空闲连接数
+	MaxOpenConns              int             // 最大打开连接数
+	ConnMaxLifetime           time.Duration   // 连接重用最大时间
+	ConnMaxIdleTime           time.Duration   // 连接空闲最大时间
+	SlowThreshold             time.Duration   // TraceLog打印慢查询日志时的阈值
+	IgnoreRecordNotFoundError bool            // TraceLog打印错误日志时是否忽略RecordNotFound错误
+	ParameterizedQueries      bool            // TraceLog打印日志时SQL语句是否使用?占位符代替实际的参数
+	SensitiveColumns          []string        // 打印实际参数时这些列的参数替换成******
+	LogLevel                  logger.LogLevel // 日志级别 Silent Error Warn Info
+	SampleRate                float64         // 非慢查询的SQL日志采样率 0到1
+	DryRun                    bool            // 生成SQL但不执行
+	Tracing                   bool            // 每条SQL创建链路追踪span
+	Metrics                   bool            // 记录SQL耗时、错误码和影响行数指标，按指纹聚合慢查询
+	Name                      string          // 指标的db标签，区分同一进程中的多个库
+	MaxSlowQueries            int             // 慢查询报告最多保留的指纹数
+	DriverName                string          // sql.Open的驱动名，测试时可以注册假驱动
+	Replicas                  []string        // 从库的dsn，读请求路由到从库，写请求和事务路由到主库
+	ReplicaPolicy             string          // 从库选择策略 random round_robin least_in_use
+	HealthCheckInterval       time.Duration   // 从库健康检查间隔，小于等于0时不检查
+	MaxFailures               int             // 从库连续检查失败多少次后摘除
 }
 
 type Option func(*Options)
@@ -57,8 +73,75 @@ func WithParameterizedQueries(b bool) Option {
 	}
 }
 
+func WithSensitiveColumns(columns ...string) Option {
+	return func(o *Options) {
+		o.SensitiveColumns = columns
+	}
+}
+
+func WithLogLevel(level logger.LogLevel) Option {
+	return func(o *Options) {
+		o.LogLevel = level
+	}
+}
+
+func WithSampleRate(rate float64) Option {
+	return func(o *Options) {
+		o.SampleRate = rate
+	}
+}
+
 func WithDryRun(b bool) Option {
 	return func(o *Options) {
 		o.DryRun = b
 	}
 }
+
+func WithTracing(b bool) Option {
+	return func(o *Options) {
+		o.Tracing = b
+	}
+}
+
+func WithMetrics(b bool) Option {
+	return func(o *Options) {
+		o.Metrics = b
+	}
+}
+
+func WithName(name string) Option {
+	return func(o *Options) {
+		o.Name = name
+	}
+}
+
+func WithMaxSlowQueries(n int) Option {
+	return func(o *Options) {
+		o.MaxSlowQueries = n
+	}
+}
+
+func WithDriverName(name string) Option {
+	return func(o *Options) {
+		o.DriverName = name
+	}
+}
+
+func WithReplicas(dsns ...string) Option {
+	return func(o *Options) {
+		o.Replicas = dsns
+	}
+}
+
+func WithReplicaPolicy(policy string) Option {
+	return func(o *Options) {
+		o.ReplicaPolicy = policy
+	}
+}
+
+func WithHealthCheck(interval time.Duration, maxFailures int) Option {
+	return func(o *Options) {
+		o.HealthCheckInterval = interval
+		o.MaxFailures = maxFailures
+	}
+}
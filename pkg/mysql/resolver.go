@@ -0,0 +1,290 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PolicyRandom     = "random"
+	PolicyRoundRobin = "round_robin"
+	PolicyLeastInUse = "least_in_use" // 选择正在使用的连接数最少的从库
+
+	resolverName = "mysql:resolver"
+)
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary 读请求也在主库执行，用于写后立即读的场景避免主从延迟读到旧数据
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+func isForcePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	force, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return force
+}
+
+type replica struct {
+	index    int
+	db       *sql.DB
+	healthy  atomic.Bool
+	failures atomic.Int64
+}
+
+type policy interface {
+	pick(replicas []*replica) *replica
+}
+
+type randomPolicy struct{}
+
+func (p *randomPolicy) pick(replicas []*replica) *replica {
+	return replicas[rand.IntN(len(replicas))]
+}
+
+type roundRobinPolicy struct {
+	next atomic.Uint64
+}
+
+func (p *roundRobinPolicy) pick(replicas []*replica) *replica {
+	return replicas[(p.next.Add(1)-1)%uint64(len(replicas))]
+}
+
+type leastInUsePolicy struct{}
+
+func (p *leastInUsePolicy) pick(replicas []*replica) *replica {
+	result, minInUse := replicas[0], replicas[0].db.Stats().InUse
+	for _, replica := range replicas[1:] {
+		if inUse := replica.db.Stats().InUse; inUse < minInUse {
+			result, minInUse = replica, inUse
+		}
+	}
+	return result
+}
+
+func newPolicy(name string) (policy, error) {
+	switch name {
+	case PolicyRandom:
+		return &randomPolicy{}, nil
+	case PolicyRoundRobin:
+		return &roundRobinPolicy{}, nil
+	case PolicyLeastInUse:
+		return &leastInUsePolicy{}, nil
+	default:
+		return nil, fmt.Errorf("replica policy[%s] not support", name)
+	}
+}
+
+// resolver gorm插件，在SQL执行前切换Statement.ConnPool，读请求路由到健康的从库，写请求和事务路由到主库
+type resolver struct {
+	primary     *sql.DB
+	replicas    []*replica
+	policy      policy
+	logger      log.Logger
+	interval    time.Duration
+	maxFailures int
+	done        chan struct{}
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+// newResolver 从库启动时不可用不会返回错误，而是先摘除，等健康检查成功后再加入
+func newResolver(ctx context.Context, primary *sql.DB, logger log.Logger, opts *Options) (*resolver, error) {
+	policy, err := newPolicy(opts.ReplicaPolicy)
+	if err != nil {
+		return nil, err
+	}
+	r := &resolver{
+		primary:     primary,
+		policy:      policy,
+		logger:      logger,
+		interval:    opts.HealthCheckInterval,
+		maxFailures: max(opts.MaxFailures, 1),
+		done:        make(chan struct{}),
+	}
+	for index, dsn := range opts.Replicas {
+		db, err := sql.Open(opts.DriverName, dsn)
+		if err != nil {
+			r.closeReplicas()
+			return nil, fmt.Errorf("replica[%d] sql.Open: %v", index, err)
+		}
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+		replica := &replica{index: index, db: db}
+		if err := db.PingContext(ctx); err != nil {
+			replica.failures.Store(int64(r.maxFailures))
+			logger.WarnFields("mysql replica unavailable", zap.Int("index", index), zap.Error(err))
+		} else {
+			replica.healthy.Store(true)
+		}
+		r.replicas = append(r.replicas, replica)
+	}
+	if r.interval > 0 {
+		r.wg.Add(1)
+		go r.healthCheck()
+	}
+	return r, nil
+}
+
+func (r *resolver) Name() string {
+	return resolverName
+}
+
+func (r *resolver) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	hooks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+		read     bool
+	}{
+		{"create", callback.Create().Before("*").Register, false},
+		{"query", callback.Query().Before("*").Register, true},
+		{"update", callback.Update().Before("*").Register, false},
+		{"delete", callback.Delete().Before("*").Register, false},
+		{"row", callback.Row().Before("*").Register, true},
+		{"raw", callback.Raw().Before("*").Register, false},
+	}
+	for _, hook := range hooks {
+		if err := hook.register(resolverName+"_"+hook.name, r.route(hook.read)); err != nil {
+			return fmt.Errorf("register %s_%s: %v", resolverName, hook.name, err)
+		}
+	}
+	return nil
+}
+
+// route 同一个Statement可能先读后写，所以写请求也要显式切回主库
+func (r *resolver) route(read bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+			return // 事务中的SQL都在开启事务的主库连接上执行
+		}
+		if read && !isForcePrimary(db.Statement.Context) && isRead(db.Statement) {
+			if replica := r.pick(); replica != nil {
+				db.Statement.ConnPool = replica.db
+				return
+			}
+		}
+		db.Statement.ConnPool = r.primary
+	}
+}
+
+// isRead select ... for update和Raw执行的非select语句在主库执行
+func isRead(stmt *gorm.Statement) bool {
+	if _, ok := stmt.Clauses[clause.Locking{}.Name()]; ok {
+		return false
+	}
+	query := strings.TrimSpace(stmt.SQL.String())
+	if query == "" {
+		return true // Find、First等方法在gorm:query中才生成SQL
+	}
+	return len(query) >= 6 && strings.EqualFold(query[:6], "select")
+}
+
+// pick 没有健康的从库时返回nil，读请求回退到主库
+func (r *resolver) pick() *replica {
+	healthy := make([]*replica, 0, len(r.replicas))
+	for _, replica := range r.replicas {
+		if replica.healthy.Load() {
+			healthy = append(healthy, replica)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	return r.policy.pick(healthy)
+}
+
+func (r *resolver) healthCheck() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			for _, replica := range r.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+				r.update(replica, replica.db.PingContext(ctx))
+				cancel()
+			}
+		}
+	}
+}
+
+// update 连续失败maxFailures次后摘除，成功一次后恢复
+func (r *resolver) update(replica *replica, err error) {
+	if err == nil {
+		replica.failures.Store(0)
+		if !replica.healthy.Swap(true) {
+			r.logger.InfoFields("mysql replica recovered", zap.Int("index", replica.index))
+		}
+		return
+	}
+	failures := replica.failures.Add(1)
+	if failures >= int64(r.maxFailures) && replica.healthy.Swap(false) {
+		r.logger.ErrorFields("mysql replica ejected", zap.Int("index", replica.index), zap.Int64("failures", failures), zap.Error(err))
+	}
+}
+
+func (r *resolver) close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+		r.closeReplicas()
+	})
+}
+
+func (r *resolver) closeReplicas() {
+	for _, replica := range r.replicas {
+		_ = replica.db.Close()
+	}
+}
+
+type Stats struct {
+	Primary  sql.DBStats     `json:"primary"`
+	Replicas []*ReplicaStats `json:"replicas,omitempty"`
+}
+
+type ReplicaStats struct {
+	Index    int         `json:"index"`
+	Healthy  bool        `json:"healthy"`
+	Failures int64       `json:"failures"` // 连续检查失败的次数
+	Stats    sql.DBStats `json:"stats"`
+}
+
+// GetStats 返回主库和从库的连接池状态
+func GetStats(db *gorm.DB) (*Stats, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("get sql.DB: %v", err)
+	}
+	stats := &Stats{Primary: sqlDB.Stats()}
+	if resolver, ok := db.Config.Plugins[resolverName].(*resolver); ok {
+		for _, replica := range resolver.replicas {
+			stats.Replicas = append(stats.Replicas, &ReplicaStats{
+				Index:    replica.index,
+				Healthy:  replica.healthy.Load(),
+				Failures: replica.failures.Load(),
+				Stats:    replica.db.Stats(),
+			})
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,146 @@
+package mysql
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type fakeTable struct {
+	ID int64 `gorm:"column:id"`
+}
+
+func newFakeCluster(t *testing.T, replicas int, opts ...Option) (*gorm.DB, *fakeServer, []*fakeServer) {
+	t.Helper()
+	primary := t.Name() + "/primary"
+	var dsns []string
+	var servers []*fakeServer
+	for i := range replicas {
+		dsn := t.Name() + "/replica" + string(rune('0'+i))
+		dsns = append(dsns, dsn)
+		servers = append(servers, getFakeServer(dsn))
+	}
+	opts = append([]Option{WithDriverName("fake_mysql"), WithReplicas(dsns...)}, opts...)
+	db, err := New(context.Background(), primary, log.DefaultLogger, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = Close(db) })
+	return db, getFakeServer(primary), servers
+}
+
+func TestResolverRouting(t *testing.T) {
+	db, primary, replicas := newFakeCluster(t, 2, WithReplicaPolicy(PolicyRoundRobin), WithHealthCheck(0, 1))
+	table := db.Table("fake_table")
+	tests := []struct {
+		name    string
+		run     func() error
+		primary int // 主库执行的SQL数
+		replica int // 路由到的从库下标，-1表示不走从库
+	}{
+		{"find", func() error { return db.Table("fake_table").Find(&[]*fakeTable{}).Error }, 0, 0},
+		{"round robin", func() error { return db.Table("fake_table").Find(&[]*fakeTable{}).Error }, 0, 1},
+		{"raw select", func() error { return db.Raw("SELECT id FROM fake_table").Scan(&[]*fakeTable{}).Error }, 0, 0},
+		{"create", func() error { return db.Table("fake_table").Create(&fakeTable{}).Error }, 3, -1},
+		{"exec", func() error { return db.Exec("UPDATE fake_table SET id = 2").Error }, 1, -1},
+		{"raw update", func() error { return db.Raw("UPDATE fake_table SET id = 2").Scan(&[]*fakeTable{}).Error }, 1, -1},
+		{"for update", func() error {
+			return db.Table("fake_table").Clauses(clause.Locking{Strength: "UPDATE"}).Find(&[]*fakeTable{}).Error
+		}, 1, -1},
+		{"force primary", func() error {
+			return db.WithContext(ForcePrimary(context.Background())).Table("fake_table").Find(&[]*fakeTable{}).Error
+		}, 1, -1},
+		{"transaction", func() error {
+			return db.Transaction(func(tx *gorm.DB) error {
+				return tx.Table("fake_table").Find(&[]*fakeTable{}).Error
+			})
+		}, 3, -1},
+		{"read then write", func() error {
+			if err := table.Find(&[]*fakeTable{}).Error; err != nil {
+				return err
+			}
+			return table.Where("id = ?", 1).Update("id", 2).Error
+		}, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if queries := primary.take(); len(queries) != tt.primary {
+				t.Errorf("primary queries = %q, want %d", queries, tt.primary)
+			}
+			for i, replica := range replicas {
+				queries := replica.take()
+				if i == tt.replica && len(queries) != 1 || i != tt.replica && len(queries) != 0 {
+					t.Errorf("replica%d queries = %q, want replica%d", i, queries, tt.replica)
+				}
+			}
+		})
+	}
+}
+
+func TestResolverHealthCheck(t *testing.T) {
+	db, primary, replicas := newFakeCluster(t, 2, WithReplicaPolicy(PolicyLeastInUse), WithHealthCheck(10*time.Millisecond, 2))
+	waitHealthy := func(want ...bool) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			stats, err := GetStats(db)
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+			var healthy []bool
+			for _, replica := range stats.Replicas {
+				healthy = append(healthy, replica.Healthy)
+			}
+			if slices.Equal(healthy, want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("healthy = %v, want %v", healthy, want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	find := func() {
+		t.Helper()
+		if err := db.Table("fake_table").Find(&[]*fakeTable{}).Error; err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+	}
+
+	replicas[0].down.Store(true)
+	waitHealthy(false, true)
+	replicas[1].take()
+	find()
+	if queries := replicas[1].take(); len(queries) != 1 {
+		t.Errorf("replica1 queries = %q, want 1", queries)
+	}
+
+	replicas[1].down.Store(true)
+	waitHealthy(false, false)
+	primary.take()
+	find()
+	if queries := primary.take(); len(queries) != 1 {
+		t.Errorf("primary queries = %q, want 1 when all replicas ejected", queries)
+	}
+
+	replicas[0].down.Store(false)
+	replicas[1].down.Store(false)
+	waitHealthy(true, true)
+}
+
+func TestResolverInvalidPolicy(t *testing.T) {
+	_, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger,
+		WithDriverName("fake_mysql"), WithReplicas(t.Name()+"/replica0"), WithReplicaPolicy("unknown"))
+	if err == nil || !strings.Contains(err.Error(), "replica policy[unknown] not support") {
+		t.Errorf("New() error = %v, want replica policy not support", err)
+	}
+}
@@ -0,0 +1,173 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stream 逐行读取查询结果，不会把整个结果集加载到内存中，T可以是结构体、结构体指针或者map[string]any
+//
+// db是构造好的查询，例如db.Model(&User{}).Where("status = ?", 1)或者db.Raw(sql)，没有指定表时使用T对应的表。
+// 遍历过程中连接一直被占用，提前break会关闭结果集释放连接，ctx取消时返回ctx的错误。
+// 返回的错误经过Classify转换，出错后不会再继续遍历
+func Stream[T any](ctx context.Context, db *gorm.DB) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		tx := withModel[T](db.WithContext(ctx))
+		rows, err := tx.Rows()
+		if err != nil {
+			yield(zero, Classify(err))
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			var value T
+			if err := tx.ScanRows(rows, &value); err != nil {
+				yield(zero, Classify(err))
+				return
+			}
+			if !yield(value, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, Classify(err))
+		}
+	}
+}
+
+type ChunkOptions struct {
+	Column string // 分页的列，需要是唯一的并且有索引，一般是主键
+	Size   int    // 每页行数
+	After  any    // 从大于该值的行开始读取，为nil时从头开始，用于断点续读
+	Desc   bool   // 按Column降序读取，After表示从小于该值的行开始读取
+}
+
+type ChunkOption func(*ChunkOptions)
+
+func WithChunkColumn(column string) ChunkOption {
+	return func(o *ChunkOptions) {
+		o.Column = column
+	}
+}
+
+func WithChunkSize(n int) ChunkOption {
+	return func(o *ChunkOptions) {
+		o.Size = n
+	}
+}
+
+func WithChunkAfter(value any) ChunkOption {
+	return func(o *ChunkOptions) {
+		o.After = value
+	}
+}
+
+func WithChunkDesc(b bool) ChunkOption {
+	return func(o *ChunkOptions) {
+		o.Desc = b
+	}
+}
+
+// Chunks 按Column做keyset分页分批读取大表，每页是一条WHERE column > last ORDER BY column LIMIT size的查询，
+// 和OFFSET分页不同，翻到后面的页也只扫描size行，每页之间不占用连接
+//
+// db是构造好的查询，不能包含Order和Limit，T是结构体时Column需要是T的字段，T是map[string]any时查询结果需要包含Column。
+// 返回的错误经过Classify转换，出错后不会再继续遍历
+func Chunks[T any](ctx context.Context, db *gorm.DB, opts ...ChunkOption) iter.Seq2[[]T, error] {
+	defaultOpts := &ChunkOptions{
+		Column: "id",
+		Size:   1000,
+		After:  nil,
+		Desc:   false,
+	}
+	for _, opt := range opts {
+		opt(defaultOpts)
+	}
+	return func(yield func([]T, error) bool) {
+		if defaultOpts.Size <= 0 {
+			yield(nil, fmt.Errorf("chunk size[%d] invalid", defaultOpts.Size))
+			return
+		}
+		column := clause.Column{Name: defaultOpts.Column}
+		after := defaultOpts.After
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			tx := withModel[T](db.WithContext(ctx))
+			if after != nil {
+				if defaultOpts.Desc {
+					tx = tx.Where(clause.Lt{Column: column, Value: after})
+				} else {
+					tx = tx.Where(clause.Gt{Column: column, Value: after})
+				}
+			}
+			var chunk []T
+			tx = tx.Order(clause.OrderByColumn{Column: column, Desc: defaultOpts.Desc}).Limit(defaultOpts.Size).Find(&chunk)
+			if tx.Error != nil {
+				yield(nil, Classify(tx.Error))
+				return
+			}
+			if len(chunk) == 0 {
+				return
+			}
+			key, err := keyOf(tx.Statement, chunk[len(chunk)-1], defaultOpts.Column)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(chunk, nil) || len(chunk) < defaultOpts.Size {
+				return
+			}
+			after = key
+		}
+	}
+}
+
+// withModel 查询没有指定表并且T是结构体时使用T对应的表
+func withModel[T any](tx *gorm.DB) *gorm.DB {
+	if tx.Statement.Model != nil || tx.Statement.Table != "" || tx.Statement.SQL.Len() != 0 {
+		return tx
+	}
+	if reflect.TypeFor[T]().Kind() == reflect.Map {
+		return tx
+	}
+	var model T
+	return tx.Model(&model)
+}
+
+// keyOf 取出一行中分页列的值，作为下一页的起点
+func keyOf(stmt *gorm.Statement, row any, column string) (any, error) {
+	// 带表名的列user.id只取列名
+	if index := strings.LastIndexByte(column, '.'); index >= 0 {
+		column = column[index+1:]
+	}
+	if value, ok := row.(map[string]any); ok {
+		key, ok := value[column]
+		if !ok || key == nil {
+			return nil, fmt.Errorf("chunk column[%s] not found in result", column)
+		}
+		return key, nil
+	}
+	if stmt.Schema == nil {
+		return nil, fmt.Errorf("chunk column[%s] not found in %T", column, row)
+	}
+	field := stmt.Schema.LookUpField(column)
+	if field == nil {
+		return nil, fmt.Errorf("chunk column[%s] not found in %T", column, row)
+	}
+	key, _ := field.ValueOf(stmt.Context, reflect.ValueOf(row))
+	return key, nil
+}
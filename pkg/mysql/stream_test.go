@@ -0,0 +1,207 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeUser struct {
+	ID   int64  `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name"`
+}
+
+func (fakeUser) TableName() string {
+	return "fake_user"
+}
+
+func newFakeUsers(t *testing.T, users int64) *gorm.DB {
+	t.Helper()
+	dsn := t.Name() + "/primary"
+	getFakeServer(dsn).users.Store(users)
+	db, err := New(context.Background(), dsn, log.DefaultLogger, WithDriverName("fake_mysql"), WithLogLevel(logger.Silent))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = Close(db) })
+	return db
+}
+
+func TestStream(t *testing.T) {
+	db := newFakeUsers(t, 5)
+	ctx := context.Background()
+
+	var ids []int64
+	for user, err := range Stream[fakeUser](ctx, db) {
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		if user.Name != "user"+string(rune('0'+user.ID)) {
+			t.Errorf("user = %+v", user)
+		}
+		ids = append(ids, user.ID)
+	}
+	if !slices.Equal(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("Stream[fakeUser] ids = %v", ids)
+	}
+
+	ids = nil
+	for user, err := range Stream[*fakeUser](ctx, db.Where("status = ?", 1)) {
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		ids = append(ids, user.ID)
+		if len(ids) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(ids, []int64{1, 2}) {
+		t.Errorf("Stream[*fakeUser] with break ids = %v", ids)
+	}
+
+	ids = nil
+	for row, err := range Stream[map[string]any](ctx, db.Raw("SELECT id, name FROM `fake_user`")) {
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		ids = append(ids, row["id"].(int64))
+	}
+	if !slices.Equal(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("Stream[map[string]any] ids = %v", ids)
+	}
+
+	// 遍历过程中取消，返回ctx的错误后结束
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var errs []error
+	for user, err := range Stream[fakeUser](cancelCtx, db) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if user.ID == 2 {
+			cancel()
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("Stream canceled errs = %v", errs)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	db := newFakeUsers(t, 10)
+	tests := []struct {
+		name   string
+		opts   []ChunkOption
+		chunks [][]int64
+	}{
+		{
+			name:   "default",
+			chunks: [][]int64{{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		},
+		{
+			name:   "size",
+			opts:   []ChunkOption{WithChunkSize(4)},
+			chunks: [][]int64{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10}},
+		},
+		{
+			name:   "size divides rows",
+			opts:   []ChunkOption{WithChunkSize(5)},
+			chunks: [][]int64{{1, 2, 3, 4, 5}, {6, 7, 8, 9, 10}},
+		},
+		{
+			name:   "after",
+			opts:   []ChunkOption{WithChunkSize(3), WithChunkAfter(int64(4))},
+			chunks: [][]int64{{5, 6, 7}, {8, 9, 10}},
+		},
+		{
+			name:   "desc",
+			opts:   []ChunkOption{WithChunkColumn("fake_user.id"), WithChunkSize(4), WithChunkDesc(true)},
+			chunks: [][]int64{{10, 9, 8, 7}, {6, 5, 4, 3}, {2, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int64
+			for chunk, err := range Chunks[fakeUser](context.Background(), db, tt.opts...) {
+				if err != nil {
+					t.Fatalf("Chunks: %v", err)
+				}
+				var ids []int64
+				for _, user := range chunk {
+					ids = append(ids, user.ID)
+				}
+				got = append(got, ids)
+			}
+			if !slices.EqualFunc(got, tt.chunks, slices.Equal) {
+				t.Errorf("Chunks() = %v, want %v", got, tt.chunks)
+			}
+
+			// map[string]any按同样的方式分页
+			got = nil
+			for chunk, err := range Chunks[map[string]any](context.Background(), db.Table("fake_user"), tt.opts...) {
+				if err != nil {
+					t.Fatalf("Chunks: %v", err)
+				}
+				var ids []int64
+				for _, row := range chunk {
+					ids = append(ids, row["id"].(int64))
+				}
+				got = append(got, ids)
+			}
+			if !slices.EqualFunc(got, tt.chunks, slices.Equal) {
+				t.Errorf("Chunks[map[string]any]() = %v, want %v", got, tt.chunks)
+			}
+		})
+	}
+}
+
+func TestChunksError(t *testing.T) {
+	db := newFakeUsers(t, 10)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []ChunkOption
+	}{
+		{
+			name: "invalid size",
+			ctx:  context.Background(),
+			opts: []ChunkOption{WithChunkSize(0)},
+		},
+		{
+			name: "column not found",
+			ctx:  context.Background(),
+			opts: []ChunkOption{WithChunkColumn("uid"), WithChunkSize(3)},
+		},
+		{
+			name: "canceled",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []error
+			for chunk, err := range Chunks[fakeUser](tt.ctx, db, tt.opts...) {
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if len(chunk) == 0 {
+					t.Errorf("Chunks() yield empty chunk")
+				}
+			}
+			if len(errs) != 1 {
+				t.Errorf("Chunks() errs = %v, want 1 error", errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/soulnov23/go-tool/pkg/tracing"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// registerTracing 在gorm的各类操作前后注册回调，为每条SQL创建client span
+func registerTracing(db *gorm.DB) error {
+	callback := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callback.Create().Before("gorm:create").Register, callback.Create().After("gorm:create").Register},
+		{"query", callback.Query().Before("gorm:query").Register, callback.Query().After("gorm:query").Register},
+		{"update", callback.Update().Before("gorm:update").Register, callback.Update().After("gorm:update").Register},
+		{"delete", callback.Delete().Before("gorm:delete").Register, callback.Delete().After("gorm:delete").Register},
+		{"row", callback.Row().Before("gorm:row").Register, callback.Row().After("gorm:row").Register},
+		{"raw", callback.Raw().Before("gorm:raw").Register, callback.Raw().After("gorm:raw").Register},
+	}
+	for _, hook := range hooks {
+		if err := hook.before("tracing:before_"+hook.name, beforeTracing("gorm."+hook.name)); err != nil {
+			return fmt.Errorf("register tracing:before_%s: %v", hook.name, err)
+		}
+		if err := hook.after("tracing:after_"+hook.name, afterTracing); err != nil {
+			return fmt.Errorf("register tracing:after_%s: %v", hook.name, err)
+		}
+	}
+	return nil
+}
+
+func beforeTracing(name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		ctx, span := tracing.DefaultTracer.Start(db.Statement.Context, name, tracing.SpanKindClient)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func afterTracing(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(*tracing.Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.system", "mysql")
+	span.SetAttribute("db.statement", db.Statement.SQL.String())
+	span.SetAttribute("db.sql.table", db.Statement.Table)
+	span.SetAttribute("db.rows_affected", db.Statement.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
@@ -0,0 +1,116 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"math/rand/v2"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TxOptions struct {
+	Isolation      sql.IsolationLevel // 隔离级别，默认使用数据库的配置
+	ReadOnly       bool               // 只读事务
+	MaxAttempts    int                // 包含第一次执行的总次数，死锁和锁等待超时时重试
+	InitialBackoff time.Duration      // 第一次重试前的等待时间
+	MaxBackoff     time.Duration      // 重试等待时间的上限
+}
+
+type TxOption func(*TxOptions)
+
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *TxOptions) {
+		o.Isolation = level
+	}
+}
+
+func WithReadOnly(b bool) TxOption {
+	return func(o *TxOptions) {
+		o.ReadOnly = b
+	}
+}
+
+func WithMaxAttempts(n int) TxOption {
+	return func(o *TxOptions) {
+		o.MaxAttempts = n
+	}
+}
+
+func WithBackoff(initial time.Duration, max time.Duration) TxOption {
+	return func(o *TxOptions) {
+		o.InitialBackoff = initial
+		o.MaxBackoff = max
+	}
+}
+
+// ErrNestedTxOptions 嵌套的WithTx使用外层事务，传入的选项不会生效
+var ErrNestedTxOptions = stderrors.New("mysql: nested WithTx does not support TxOption")
+
+type txKey struct{}
+
+// NewTxContext 把事务放到ctx中，WithTx调用fn时已经设置
+func NewTxContext(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext 返回ctx中的事务
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// FromContext ctx中有事务时返回事务，否则返回db，repository代码不需要关心是否在事务中
+func FromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// WithTx 在事务中执行fn，fn返回错误或者panic时回滚，否则提交
+//
+// ctx中已经有事务时使用savepoint嵌套执行，只回滚到savepoint，重试由最外层的WithTx负责，
+// 隔离级别等选项只能在最外层设置，嵌套时传入opts返回ErrNestedTxOptions。
+// 死锁和锁等待超时时按指数退避重试整个事务，fn需要是可以重复执行的。
+// 返回的错误经过Classify转换
+func WithTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error, opts ...TxOption) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		if len(opts) > 0 {
+			return ErrNestedTxOptions
+		}
+		return Classify(tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(NewTxContext(ctx, tx), tx)
+		}))
+	}
+	defaultOpts := &TxOptions{
+		Isolation:      sql.LevelDefault,
+		ReadOnly:       false,
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+	for _, opt := range opts {
+		opt(defaultOpts)
+	}
+	txOpts := &sql.TxOptions{Isolation: defaultOpts.Isolation, ReadOnly: defaultOpts.ReadOnly}
+	backoff := defaultOpts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(NewTxContext(ctx, tx), tx)
+		}, txOpts)
+		if err == nil || attempt >= defaultOpts.MaxAttempts || !IsRetryable(err) {
+			return Classify(err)
+		}
+		// 等待[backoff/2, backoff)，避免冲突的事务同时重试再次冲突
+		timer := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Classify(err)
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, defaultOpts.MaxBackoff)
+	}
+}
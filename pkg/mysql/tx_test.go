@@ -0,0 +1,142 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/errors"
+	"github.com/soulnov23/go-tool/pkg/log"
+	"gorm.io/gorm"
+)
+
+func TestWithTx(t *testing.T) {
+	errBusiness := stderrors.New("balance not enough")
+	update := func(query string) func(ctx context.Context, tx *gorm.DB) error {
+		return func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec(query).Error
+		}
+	}
+	tests := []struct {
+		name      string
+		deadlocks int32
+		opts      []TxOption
+		fn        func(ctx context.Context, tx *gorm.DB) error
+		want      []string
+		wantErr   error
+	}{
+		{
+			name: "commit",
+			fn:   update("UPDATE account SET balance = 1"),
+			want: []string{"BEGIN", "UPDATE account SET balance = 1", "COMMIT"},
+		},
+		{
+			name: "rollback",
+			fn: func(ctx context.Context, tx *gorm.DB) error {
+				if err := tx.Exec("UPDATE account SET balance = 1").Error; err != nil {
+					return err
+				}
+				return errBusiness
+			},
+			want:    []string{"BEGIN", "UPDATE account SET balance = 1", "ROLLBACK"},
+			wantErr: errBusiness,
+		},
+		{
+			name: "isolation read only",
+			opts: []TxOption{WithIsolation(sql.LevelRepeatableRead), WithReadOnly(true)},
+			fn:   update("SELECT 1"),
+			want: []string{"BEGIN Repeatable Read READ ONLY", "SELECT 1", "COMMIT"},
+		},
+		{
+			name:      "retry deadlock",
+			deadlocks: 2,
+			opts:      []TxOption{WithBackoff(time.Millisecond, time.Millisecond)},
+			fn:        update("UPDATE account SET balance = 1 -- deadlock"),
+			want: []string{
+				"BEGIN", "UPDATE account SET balance = 1 -- deadlock", "ROLLBACK",
+				"BEGIN", "UPDATE account SET balance = 1 -- deadlock", "ROLLBACK",
+				"BEGIN", "UPDATE account SET balance = 1 -- deadlock", "COMMIT",
+			},
+		},
+		{
+			name:      "retry exhausted",
+			deadlocks: 5,
+			opts:      []TxOption{WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond)},
+			fn:        update("UPDATE account SET balance = 1 -- deadlock"),
+			want: []string{
+				"BEGIN", "UPDATE account SET balance = 1 -- deadlock", "ROLLBACK",
+				"BEGIN", "UPDATE account SET balance = 1 -- deadlock", "ROLLBACK",
+			},
+			wantErr: ErrTxConflict,
+		},
+	}
+	db, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger, WithDriverName("fake_mysql"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(db)
+	server := getFakeServer(t.Name() + "/primary")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.take()
+			server.deadlocks.Store(tt.deadlocks)
+			err := WithTx(context.Background(), db, tt.fn, tt.opts...)
+			if !errors.Equal(err, tt.wantErr) && !stderrors.Is(err, tt.wantErr) {
+				t.Errorf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := server.take(); !slices.Equal(got, tt.want) {
+				t.Errorf("queries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTxNested(t *testing.T) {
+	db, err := New(context.Background(), t.Name()+"/primary", log.DefaultLogger, WithDriverName("fake_mysql"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(db)
+	server := getFakeServer(t.Name() + "/primary")
+	server.take()
+
+	errInner := stderrors.New("inner failed")
+	err = WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+		if got, ok := TxFromContext(ctx); !ok || got != tx {
+			t.Errorf("TxFromContext() = %v, %v, want tx", got, ok)
+		}
+		if err := FromContext(ctx, db).Exec("UPDATE account SET balance = 1").Error; err != nil {
+			return err
+		}
+		if err := WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE account SET balance = 2").Error; err != nil {
+				return err
+			}
+			return errInner
+		}); !stderrors.Is(err, errInner) {
+			t.Errorf("nested WithTx() error = %v, want %v", err, errInner)
+		}
+		if err := WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+			t.Errorf("nested WithTx() with options ran fn")
+			return nil
+		}, WithReadOnly(true)); err != ErrNestedTxOptions {
+			t.Errorf("nested WithTx() with options error = %v, want %v", err, ErrNestedTxOptions)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	got := server.take()
+	if len(got) != 6 || got[0] != "BEGIN" || !strings.HasPrefix(got[2], "SAVEPOINT sp") ||
+		got[4] != strings.Replace(got[2], "SAVEPOINT", "ROLLBACK TO SAVEPOINT", 1) || got[5] != "COMMIT" {
+		t.Errorf("queries = %q, want savepoint rolled back and outer committed", got)
+	}
+	if _, ok := TxFromContext(context.Background()); ok {
+		t.Errorf("TxFromContext(background) ok = true, want false")
+	}
+}
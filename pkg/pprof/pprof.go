@@ -15,6 +15,7 @@ const (
 // "Profile"是指性能分析数据，"Profiler"是指生成和处理这些数据的工具
 type ProfileProfiler struct {
 	opts *Options
+	mux  *http.ServeMux
 }
 
 func New(opts ...Option) *ProfileProfiler {
@@ -25,6 +26,7 @@ func New(opts ...Option) *ProfileProfiler {
 			WriteTimeout: defaultWriteTimeout,
 			IdleTimeout:  defaultIdleTimeout,
 		},
+		mux: http.NewServeMux(),
 	}
 	for _, opt := range opts {
 		opt(pprof.opts)
@@ -32,12 +34,17 @@ func New(opts ...Option) *ProfileProfiler {
 	return pprof
 }
 
+// HandleFunc 在同一个端口上注册/debug/pprof/之外的处理函数，需要在Serve之前调用
+func (pp *ProfileProfiler) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	pp.mux.HandleFunc(pattern, handler)
+}
+
 // 创建mux自定义处理函数，避免与pprof的默认http.DefaultServeMux冲突
 // mux := http.NewServeMux()
 // mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
 // http.ListenAndServe("ip:port", mux)
 func (pp *ProfileProfiler) Serve() error {
-	mux := http.NewServeMux()
+	mux := pp.mux
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
+)
+
+type Exporter interface {
+	Export(ctx context.Context, spans []*SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+type noopExporter struct{}
+
+func (noopExporter) Export(ctx context.Context, spans []*SpanData) error {
+	return nil
+}
+
+func (noopExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// StdoutExporter 每个span输出一行JSON
+type StdoutExporter struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+func NewStdoutExporter(writer io.Writer) *StdoutExporter {
+	return &StdoutExporter{
+		writer: writer,
+	}
+}
+
+func (e *StdoutExporter) Export(ctx context.Context, spans []*SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, span := range spans {
+		buf, err := jsoniter.Marshal(span)
+		if err != nil {
+			return fmt.Errorf("jsoniter.Marshal span[%s]: %v", span.Name, err)
+		}
+		buf = append(buf, '\n')
+		if _, err := e.writer.Write(buf); err != nil {
+			return fmt.Errorf("write span[%s]: %v", span.Name, err)
+		}
+	}
+	return nil
+}
+
+func (e *StdoutExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,297 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
+)
+
+const (
+	otlpTracesPath           = "/v1/traces"
+	otlpScopeName            = "github.com/soulnov23/go-tool/pkg/tracing"
+	defaultOTLPTimeout       = 3 * time.Second
+	defaultOTLPBatchSize     = 512
+	defaultOTLPFlushInterval = 5 * time.Second
+)
+
+type OTLPOptions struct {
+	Timeout       time.Duration
+	BatchSize     int
+	FlushInterval time.Duration
+	Headers       map[string]string
+	Errorf        func(formatter string, args ...any)
+}
+
+type OTLPOption func(*OTLPOptions)
+
+func WithOTLPTimeout(timeout time.Duration) OTLPOption {
+	return func(o *OTLPOptions) {
+		o.Timeout = timeout
+	}
+}
+
+func WithOTLPBatchSize(batchSize int) OTLPOption {
+	return func(o *OTLPOptions) {
+		o.BatchSize = batchSize
+	}
+}
+
+func WithOTLPFlushInterval(interval time.Duration) OTLPOption {
+	return func(o *OTLPOptions) {
+		o.FlushInterval = interval
+	}
+}
+
+func WithOTLPHeaders(headers map[string]string) OTLPOption {
+	return func(o *OTLPOptions) {
+		o.Headers = headers
+	}
+}
+
+func WithOTLPErrorf(errorf func(formatter string, args ...any)) OTLPOption {
+	return func(o *OTLPOptions) {
+		o.Errorf = errorf
+	}
+}
+
+// OTLPExporter 使用OTLP/HTTP JSON编码上报span，攒够BatchSize或者每隔FlushInterval发送一次
+type OTLPExporter struct {
+	url    string
+	client *http.Client
+	opts   *OTLPOptions
+
+	mutex   sync.Mutex
+	pending []*SpanData
+
+	flush chan struct{}
+	close chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewOTLPExporter endpoint形如http://127.0.0.1:4318，自动追加/v1/traces
+func NewOTLPExporter(endpoint string, opts ...OTLPOption) *OTLPExporter {
+	e := &OTLPExporter{
+		url: strings.TrimSuffix(endpoint, "/") + otlpTracesPath,
+		opts: &OTLPOptions{
+			Timeout:       defaultOTLPTimeout,
+			BatchSize:     defaultOTLPBatchSize,
+			FlushInterval: defaultOTLPFlushInterval,
+		},
+		flush: make(chan struct{}, 1),
+		close: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e.opts)
+	}
+	e.client = &http.Client{Timeout: e.opts.Timeout}
+	go e.loop()
+	return e
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []*SpanData) error {
+	e.mutex.Lock()
+	e.pending = append(e.pending, spans...)
+	full := len(e.pending) >= e.opts.BatchSize
+	e.mutex.Unlock()
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush 立即发送所有待上报的span
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	e.mutex.Lock()
+	spans := e.pending
+	e.pending = nil
+	e.mutex.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := jsoniter.Marshal(newOTLPRequest(spans))
+	if err != nil {
+		return fmt.Errorf("jsoniter.Marshal: %v", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequest: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range e.opts.Headers {
+		request.Header.Set(key, value)
+	}
+	response, err := e.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("post %s: %v", e.url, err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("post %s: status code[%d]", e.url, response.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() {
+		close(e.close)
+	})
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.Flush(ctx)
+}
+
+func (e *OTLPExporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.close:
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+		if err := e.Flush(context.Background()); err != nil && e.opts.Errorf != nil {
+			e.opts.Errorf("otlp exporter flush: %v", err)
+		}
+	}
+}
+
+// 以下结构对应opentelemetry-proto的JSON映射，trace_id和span_id使用十六进制字符串，int64使用字符串
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   *otlpResource     `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope *otlpScope  `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []*otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string        `json:"key"`
+	Value *otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+const otlpStatusCodeError = 2
+
+func newOTLPRequest(spans []*SpanData) *otlpRequest {
+	request := &otlpRequest{}
+	resources := map[string]*otlpScopeSpans{}
+	for _, span := range spans {
+		scopeSpans, ok := resources[span.ServiceName]
+		if !ok {
+			scopeSpans = &otlpScopeSpans{Scope: &otlpScope{Name: otlpScopeName}}
+			resources[span.ServiceName] = scopeSpans
+			request.ResourceSpans = append(request.ResourceSpans, &otlpResourceSpans{
+				Resource: &otlpResource{
+					Attributes: []*otlpKeyValue{newOTLPKeyValue("service.name", span.ServiceName)},
+				},
+				ScopeSpans: []*otlpScopeSpans{scopeSpans},
+			})
+		}
+		otlp := &otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			TraceState:        span.TraceState,
+			Name:              span.Name,
+			Kind:              int(span.kind),
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		}
+		for key, value := range span.Attributes {
+			otlp.Attributes = append(otlp.Attributes, newOTLPKeyValue(key, value))
+		}
+		if span.Error != "" {
+			otlp.Status = &otlpStatus{Code: otlpStatusCodeError, Message: span.Error}
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, otlp)
+	}
+	return request
+}
+
+func newOTLPKeyValue(key string, value any) *otlpKeyValue {
+	anyValue := &otlpAnyValue{}
+	switch v := value.(type) {
+	case string:
+		anyValue.StringValue = &v
+	case bool:
+		anyValue.BoolValue = &v
+	case int:
+		s := strconv.FormatInt(int64(v), 10)
+		anyValue.IntValue = &s
+	case int32:
+		s := strconv.FormatInt(int64(v), 10)
+		anyValue.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		anyValue.IntValue = &s
+	case uint32:
+		s := strconv.FormatUint(uint64(v), 10)
+		anyValue.IntValue = &s
+	case float32:
+		f := float64(v)
+		anyValue.DoubleValue = &f
+	case float64:
+		anyValue.DoubleValue = &v
+	default:
+		s := fmt.Sprint(v)
+		anyValue.StringValue = &s
+	}
+	return &otlpKeyValue{Key: key, Value: anyValue}
+}
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/soulnov23/go-tool/pkg/log"
+	"go.uber.org/zap"
+)
+
+// Fields 返回ctx中当前span的trace_id和span_id日志字段
+func Fields(ctx context.Context) []zap.Field {
+	sc := SpanFromContext(ctx).SpanContext()
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID.String()),
+		zap.String("span_id", sc.SpanID.String()),
+	}
+}
+
+// Logger 返回带有trace_id和span_id字段的logger，ctx中没有span时原样返回
+func Logger(ctx context.Context, logger log.Logger) log.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
@@ -0,0 +1,29 @@
+package tracing
+
+const defaultServiceName = "go_tool"
+
+type Options struct {
+	ServiceName string
+	Exporter    Exporter
+	Errorf      func(formatter string, args ...any)
+}
+
+type Option func(*Options)
+
+func WithServiceName(serviceName string) Option {
+	return func(o *Options) {
+		o.ServiceName = serviceName
+	}
+}
+
+func WithExporter(exporter Exporter) Option {
+	return func(o *Options) {
+		o.Exporter = exporter
+	}
+}
+
+func WithErrorf(errorf func(formatter string, args ...any)) Option {
+	return func(o *Options) {
+		o.Errorf = errorf
+	}
+}
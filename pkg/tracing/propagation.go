@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// https://www.w3.org/TR/trace-context/
+const (
+	TraceparentKey = "traceparent"
+	TracestateKey  = "tracestate"
+
+	traceparentVersion = "00"
+	traceparentLength  = 55 // 00-{32}-{16}-{2}
+)
+
+// ParseTraceparent 解析version-trace_id-parent_id-trace_flags
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	sc := SpanContext{}
+	traceparent = strings.TrimSpace(traceparent)
+	if len(traceparent) < traceparentLength {
+		return sc, fmt.Errorf("traceparent[%s] invalid length", traceparent)
+	}
+	version := traceparent[:2]
+	if _, err := hex.DecodeString(version); err != nil || version == "ff" {
+		return sc, fmt.Errorf("traceparent[%s] invalid version", traceparent)
+	}
+	// 当前版本长度必须严格相等，未来版本允许追加字段
+	if version == traceparentVersion && len(traceparent) != traceparentLength {
+		return sc, fmt.Errorf("traceparent[%s] invalid length", traceparent)
+	}
+	if version != traceparentVersion && len(traceparent) > traceparentLength && traceparent[traceparentLength] != '-' {
+		return sc, fmt.Errorf("traceparent[%s] invalid format", traceparent)
+	}
+	if traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return sc, fmt.Errorf("traceparent[%s] invalid format", traceparent)
+	}
+	if !isLowerHex(traceparent[3:35]) || !isLowerHex(traceparent[36:52]) || !isLowerHex(traceparent[53:55]) {
+		return sc, fmt.Errorf("traceparent[%s] invalid hex", traceparent)
+	}
+	_, _ = hex.Decode(sc.TraceID[:], []byte(traceparent[3:35]))
+	_, _ = hex.Decode(sc.SpanID[:], []byte(traceparent[36:52]))
+	flags := make([]byte, 1)
+	_, _ = hex.Decode(flags, []byte(traceparent[53:55]))
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("traceparent[%s] all zero trace_id or parent_id", traceparent)
+	}
+	return sc, nil
+}
+
+func FormatTraceparent(sc SpanContext) string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract 从请求元数据中解析上游的SpanContext，md可以直接传入framework的metadata.MD
+func Extract(md map[string]string) (SpanContext, bool) {
+	if md == nil {
+		return SpanContext{}, false
+	}
+	sc, err := ParseTraceparent(md[TraceparentKey])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = md[TracestateKey]
+	sc.Remote = true
+	return sc, true
+}
+
+// Inject 把ctx中当前span写入请求元数据，透传给下游
+func Inject(ctx context.Context, md map[string]string) {
+	if md == nil {
+		return
+	}
+	sc := SpanFromContext(ctx).SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+	md[TraceparentKey] = FormatTraceparent(sc)
+	if sc.TraceState != "" {
+		md[TracestateKey] = sc.TraceState
+	}
+}
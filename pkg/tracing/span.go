@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"maps"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+// 取值与OTLP协议中的SpanKind保持一致
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+const flagsSampled = 0x01
+
+// SpanContext 跨服务传递的span标识，对应W3C traceparent和tracestate
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&flagsSampled != 0
+}
+
+// SpanData 是span结束后交给Exporter的快照
+type SpanData struct {
+	ServiceName  string         `json:"service_name"`
+	Name         string         `json:"name"`
+	Kind         string         `json:"kind"`
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	TraceState   string         `json:"trace_state,omitempty"`
+	StartTime    time.Time      `json:"start_time"`
+	EndTime      time.Time      `json:"end_time"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Error        string         `json:"error,omitempty"`
+
+	kind SpanKind
+}
+
+type Span struct {
+	tracer       *Tracer
+	name         string
+	kind         SpanKind
+	spanContext  SpanContext
+	parentSpanID SpanID
+	startTime    time.Time
+
+	mutex      sync.Mutex
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+// 以下方法都允许nil接收者，调用方不需要判断span是否存在
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanContext
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]any)
+	}
+	s.attributes[key] = value
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.err = err
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	data := &SpanData{
+		ServiceName: s.tracer.opts.ServiceName,
+		Name:        s.name,
+		Kind:        s.kind.String(),
+		TraceID:     s.spanContext.TraceID.String(),
+		SpanID:      s.spanContext.SpanID.String(),
+		TraceState:  s.spanContext.TraceState,
+		StartTime:   s.startTime,
+		EndTime:     time.Now(),
+		Attributes:  maps.Clone(s.attributes),
+		kind:        s.kind,
+	}
+	if s.parentSpanID.IsValid() {
+		data.ParentSpanID = s.parentSpanID.String()
+	}
+	if s.err != nil {
+		data.Error = s.err.Error()
+	}
+	s.mutex.Unlock()
+
+	// 未采样的span只用于透传，不上报
+	if !s.spanContext.Sampled() {
+		return
+	}
+	s.tracer.export(data)
+}
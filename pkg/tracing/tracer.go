@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultTracer 未配置Exporter时只生成和透传trace id，不上报span
+var DefaultTracer = New()
+
+type spanKey struct{}
+
+type Tracer struct {
+	opts *Options
+}
+
+func New(opts ...Option) *Tracer {
+	tracer := &Tracer{
+		opts: &Options{
+			ServiceName: defaultServiceName,
+			Exporter:    noopExporter{},
+		},
+	}
+	for _, opt := range opts {
+		opt(tracer.opts)
+	}
+	return tracer
+}
+
+// Start 创建子span，ctx中没有span时创建新的trace
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return t.start(ctx, name, kind, SpanFromContext(ctx).SpanContext())
+}
+
+// StartServerSpan 服务端处理请求时调用，从请求元数据中提取上游的traceparent/tracestate
+func (t *Tracer) StartServerSpan(ctx context.Context, name string, md map[string]string) (context.Context, *Span) {
+	parent, ok := Extract(md)
+	if !ok {
+		parent = SpanFromContext(ctx).SpanContext()
+	}
+	return t.start(ctx, name, SpanKindServer, parent)
+}
+
+// StartClientSpan 客户端发起调用时调用，把新span的traceparent/tracestate写入请求元数据
+func (t *Tracer) StartClientSpan(ctx context.Context, name string, md map[string]string) (context.Context, *Span) {
+	ctx, span := t.Start(ctx, name, SpanKindClient)
+	Inject(ctx, md)
+	return ctx, span
+}
+
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.opts.Exporter.Shutdown(ctx)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind SpanKind, parent SpanContext) (context.Context, *Span) {
+	span := &Span{
+		tracer:    t,
+		name:      name,
+		kind:      kind,
+		startTime: time.Now(),
+	}
+	if parent.IsValid() {
+		span.spanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		}
+		span.parentSpanID = parent.SpanID
+	} else {
+		span.spanContext = SpanContext{
+			TraceID: newTraceID(),
+			Flags:   flagsSampled,
+		}
+	}
+	span.spanContext.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) export(data *SpanData) {
+	if err := t.opts.Exporter.Export(context.Background(), []*SpanData{data}); err != nil && t.opts.Errorf != nil {
+		t.opts.Errorf("tracing export span[%s]: %v", data.Name, err)
+	}
+}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, ok := ctx.Value(spanKey{}).(*Span)
+	if !ok {
+		return nil
+	}
+	return span
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
@@ -0,0 +1,218 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantErr     bool
+	}{
+		{
+			name:        "valid",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantErr:     false,
+		},
+		{
+			name:        "future version with suffix",
+			traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantErr:     false,
+		},
+		{
+			name:        "invalid version ff",
+			traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantErr:     true,
+		},
+		{
+			name:        "version 00 with suffix",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantErr:     true,
+		},
+		{
+			name:        "upper hex",
+			traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			wantErr:     true,
+		},
+		{
+			name:        "zero trace id",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			wantErr:     true,
+		},
+		{
+			name:        "zero span id",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			wantErr:     true,
+		},
+		{
+			name:        "too short",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.traceparent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceparent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := FormatTraceparent(sc); !strings.HasPrefix(tt.traceparent[3:], got[3:]) {
+				t.Errorf("FormatTraceparent() = %v, want %v", got, tt.traceparent)
+			}
+		})
+	}
+}
+
+type memoryExporter struct {
+	mutex sync.Mutex
+	spans []*SpanData
+}
+
+func (e *memoryExporter) Export(ctx context.Context, spans []*SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestServerClientSpan(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := New(WithServiceName("test"), WithExporter(exporter))
+
+	md := map[string]string{
+		TraceparentKey: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		TracestateKey:  "congo=t61rcWkgMzE",
+	}
+	ctx, server := tracer.StartServerSpan(context.Background(), "service/rpc", md)
+	if got := server.SpanContext().TraceID.String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("server trace id = %v", got)
+	}
+
+	downstream := map[string]string{}
+	_, client := tracer.StartClientSpan(ctx, "downstream/rpc", downstream)
+	sc, ok := Extract(downstream)
+	if !ok {
+		t.Fatalf("Extract() downstream metadata failed: %v", downstream)
+	}
+	if sc.TraceID != server.SpanContext().TraceID || sc.SpanID != client.SpanContext().SpanID {
+		t.Errorf("downstream traceparent = %v, want client span %v", downstream, FormatTraceparent(client.SpanContext()))
+	}
+	if downstream[TracestateKey] != "congo=t61rcWkgMzE" {
+		t.Errorf("downstream tracestate = %v", downstream[TracestateKey])
+	}
+	server.SetAttribute("rpc.method", "rpc")
+	client.End()
+	server.End()
+	server.End()
+	// End之后修改属性不能影响已经交给exporter的数据
+	server.SetAttribute("rpc.method", "changed")
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("exported spans = %d, want 2", len(exporter.spans))
+	}
+	if exporter.spans[0].ParentSpanID != server.SpanContext().SpanID.String() {
+		t.Errorf("client parent span id = %v, want %v", exporter.spans[0].ParentSpanID, server.SpanContext().SpanID)
+	}
+	if exporter.spans[1].ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("server parent span id = %v", exporter.spans[1].ParentSpanID)
+	}
+	if got := exporter.spans[1].Attributes["rpc.method"]; got != "rpc" {
+		t.Errorf("server attribute rpc.method = %v, want rpc", got)
+	}
+}
+
+func TestNotSampled(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := New(WithExporter(exporter))
+	md := map[string]string{TraceparentKey: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}
+	_, span := tracer.StartServerSpan(context.Background(), "service/rpc", md)
+	span.End()
+	if len(exporter.spans) != 0 {
+		t.Errorf("exported spans = %d, want 0", len(exporter.spans))
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	tracer := New(WithExporter(NewStdoutExporter(buffer)))
+	_, span := tracer.Start(context.Background(), "internal", SpanKindInternal)
+	span.SetAttribute("key", "value")
+	span.End()
+	data := &SpanData{}
+	if err := jsoniter.Unmarshal(buffer.Bytes(), data); err != nil {
+		t.Fatalf("jsoniter.Unmarshal: %v", err)
+	}
+	if data.Name != "internal" || data.Attributes["key"] != "value" {
+		t.Errorf("stdout span = %s", buffer.String())
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL, WithOTLPBatchSize(2), WithOTLPFlushInterval(time.Hour))
+	tracer := New(WithServiceName("test"), WithExporter(exporter))
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("rows", int64(1))
+	child.End()
+	parent.End()
+
+	select {
+	case body := <-bodies:
+		request := &otlpRequest{}
+		if err := jsoniter.Unmarshal(body, request); err != nil {
+			t.Fatalf("jsoniter.Unmarshal: %v", err)
+		}
+		spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+		if len(spans) != 2 {
+			t.Fatalf("otlp spans = %d, want 2", len(spans))
+		}
+		if spans[0].Name != "child" || spans[0].Kind != int(SpanKindClient) || spans[0].ParentSpanID != spans[1].SpanID {
+			t.Errorf("otlp child span = %s", body)
+		}
+		if *request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "test" {
+			t.Errorf("otlp resource = %s", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("otlp exporter not flushed")
+	}
+
+	_, span := tracer.Start(context.Background(), "shutdown", SpanKindInternal)
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case <-bodies:
+	case <-time.After(time.Second):
+		t.Fatal("otlp exporter not flushed on shutdown")
+	}
+}
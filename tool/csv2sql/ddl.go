@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type kind int
+
+const (
+	kindUnknown kind = iota // 只有空单元格
+	kindInt
+	kindDecimal
+	kindDouble
+	kindDate
+	kindDateTime
+	kindString
+)
+
+var (
+	decimalRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)\.([0-9]+)$`)
+	// varcharSizes 字符串按采样的最大长度向上取整，超过最大值时使用TEXT
+	varcharSizes = []int{16, 32, 64, 128, 255, 512, 1024, 2048, 4096}
+	dateLayouts  = []string{time.DateOnly, "2006/01/02"}
+	// dateTimeLayouts 只保留MySQL严格模式能直接插入的格式，RFC3339带T和时区，按字符串处理
+	dateTimeLayouts = []string{time.DateTime, "2006-01-02 15:04:05.999999", "2006/01/02 15:04:05"}
+)
+
+// columnStat 采样统计一列的值，用于推断字段类型
+type columnStat struct {
+	name         string
+	kind         kind
+	nullable     bool  // 有空单元格
+	maxLength    int   // 最大字符数
+	intDigits    int   // DECIMAL整数部分最大位数
+	scale        int   // DECIMAL小数部分最大位数
+	fsp          int   // DATETIME小数秒最大位数，MySQL最多6位
+	minInt       int64 // 整数的最小值
+	maxInt       int64 // 整数的最大值
+	values       map[string]struct{}
+	hasDuplicate bool
+}
+
+func newColumnStat(name string) *columnStat {
+	return &columnStat{name: name, minInt: math.MaxInt64, maxInt: math.MinInt64, values: map[string]struct{}{}}
+}
+
+// add emptyNull为false时空单元格插入空字符串，这一列只能是字符串类型
+func (c *columnStat) add(value string, emptyNull bool) {
+	if _, ok := c.values[value]; ok {
+		c.hasDuplicate = true
+	}
+	c.values[value] = struct{}{}
+	c.maxLength = max(c.maxLength, utf8.RuneCountInString(value))
+	if value == "" {
+		c.nullable = true
+		if !emptyNull {
+			c.kind = kindString
+		}
+		return
+	}
+	c.kind = merge(c.kind, c.kindOf(value))
+}
+
+func (c *columnStat) kindOf(value string) kind {
+	if numberRegexp.MatchString(value) {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			c.minInt, c.maxInt = min(c.minInt, i), max(c.maxInt, i)
+			c.intDigits = max(c.intDigits, len(strings.TrimPrefix(value, "-")))
+			return kindInt
+		}
+		if match := decimalRegexp.FindStringSubmatch(value); match != nil {
+			c.intDigits = max(c.intDigits, len(match[1]))
+			c.scale = max(c.scale, len(match[2]))
+			return kindDecimal
+		}
+		if !strings.ContainsAny(value, ".eE") {
+			// 超过BIGINT范围的整数
+			c.intDigits = max(c.intDigits, len(strings.TrimPrefix(value, "-")))
+			return kindDecimal
+		}
+		return kindDouble
+	}
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return kindDate
+		}
+	}
+	for _, layout := range dateTimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			if i := strings.LastIndexByte(value, '.'); i >= 0 {
+				c.fsp = max(c.fsp, min(len(value)-i-1, 6))
+			}
+			return kindDateTime
+		}
+	}
+	return kindString
+}
+
+// merge 数字之间和日期之间取范围更大的类型，其他组合都是字符串
+func merge(a kind, b kind) kind {
+	switch {
+	case a == kindUnknown:
+		return b
+	case b == kindUnknown:
+		return a
+	case a == kindString || b == kindString:
+		return kindString
+	case a <= kindDouble && b <= kindDouble, a >= kindDate && b >= kindDate:
+		return max(a, b)
+	default:
+		return kindString
+	}
+}
+
+func (c *columnStat) columnType() string {
+	switch c.kind {
+	case kindInt:
+		if c.minInt >= math.MinInt32 && c.maxInt <= math.MaxInt32 {
+			return "INT"
+		}
+		return "BIGINT"
+	case kindDecimal:
+		precision := c.intDigits + c.scale
+		if precision > 65 || c.scale > 30 {
+			return "DOUBLE"
+		}
+		return fmt.Sprintf("DECIMAL(%d,%d)", max(precision, 1), c.scale)
+	case kindDouble:
+		return "DOUBLE"
+	case kindDate:
+		return "DATE"
+	case kindDateTime:
+		// 不带精度的DATETIME会把小数秒四舍五入掉
+		if c.fsp > 0 {
+			return fmt.Sprintf("DATETIME(%d)", c.fsp)
+		}
+		return "DATETIME"
+	case kindUnknown:
+		return "VARCHAR(255)"
+	default:
+		for _, size := range varcharSizes {
+			if c.maxLength <= size {
+				return fmt.Sprintf("VARCHAR(%d)", size)
+			}
+		}
+		return "TEXT"
+	}
+}
+
+// primaryKey 建议的主键，优先使用名为id的列，其次是第一个以id结尾并且采样中唯一不为空的整数列
+func primaryKey(stats []*columnStat) *columnStat {
+	isKey := func(c *columnStat) bool {
+		return !c.nullable && !c.hasDuplicate && (c.kind == kindInt || c.kind == kindString && c.maxLength <= 255)
+	}
+	for _, c := range stats {
+		if strings.EqualFold(c.name, "id") && isKey(c) {
+			return c
+		}
+	}
+	for _, c := range stats {
+		if c.kind == kindInt && strings.HasSuffix(strings.ToLower(c.name), "id") && isKey(c) {
+			return c
+		}
+	}
+	return nil
+}
+
+// createTable 按采样的数据生成CREATE TABLE语句
+func createTable(table string, columns []column, records [][]string, emptyNull bool) string {
+	stats := make([]*columnStat, len(columns))
+	for i, column := range columns {
+		stats[i] = newColumnStat(column.name)
+		for _, record := range records {
+			stats[i].add(record[column.index], emptyNull)
+		}
+	}
+	key := primaryKey(stats)
+	builder := &strings.Builder{}
+	builder.WriteString("CREATE TABLE IF NOT EXISTS ")
+	writeTable(builder, table)
+	builder.WriteString(" (\n")
+	for i, c := range stats {
+		if i > 0 {
+			builder.WriteString(",\n")
+		}
+		builder.WriteString("  ")
+		writeIdentifier(builder, c.name)
+		builder.WriteByte(' ')
+		builder.WriteString(c.columnType())
+		// 主键列不能为NULL，只有空单元格的列不知道类型也允许为NULL
+		if c != key && (c.nullable && emptyNull || c.kind == kindUnknown) {
+			builder.WriteString(" NULL")
+		} else {
+			builder.WriteString(" NOT NULL")
+		}
+	}
+	if key != nil {
+		builder.WriteString(",\n  PRIMARY KEY (")
+		writeIdentifier(builder, key.name)
+		builder.WriteByte(')')
+	}
+	builder.WriteString("\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	return builder.String()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColumnStat(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []string
+		emptyNull bool
+		want      string
+		nullable  bool
+	}{
+		{name: "int", values: []string{"1", "-2", "30"}, want: "INT"},
+		{name: "bigint", values: []string{"1", "9223372036854775807"}, want: "BIGINT"},
+		{name: "decimal", values: []string{"1.5", "12.25", "3"}, want: "DECIMAL(4,2)"},
+		{name: "out of bigint", values: []string{"99999999999999999999"}, want: "DECIMAL(20,0)"},
+		{name: "double", values: []string{"1.5", "1e10"}, want: "DOUBLE"},
+		{name: "leading zero", values: []string{"0123", "0456"}, want: "VARCHAR(16)"},
+		{name: "date", values: []string{"2024-01-02", "2024/01/03"}, want: "DATE"},
+		{name: "datetime", values: []string{"2024-01-02", "2024-01-02 03:04:05", "2024-01-02 03:04:05.123456"}, want: "DATETIME(6)"},
+		{name: "datetime millisecond", values: []string{"2024-01-02 03:04:05.5", "2024-01-02 03:04:05.123"}, want: "DATETIME(3)"},
+		{name: "datetime second", values: []string{"2024-01-02", "2024/01/02 03:04:05"}, want: "DATETIME"},
+		{name: "rfc3339", values: []string{"2024-01-02T03:04:05Z"}, want: "VARCHAR(32)"},
+		{name: "mixed", values: []string{"1", "2024-01-02"}, want: "VARCHAR(16)"},
+		{name: "text", values: []string{strings.Repeat("a", 4097)}, want: "TEXT"},
+		{name: "empty null", values: []string{"1", ""}, emptyNull: true, want: "INT", nullable: true},
+		{name: "empty string", values: []string{"1", ""}, want: "VARCHAR(16)", nullable: true},
+		{name: "only empty", values: []string{"", ""}, emptyNull: true, want: "VARCHAR(255)", nullable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newColumnStat(tt.name)
+			for _, value := range tt.values {
+				c.add(value, tt.emptyNull)
+			}
+			if got := c.columnType(); got != tt.want {
+				t.Errorf("columnType() = %s, want %s", got, tt.want)
+			}
+			if c.nullable != tt.nullable {
+				t.Errorf("nullable = %v, want %v", c.nullable, tt.nullable)
+			}
+		})
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     string
+		columns   []column
+		records   [][]string
+		emptyNull bool
+		want      string
+	}{
+		{
+			name:    "primary key",
+			table:   "db.user",
+			columns: []column{{index: 0, name: "id"}, {index: 2, name: "name"}},
+			records: [][]string{{"1", "x", "alice"}, {"2", "y", "bob"}},
+			want: "CREATE TABLE IF NOT EXISTS `db`.`user` (\n" +
+				"  `id` INT NOT NULL,\n" +
+				"  `name` VARCHAR(16) NOT NULL,\n" +
+				"  PRIMARY KEY (`id`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		{
+			name:      "nullable without key",
+			table:     "user",
+			columns:   []column{{index: 0, name: "user_id"}, {index: 1, name: "a.b"}},
+			records:   [][]string{{"1", ""}, {"1", "2024-01-02 03:04:05"}},
+			emptyNull: true,
+			want: "CREATE TABLE IF NOT EXISTS `user` (\n" +
+				"  `user_id` INT NOT NULL,\n" +
+				"  `a.b` DATETIME NULL\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		{
+			name:    "key by suffix",
+			table:   "user",
+			columns: []column{{index: 0, name: "name"}, {index: 1, name: "UserID"}},
+			records: [][]string{{"alice", "10"}, {"bob", "20"}},
+			want: "CREATE TABLE IF NOT EXISTS `user` (\n" +
+				"  `name` VARCHAR(16) NOT NULL,\n" +
+				"  `UserID` INT NOT NULL,\n" +
+				"  PRIMARY KEY (`UserID`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createTable(tt.table, tt.columns, tt.records, tt.emptyNull); got != tt.want {
+				t.Errorf("createTable() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,25 +1,65 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"database/sql"
 	"encoding/csv"
+	"errors"
 	"flag"
-	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/soulnov23/go-tool/pkg/utils"
+	_ "github.com/go-sql-driver/mysql"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
 )
 
 // csv2sql -csv tmp.csv -sql tmp.sql -table database.table
+// csv2sql -csv tmp.csv -table database.table -mode upsert -update name,age -batch 500 -max-packet 16777216 -infer -empty-null
+// csv2sql -csv tmp.csv -sql tmp.sql -table database.table -ddl -sample 10000 -empty-null
+// csv2sql -csv tmp.csv -table database.table -delimiter '\t' -encoding gbk -dsn 'user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local'
 func main() {
 	// 定义需要解析的命令行参数
 	var csvPath string
 	var sqlPath string
 	var tableName string
+	var include string
+	var exclude string
+	var rename string
+	var emptyNull bool
+	var batch int
+	var maxPacket int
+	var mode string
+	var update string
+	var infer bool
+	var delimiter string
+	var encoding string
+	var dsn string
+	var txRows int
+	var ddl bool
+	var sample int
 	flag.StringVar(&csvPath, "csv", "tmp.csv", "csv file path")
 	flag.StringVar(&sqlPath, "sql", "tmp.sql", "sql file path")
 	flag.StringVar(&tableName, "table", "tmp", "table name")
+	flag.StringVar(&include, "include", "", "only import these columns, separated by comma")
+	flag.StringVar(&exclude, "exclude", "create_time,update_time,createtime,updatetime", "skip these columns, separated by comma")
+	flag.StringVar(&rename, "rename", "", "rename columns, old:new separated by comma")
+	flag.BoolVar(&emptyNull, "empty-null", false, "insert NULL for empty cells")
+	flag.IntVar(&batch, "batch", 100, "rows per INSERT statement")
+	flag.IntVar(&maxPacket, "max-packet", 0, "max bytes per INSERT statement, default 90% of max_allowed_packet in dsn mode, 90% of 4MB otherwise")
+	flag.StringVar(&mode, "mode", modeInsert, "insert|ignore|replace|upsert")
+	flag.StringVar(&update, "update", "", "columns to update in upsert mode, separated by comma, default all imported columns")
+	flag.BoolVar(&infer, "infer", false, "write numbers without quotes")
+	flag.StringVar(&delimiter, "delimiter", ",", "csv delimiter, \\t for tab")
+	flag.StringVar(&encoding, "encoding", "utf-8", "csv encoding, such as utf-8, gbk, gb18030, utf-16le")
+	flag.StringVar(&dsn, "dsn", "", "execute against mysql instead of writing sql file")
+	flag.IntVar(&txRows, "tx-rows", 10000, "rows per transaction in dsn mode")
+	flag.BoolVar(&ddl, "ddl", false, "infer column types from sampled rows and create table before inserts")
+	flag.IntVar(&sample, "sample", 1000, "rows to sample for -ddl")
 	// 开始解析命令行
 	flag.Parse()
 	// 命令行参数都不匹配，打印help
@@ -31,46 +71,316 @@ func main() {
 	log.SetFlags(0)
 	log.SetPrefix("\033[1;32m[csv2sql]\033[m ")
 
+	if batch <= 0 || txRows <= 0 || sample <= 0 || maxPacket < 0 {
+		log.Fatalf("❌ batch、tx-rows和sample必须大于0，max-packet不能小于0")
+	}
+	comma, err := parseDelimiter(delimiter)
+	if err != nil {
+		log.Fatalf("❌ 分隔符错误: %s", err.Error())
+	}
+	renames := map[string]string{}
+	for _, item := range splitList(rename) {
+		oldName, newName, ok := strings.Cut(item, ":")
+		if !ok || oldName == "" || newName == "" {
+			log.Fatalf("❌ 重命名格式错误: %s", item)
+		}
+		renames[oldName] = newName
+	}
+
 	csvFile, err := os.Open(csvPath)
 	if err != nil {
 		log.Fatalf("❌ 打开CSV文件失败: %s", err.Error())
 	}
 	defer csvFile.Close()
-	records, err := csv.NewReader(csvFile).ReadAll()
+	decoded, err := decode(csvFile, encoding)
 	if err != nil {
-		log.Fatalf("❌ 读取CSV文件失败: %s", err.Error())
+		log.Fatalf("❌ 编码错误: %s", err.Error())
 	}
-	if len(records) == 0 {
+	reader := csv.NewReader(decoded)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err == io.EOF {
 		log.Fatalf("❌ CSV文件为空: %s", csvPath)
 	}
-	size := len(records[0])
-	var indexs []int
-	var columns []string
-	for i, column := range records[0] {
-		tmp := strings.ToLower(column)
-		if strings.Contains(tmp, "create_time") ||
-			strings.Contains(tmp, "update_time") ||
-			strings.Contains(tmp, "createtime") ||
-			strings.Contains(tmp, "updatetime") {
-			continue
+	if err != nil {
+		log.Fatalf("❌ 读取CSV文件失败: %s", err.Error())
+	}
+	header = append([]string(nil), header...)
+	columns, err := selectColumns(header, splitList(include), splitList(exclude), renames)
+	if err != nil {
+		log.Fatalf("❌ 选择列失败: %s", err.Error())
+	}
+	b, err := newBuilder(strings.ToLower(mode), tableName, columns, splitList(update), emptyNull, infer)
+	if err != nil {
+		log.Fatalf("❌ 参数错误: %s", err.Error())
+	}
+
+	var out output
+	if dsn != "" {
+		dbOut, err := newDBOutput(dsn, txRows)
+		if err != nil {
+			log.Fatalf("❌ %s", err.Error())
+		}
+		if maxPacket == 0 {
+			if maxPacket, err = dbOut.maxPacket(); err != nil {
+				dbOut.abort()
+				log.Fatalf("❌ %s", err.Error())
+			}
+		}
+		out = dbOut
+	} else {
+		if out, err = newFileOutput(sqlPath); err != nil {
+			log.Fatalf("❌ %s", err.Error())
+		}
+		if maxPacket == 0 {
+			maxPacket = defaultMaxPacket * 9 / 10
 		}
-		indexs = append(indexs, i)
-		columns = append(columns, column)
 	}
-	var sqls []string
-	for line, record := range records[1:] {
-		if len(record) != size {
-			log.Fatalf("❌ 第%d行列数不匹配: %s", line+2, csvPath)
+	b.maxBytes = maxPacket
+	read := func() ([]string, bool) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil, false
 		}
-		var values []string
-		for _, index := range indexs {
-			values = append(values, fmt.Sprintf("'%s'", utils.MySQLRealEscapeString(record[index])))
+		if err != nil {
+			out.abort()
+			log.Fatalf("❌ 读取CSV文件失败: %s", err.Error())
 		}
-		sqls = append(sqls, fmt.Sprintf("insert into %s (%s) values (%s);", tableName, strings.Join(columns, ","), strings.Join(values, ",")))
+		if len(record) != len(header) {
+			line, _ := reader.FieldPos(0)
+			out.abort()
+			log.Fatalf("❌ 第%d行列数不匹配: %s", line, csvPath)
+		}
+		return record, true
 	}
-	sql := strings.Join(sqls, "\n")
-	if err := os.WriteFile(sqlPath, utils.StringToBytes(sql), 0o644); err != nil {
-		log.Fatalf("❌ 写入SQL文件失败: %s", err.Error())
+	// 先读取采样的行推断字段类型，建表后再插入这些行
+	var samples [][]string
+	if ddl {
+		for len(samples) < sample {
+			record, ok := read()
+			if !ok {
+				break
+			}
+			samples = append(samples, append([]string(nil), record...))
+		}
+		if err := out.exec(createTable(tableName, columns, samples, emptyNull)); err != nil {
+			out.abort()
+			log.Fatalf("❌ 建表失败: %s", err.Error())
+		}
+		log.Printf("✅ 按%d行采样生成建表语句", len(samples))
 	}
-	log.Printf("✅ 结果已保存到: %s", sqlPath)
+	rows := 0
+	for {
+		var record []string
+		if len(samples) > 0 {
+			record, samples = samples[0], samples[1:]
+		} else if next, ok := read(); ok {
+			record = next
+		} else {
+			break
+		}
+		row := b.row(record)
+		if !b.fits(row) {
+			flush(out, b, rows)
+		}
+		b.add(row)
+		rows++
+		if b.rows >= batch {
+			flush(out, b, rows)
+		}
+	}
+	flush(out, b, rows)
+	if err := out.close(); err != nil {
+		log.Fatalf("❌ 导入失败: %s", err.Error())
+	}
+	if dsn != "" {
+		log.Printf("✅ 共导入%d行到: %s", rows, tableName)
+		return
+	}
+	log.Printf("✅ 共%d行，结果已保存到: %s", rows, sqlPath)
+}
+
+// flush 输出builder中的行，失败时回滚未提交的数据后退出
+func flush(out output, b *builder, rows int) {
+	n := b.rows
+	if err := out.write(b.statement(), n); err != nil {
+		out.abort()
+		log.Fatalf("❌ 导入失败，已读取%d行数据: %s", rows, err.Error())
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseDelimiter(delimiter string) (rune, error) {
+	switch delimiter {
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if r == utf8.RuneError || size != len(delimiter) {
+		return 0, errors.New("delimiter must be a single character")
+	}
+	return r, nil
+}
+
+// decode 把CSV转换成UTF-8，UTF-8文件去掉开头的BOM
+func decode(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf8", "utf-8":
+		reader := bufio.NewReader(r)
+		if prefix, err := reader.Peek(3); err == nil && bytes.Equal(prefix, []byte("\xef\xbb\xbf")) {
+			_, _ = reader.Discard(3)
+		}
+		return reader, nil
+	}
+	enc, err := htmlindex.Get(encoding)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewReader(r, enc.NewDecoder()), nil
+}
+
+// defaultMaxPacket 写SQL文件时不知道目标库的max_allowed_packet，按MySQL 5.7的默认值4MB
+const defaultMaxPacket = 4 << 20
+
+// output 输出到SQL文件或者直接在数据库中执行
+type output interface {
+	exec(statement string) error // 执行建表之类不能放在事务中的语句
+	write(statement string, rows int) error
+	abort()
+	close() error
+}
+
+type fileOutput struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newFileOutput(path string) (*fileOutput, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.New("创建SQL文件失败: " + err.Error())
+	}
+	return &fileOutput{file: file, writer: bufio.NewWriterSize(file, 1<<20)}, nil
+}
+
+func (f *fileOutput) exec(statement string) error {
+	_, _ = f.writer.WriteString(statement)
+	_, err := f.writer.WriteString(";\n")
+	return err
+}
+
+func (f *fileOutput) write(statement string, rows int) error {
+	if statement == "" {
+		return nil
+	}
+	return f.exec(statement)
+}
+
+func (f *fileOutput) abort() {
+	_ = f.file.Close()
+}
+
+func (f *fileOutput) close() error {
+	if err := f.writer.Flush(); err != nil {
+		_ = f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+// dbOutput 每txRows行提交一次事务，失败时回滚当前事务，之前已经提交的事务不会回滚
+type dbOutput struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	txRows    int
+	rows      int // 当前事务中的行数
+	committed int // 已经提交的行数
+}
+
+func newDBOutput(dsn string, txRows int) (*dbOutput, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, errors.New("参数解析失败: " + err.Error())
+	}
+	// sql.Open 只验证参数格式，不建立真实连接，需要 Ping 确认连通性
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, errors.New("连接失败: " + err.Error())
+	}
+	log.Printf("✅ 连接成功")
+	return &dbOutput{db: db, txRows: txRows}, nil
+}
+
+// maxPacket 返回max_allowed_packet的90%，留出协议头等开销
+func (d *dbOutput) maxPacket() (int, error) {
+	var packet int64
+	if err := d.db.QueryRow("SELECT @@max_allowed_packet").Scan(&packet); err != nil {
+		return 0, errors.New("查询max_allowed_packet失败: " + err.Error())
+	}
+	return int(packet * 9 / 10), nil
+}
+
+func (d *dbOutput) exec(statement string) error {
+	_, err := d.db.Exec(statement)
+	return err
+}
+
+func (d *dbOutput) write(statement string, rows int) error {
+	if statement == "" {
+		return nil
+	}
+	if d.tx == nil {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return err
+		}
+		d.tx = tx
+	}
+	if _, err := d.tx.Exec(statement); err != nil {
+		return err
+	}
+	d.rows += rows
+	if d.rows >= d.txRows {
+		return d.commit()
+	}
+	return nil
+}
+
+func (d *dbOutput) commit() error {
+	if d.tx == nil {
+		return nil
+	}
+	err := d.tx.Commit()
+	d.tx = nil
+	if err != nil {
+		return err
+	}
+	d.committed += d.rows
+	d.rows = 0
+	log.Printf("📢 已提交%d行", d.committed)
+	return nil
+}
+
+func (d *dbOutput) abort() {
+	if d.tx != nil {
+		_ = d.tx.Rollback()
+		log.Printf("📢 已回滚未提交的%d行，之前已提交%d行", d.rows, d.committed)
+	}
+	_ = d.db.Close()
+}
+
+func (d *dbOutput) close() error {
+	defer d.db.Close()
+	return d.commit()
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/soulnov23/go-tool/pkg/utils"
+)
+
+const (
+	modeInsert  = "insert"
+	modeIgnore  = "ignore"
+	modeReplace = "replace"
+	modeUpsert  = "upsert"
+)
+
+// modes k=mode,v=语句开头
+var modes = map[string]string{
+	modeInsert:  "INSERT INTO ",
+	modeIgnore:  "INSERT IGNORE INTO ",
+	modeReplace: "REPLACE INTO ",
+	modeUpsert:  "INSERT INTO ",
+}
+
+// numberRegexp 开启类型推断时不加引号的数字，0开头的整数一般是编号或者手机号，按字符串处理
+var numberRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// column CSV中需要导入的列
+type column struct {
+	index int    // CSV中的列序号
+	name  string // 重命名后的表字段名
+}
+
+// selectColumns 按include、exclude和rename从表头中选出需要导入的列
+func selectColumns(header []string, include []string, exclude []string, rename map[string]string) ([]column, error) {
+	positions := make(map[string]int, len(header)) // k=表头,v=列序号
+	for i, name := range header {
+		positions[name] = i
+	}
+	for _, name := range include {
+		if !containsFold(header, name) {
+			return nil, fmt.Errorf("include column[%s] not found in header", name)
+		}
+	}
+	for name := range rename {
+		if _, ok := positions[name]; !ok {
+			return nil, fmt.Errorf("rename column[%s] not found in header", name)
+		}
+	}
+	var columns []column
+	for i, name := range header {
+		if len(include) != 0 && !containsFold(include, name) {
+			continue
+		}
+		if containsFold(exclude, name) {
+			continue
+		}
+		if newName, ok := rename[name]; ok {
+			name = newName
+		}
+		columns = append(columns, column{index: i, name: name})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no column to import")
+	}
+	return columns, nil
+}
+
+func containsFold(names []string, name string) bool {
+	for _, item := range names {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// builder 把多行拼成一条INSERT语句
+type builder struct {
+	prefix    string // INSERT INTO `t` (`a`,`b`) VALUES
+	suffix    string // ON DUPLICATE KEY UPDATE `a`=VALUES(`a`)
+	columns   []column
+	emptyNull bool // 空单元格插入NULL
+	infer     bool // 数字不加引号
+	maxBytes  int  // 每条语句最大字节数，由输出目标的max_allowed_packet决定
+	values    strings.Builder
+	rows      int
+}
+
+func newBuilder(mode string, table string, columns []column, updateColumns []string, emptyNull bool, infer bool) (*builder, error) {
+	verb, ok := modes[mode]
+	if !ok {
+		return nil, fmt.Errorf("mode[%s] not support", mode)
+	}
+	prefix := &strings.Builder{}
+	prefix.WriteString(verb)
+	writeTable(prefix, table)
+	prefix.WriteString(" (")
+	for i, column := range columns {
+		if i > 0 {
+			prefix.WriteByte(',')
+		}
+		writeIdentifier(prefix, column.name)
+	}
+	prefix.WriteString(") VALUES ")
+
+	suffix := &strings.Builder{}
+	if mode == modeUpsert {
+		if len(updateColumns) == 0 {
+			for _, column := range columns {
+				updateColumns = append(updateColumns, column.name)
+			}
+		}
+		suffix.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, name := range updateColumns {
+			if !containsName(columns, name) {
+				return nil, fmt.Errorf("update column[%s] not in imported columns", name)
+			}
+			if i > 0 {
+				suffix.WriteByte(',')
+			}
+			writeIdentifier(suffix, name)
+			suffix.WriteString("=VALUES(")
+			writeIdentifier(suffix, name)
+			suffix.WriteByte(')')
+		}
+	} else if len(updateColumns) != 0 {
+		return nil, fmt.Errorf("update columns only support mode[%s]", modeUpsert)
+	}
+	return &builder{
+		prefix:    prefix.String(),
+		suffix:    suffix.String(),
+		columns:   columns,
+		emptyNull: emptyNull,
+		infer:     infer,
+	}, nil
+}
+
+func containsName(columns []column, name string) bool {
+	for _, column := range columns {
+		if column.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// row 把一行拼成(v1,v2)
+func (b *builder) row(record []string) string {
+	row := &strings.Builder{}
+	row.WriteByte('(')
+	for i, column := range b.columns {
+		if i > 0 {
+			row.WriteByte(',')
+		}
+		row.WriteString(b.literal(record[column.index]))
+	}
+	row.WriteByte(')')
+	return row.String()
+}
+
+// fits 判断加上row后语句是否超过maxBytes，单行超过时也只能单独成一条语句
+func (b *builder) fits(row string) bool {
+	return b.rows == 0 || len(b.prefix)+b.values.Len()+1+len(row)+len(b.suffix) <= b.maxBytes
+}
+
+func (b *builder) add(row string) {
+	if b.rows > 0 {
+		b.values.WriteByte(',')
+	}
+	b.rows++
+	b.values.WriteString(row)
+}
+
+func (b *builder) literal(value string) string {
+	if value == "" && b.emptyNull {
+		return "NULL"
+	}
+	if b.infer && numberRegexp.MatchString(value) {
+		return value
+	}
+	return "'" + utils.MySQLRealEscapeString(value) + "'"
+}
+
+// statement 返回已经添加的行拼成的语句并清空，没有行时返回空字符串
+func (b *builder) statement() string {
+	if b.rows == 0 {
+		return ""
+	}
+	statement := b.prefix + b.values.String() + b.suffix
+	b.values.Reset()
+	b.rows = 0
+	return statement
+}
+
+// writeTable 表名写成database.table时库名和表名分别包裹
+func writeTable(builder *strings.Builder, table string) {
+	for i, part := range strings.Split(table, ".") {
+		if i > 0 {
+			builder.WriteByte('.')
+		}
+		writeIdentifier(builder, part)
+	}
+}
+
+// writeIdentifier 用反引号包裹标识符，列名中的.是名字的一部分
+func writeIdentifier(builder *strings.Builder, name string) {
+	builder.WriteByte('`')
+	builder.WriteString(strings.ReplaceAll(name, "`", "``"))
+	builder.WriteByte('`')
+}
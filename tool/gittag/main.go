@@ -2,28 +2,70 @@ package main
 
 import (
 	"bytes"
-	"fmt"
+	"flag"
 	"log"
 	"os"
 	"os/exec"
-	"strconv"
 	"strings"
 )
 
-// v${MAJOR}.${MINOR}.${PATCH}
-const (
-	tagPrefix           = "v"
-	initialVersion      = "1.0.0"
-	minorCarryThreshold = 99
-	patchCarryThreshold = 9
-)
-
 var workdir string
 
+// gittag                                  默认按patch递增，patch超过9进位到minor，minor超过99进位到major
+// gittag -bump minor -strict              严格SemVer递增minor
+// gittag -pre rc                          发布预发布版本，例如v1.2.1-rc.1，再次执行发布v1.2.1-rc.2，不带-pre执行发布v1.2.1
+// gittag -module pkg/foo -dry-run         monorepo中按模块打标签，例如pkg/foo/v1.2.3，只打印不创建
 func main() {
+	// 定义需要解析的命令行参数，不带参数时和之前的行为一致
+	var prefix string
+	var module string
+	var initialVersion string
+	var bump string
+	var strict bool
+	var patchMax int
+	var minorMax int
+	var pre string
+	var build string
+	var dryRun bool
+	var push bool
+	var remote string
+	flag.StringVar(&prefix, "prefix", "v", "tag prefix")
+	flag.StringVar(&module, "module", "", "module path in monorepo, tags look like module/v1.2.3")
+	flag.StringVar(&initialVersion, "initial", "1.0.0", "version to bump from when no tag found")
+	flag.StringVar(&bump, "bump", bumpPatch, "major|minor|patch")
+	flag.BoolVar(&strict, "strict", false, "strict SemVer, never carry patch to minor or minor to major")
+	flag.IntVar(&patchMax, "patch-max", 9, "carry to minor when patch exceeds this, ignored by -strict")
+	flag.IntVar(&minorMax, "minor-max", 99, "carry to major when minor exceeds this, ignored by -strict")
+	flag.StringVar(&pre, "pre", "", "pre-release identifier such as rc or beta, numbered automatically")
+	flag.StringVar(&build, "build", "", "build metadata appended after +")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the next tag without creating it")
+	flag.BoolVar(&push, "push", true, "push the tag to remote")
+	flag.StringVar(&remote, "remote", "origin", "remote to push the tag to")
+	// 开始解析命令行
+	flag.Parse()
+
 	log.SetFlags(0)
 	log.SetPrefix("\033[1;32m[gittag]\033[m ")
 
+	if module != "" {
+		prefix = strings.Trim(module, "/") + "/" + prefix
+	}
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "bump" {
+			explicit = true
+		}
+	})
+	b := &bumper{
+		bump:     strings.ToLower(bump),
+		explicit: explicit,
+		strict:   strict,
+		patchMax: patchMax,
+		minorMax: minorMax,
+		pre:      pre,
+		build:    build,
+	}
+
 	// 获取当前工作目录
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -31,23 +73,35 @@ func main() {
 	}
 	workdir = cwd
 
-	// 1. 检查当前HEAD是否已经打过tag，如果是则跳过
-	if tags := getHeadTags(); len(tags) > 0 {
+	// 1. 检查当前HEAD是否已经打过正式版本的tag，如果是则跳过，只有预发布tag时可以继续打正式版本
+	if tags := releaseTags(prefix, getHeadTags(prefix)); len(tags) > 0 {
 		log.Fatalf("📢 当前最新提交已有标签%v，跳过打标签", tags)
 	}
 
 	// 2. 获取当前版本
-	currentVersion := getCurrentVersion()
+	currentVersion := getCurrentVersion(prefix, initialVersion)
 
 	// 3. 递增版本号
-	newVersion := incrementVersion(currentVersion)
+	newVersion, err := b.next(currentVersion)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	log.Printf("✅ 版本递增成功[%s->%s]", currentVersion, newVersion)
 
 	// 4. 创建并推送Git标签
-	createAndPushGitTag(newVersion)
+	tag := prefix + newVersion.String()
+	if tagExists(tag) {
+		log.Fatalf("📢 标签[%s]已存在，跳过打标签", tag)
+	}
+	if dryRun {
+		log.Printf("📢 dry-run，不创建Git标签[%s]", tag)
+		return
+	}
+	createAndPushGitTag(tag, push, remote)
 }
 
 // 获取当前HEAD commit上的所有tag
-func getHeadTags() []string {
+func getHeadTags(prefix string) []string {
 	cmd := exec.Command("git", "tag", "--points-at", "HEAD")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -59,93 +113,66 @@ func getHeadTags() []string {
 	var tags []string
 	for tag := range strings.SplitSeq(strings.TrimSpace(stdout.String()), "\n") {
 		tag = strings.TrimSpace(tag)
-		if tag != "" && strings.HasPrefix(tag, tagPrefix) {
+		if tag != "" && strings.HasPrefix(tag, prefix) {
 			tags = append(tags, tag)
 		}
 	}
 	return tags
 }
 
-// 获取当前版本
-func getCurrentVersion() string {
-	// 执行git命令获取最新标签
-	cmd := exec.Command("git", "tag", "--sort=-version:refname")
+// 检查标签是否已经存在
+func tagExists(tag string) bool {
+	cmd := exec.Command("git", "rev-parse", "--quiet", "--verify", "refs/tags/"+tag)
+	cmd.Dir = workdir
+	return cmd.Run() == nil
+}
+
+// 获取当前版本，按SemVer优先级取最大的标签，git的version:refname排序会把预发布版本排在正式版本之后
+func getCurrentVersion(prefix string, initialVersion string) *version {
+	// 执行git命令获取所有标签
+	cmd := exec.Command("git", "tag", "--list", prefix+"*")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	cmd.Dir = workdir
+	var current *version
 	if err := cmd.Run(); err == nil {
 		for tag := range strings.SplitSeq(strings.TrimSpace(stdout.String()), "\n") {
 			tag = strings.TrimSpace(tag)
-			currentVersion, ok := strings.CutPrefix(tag, tagPrefix)
+			s, ok := strings.CutPrefix(tag, prefix)
 			if !ok {
-				log.Printf("📢 跳过无效标签[%s]: %v", tag, err)
 				continue
 			}
-			if _, _, _, err := parseVersion(currentVersion); err != nil {
-				log.Printf("📢 跳过无效标签[%s]: %v", tag, err)
-				continue
+			v, err := parseVersion(s)
+			if err != nil {
+				legacy, legacyErr := parseLegacyVersion(s)
+				if legacyErr != nil {
+					log.Printf("📢 跳过无效标签[%s]: %v", tag, err)
+					continue
+				}
+				log.Printf("📢 标签[%s]不是SemVer，忽略第四段按[%s]处理", tag, legacy)
+				v = legacy
+			}
+			if current == nil || v.compare(current) > 0 {
+				current = v
 			}
-			log.Printf("✅ 从Git标签获取当前版本[%s]", currentVersion)
-			return currentVersion
 		}
 	}
-
-	log.Printf("📢 未找到任何有效Git标签，使用初始版本[%s]", initialVersion)
-	return initialVersion
-}
-
-// parseVersion解析MAJOR.MINOR.PATCH，忽略-及之后的后缀（如 1.2.3-rc1）
-func parseVersion(version string) (major, minor, patch int, err error) {
-	numbers, _, _ := strings.Cut(version, "-")
-	majStr, rest, ok := strings.Cut(numbers, ".")
-	if !ok || majStr == "" || rest == "" {
-		return 0, 0, 0, fmt.Errorf("无效的版本格式[%s]", version)
-	}
-	minStr, patchStr, ok := strings.Cut(rest, ".")
-	if !ok || minStr == "" || patchStr == "" {
-		return 0, 0, 0, fmt.Errorf("无效的版本格式[%s]", version)
+	if current != nil {
+		log.Printf("✅ 从Git标签获取当前版本[%s]", current)
+		return current
 	}
-	patchStr, _, _ = strings.Cut(patchStr, ".")
 
-	if major, err = strconv.Atoi(majStr); err != nil {
-		return 0, 0, 0, fmt.Errorf("无法解析major[%s]: %s", majStr, version)
-	}
-	if minor, err = strconv.Atoi(minStr); err != nil {
-		return 0, 0, 0, fmt.Errorf("无法解析minor[%s]: %s", minStr, version)
-	}
-	if patch, err = strconv.Atoi(patchStr); err != nil {
-		return 0, 0, 0, fmt.Errorf("无法解析patch[%s]: %s", patchStr, version)
-	}
-	return major, minor, patch, nil
-}
-
-// 递增版本号
-func incrementVersion(version string) string {
-	major, minor, patch, err := parseVersion(version)
+	log.Printf("📢 未找到任何有效Git标签，使用初始版本[%s]", initialVersion)
+	current, err := parseVersion(initialVersion)
 	if err != nil {
-		log.Fatalf("❌ %v", err)
-	}
-
-	// 智能进位逻辑
-	patch++
-	if patch > patchCarryThreshold {
-		patch = 0
-		minor++
-		if minor > minorCarryThreshold {
-			minor = 0
-			major++
-		}
+		log.Fatalf("❌ 初始版本错误: %v", err)
 	}
-	newVersion := fmt.Sprintf("%d.%d.%d", major, minor, patch)
-	log.Printf("✅ 版本递增成功[%s->%s]", version, newVersion)
-	return newVersion
+	return current
 }
 
 // 创建并推送Git标签（伪原子操作：push失败则回滚本地tag）
-func createAndPushGitTag(version string) {
-	tag := tagPrefix + version
-
+func createAndPushGitTag(tag string, push bool, remote string) {
 	createCmd := exec.Command("git", "tag", tag)
 	var stdout, stderr bytes.Buffer
 	createCmd.Stdout = &stdout
@@ -155,8 +182,12 @@ func createAndPushGitTag(version string) {
 		log.Fatalf("❌ 创建Git标签[%s]失败: %s", tag, stderr.String())
 	}
 	log.Printf("✅ 已创建Git标签[%s]", tag)
+	if !push {
+		log.Printf("📢 不推送Git标签[%s]，需要时手动执行[git push %s %s]", tag, remote, tag)
+		return
+	}
 
-	pushCmd := exec.Command("git", "push", "origin", tag)
+	pushCmd := exec.Command("git", "push", remote, tag)
 	pushCmd.Stdout = &stdout
 	pushCmd.Stderr = &stderr
 	pushCmd.Dir = workdir
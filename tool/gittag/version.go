@@ -0,0 +1,224 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	bumpMajor = "major"
+	bumpMinor = "minor"
+	bumpPatch = "patch"
+)
+
+// version MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]
+type version struct {
+	major int
+	minor int
+	patch int
+	pre   string // 不包含-，例如rc.1
+	build string // 不包含+，不参与比较
+}
+
+// parseVersion 解析不带前缀的版本号
+func parseVersion(s string) (*version, error) {
+	return parse(s, 3)
+}
+
+// parseLegacyVersion 兼容旧版本gittag接受的MAJOR.MINOR.PATCH.N标签，忽略第四段
+func parseLegacyVersion(s string) (*version, error) {
+	return parse(s, 4)
+}
+
+// releaseTags 返回tags中的正式版本标签，预发布标签所在的提交还可以再打正式版本
+func releaseTags(prefix string, tags []string) []string {
+	var releases []string
+	for _, tag := range tags {
+		s, ok := strings.CutPrefix(tag, prefix)
+		if !ok {
+			continue
+		}
+		v, err := parseVersion(s)
+		if err != nil {
+			if v, err = parseLegacyVersion(s); err != nil {
+				continue
+			}
+		}
+		if v.pre == "" {
+			releases = append(releases, tag)
+		}
+	}
+	return releases
+}
+
+func parse(s string, count int) (*version, error) {
+	v := &version{}
+	s, v.build, _ = strings.Cut(s, "+")
+	s, v.pre, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != count {
+		return nil, fmt.Errorf("无效的版本格式[%s]", s)
+	}
+	parts = parts[:3]
+	numbers := []*int{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || len(part) > 1 && part[0] == '0' {
+			return nil, fmt.Errorf("无法解析版本号[%s]: %s", part, s)
+		}
+		*numbers[i] = n
+	}
+	return v, nil
+}
+
+// checkIdentifiers 按SemVer检查预发布标识和构建元数据，点分隔的每段只能是非空的[0-9A-Za-z-]，
+// 预发布标识中的数字不能以0开头
+func checkIdentifiers(s string, pre bool) error {
+	for part := range strings.SplitSeq(s, ".") {
+		if part == "" {
+			return fmt.Errorf("[%s]包含空的标识", s)
+		}
+		numeric := true
+		for _, c := range part {
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return fmt.Errorf("[%s]包含无效字符[%c]，只能是[0-9A-Za-z-]", s, c)
+			}
+		}
+		if pre && numeric && len(part) > 1 && part[0] == '0' {
+			return fmt.Errorf("[%s]中的数字标识[%s]不能以0开头", s, part)
+		}
+	}
+	return nil
+}
+
+func (v *version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	if v.build != "" {
+		s += "+" + v.build
+	}
+	return s
+}
+
+// compare 按SemVer的优先级比较，预发布版本小于正式版本
+func (v *version) compare(o *version) int {
+	if c := cmp.Compare(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.patch, o.patch); c != 0 {
+		return c
+	}
+	return comparePre(v.pre, o.pre)
+}
+
+func comparePre(a string, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		x, xErr := strconv.Atoi(as[i])
+		y, yErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case xErr == nil && yErr == nil:
+			c = cmp.Compare(x, y)
+		case xErr == nil:
+			c = -1 // 数字标识符小于字母标识符
+		case yErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// bumper 计算下一个版本号
+type bumper struct {
+	bump     string // major|minor|patch
+	explicit bool   // 是否指定了-bump，当前是预发布版本时没有指定则继续在同一个版本上发布
+	strict   bool   // 严格SemVer，不进位
+	patchMax int    // 不是strict时patch超过该值进位到minor
+	minorMax int    // 不是strict时minor超过该值进位到major
+	pre      string // 预发布标识，例如rc，rc.3
+	build    string // 构建元数据
+}
+
+func (b *bumper) next(current *version) (*version, error) {
+	if b.pre != "" {
+		if err := checkIdentifiers(b.pre, true); err != nil {
+			return nil, fmt.Errorf("预发布标识错误: %v", err)
+		}
+	}
+	if b.build != "" {
+		if err := checkIdentifiers(b.build, false); err != nil {
+			return nil, fmt.Errorf("构建元数据错误: %v", err)
+		}
+	}
+	next := &version{major: current.major, minor: current.minor, patch: current.patch, build: b.build}
+	// 1.2.0-rc.1的下一个版本：没有-pre时发布正式版1.2.0，有-pre时发布1.2.0-rc.2
+	if current.pre == "" || b.explicit {
+		switch b.bump {
+		case bumpMajor:
+			next.major, next.minor, next.patch = next.major+1, 0, 0
+		case bumpMinor:
+			next.minor, next.patch = next.minor+1, 0
+		case bumpPatch:
+			next.patch++
+		default:
+			return nil, fmt.Errorf("不支持的递增类型[%s]", b.bump)
+		}
+		if !b.strict {
+			if next.patch > b.patchMax {
+				next.minor, next.patch = next.minor+1, 0
+			}
+			if next.minor > b.minorMax {
+				next.major, next.minor, next.patch = next.major+1, 0, 0
+			}
+		}
+	}
+	if b.pre != "" {
+		next.pre = b.preRelease(current, next)
+		if next.compare(current) <= 0 {
+			return nil, fmt.Errorf("预发布版本[%s]不大于当前版本[%s]", next, current)
+		}
+	}
+	return next, nil
+}
+
+// preRelease 标识没有带序号时自动递增，例如当前是1.2.0-rc.1时返回rc.2
+func (b *bumper) preRelease(current *version, next *version) string {
+	id, number, ok := strings.Cut(b.pre, ".")
+	if ok && number != "" {
+		return b.pre
+	}
+	n := 1
+	if current.major == next.major && current.minor == next.minor && current.patch == next.patch {
+		if currentID, currentNumber, ok := strings.Cut(current.pre, "."); ok && currentID == id {
+			if i, err := strconv.Atoi(currentNumber); err == nil {
+				n = i + 1
+			}
+		}
+	}
+	return id + "." + strconv.Itoa(n)
+}
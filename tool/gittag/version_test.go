@@ -0,0 +1,147 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		s       string
+		legacy  bool
+		want    string
+		wantErr bool
+	}{
+		{s: "1.2.3", want: "1.2.3"},
+		{s: "1.2.3-rc.1+build.5", want: "1.2.3-rc.1+build.5"},
+		{s: "1.2", wantErr: true},
+		{s: "01.2.3", wantErr: true},
+		{s: "1.2.3.4", wantErr: true},
+		{s: "1.2.3.4", legacy: true, want: "1.2.3"},
+		{s: "1.2.3.4-rc.1", legacy: true, want: "1.2.3-rc.1"},
+		{s: "1.2.3", legacy: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			parse := parseVersion
+			if tt.legacy {
+				parse = parseLegacyVersion
+			}
+			v, err := parse(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && v.String() != tt.want {
+				t.Errorf("parse() = %s, want %s", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparePre(t *testing.T) {
+	// SemVer 2.0.0第11节的例子，按从小到大排列
+	ordered := []string{"alpha", "alpha.1", "alpha.beta", "beta", "beta.2", "beta.11", "rc.1", ""}
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := comparePre(ordered[i], ordered[j]); got != want {
+				t.Errorf("comparePre(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestPreRelease(t *testing.T) {
+	tests := []struct {
+		pre     string
+		current string
+		next    string
+		want    string
+	}{
+		{pre: "rc", current: "1.2.0", next: "1.2.1", want: "rc.1"},
+		{pre: "rc", current: "1.2.1-rc.1", next: "1.2.1", want: "rc.2"},
+		{pre: "rc", current: "1.2.1-beta.3", next: "1.2.1", want: "rc.1"},
+		{pre: "rc", current: "1.2.1-rc.1", next: "1.3.0", want: "rc.1"},
+		{pre: "rc", current: "1.2.1-rc", next: "1.2.1", want: "rc.1"},
+		{pre: "rc.5", current: "1.2.1-rc.1", next: "1.2.1", want: "rc.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pre+"/"+tt.current, func(t *testing.T) {
+			current, _ := parseVersion(tt.current)
+			next, _ := parseVersion(tt.next)
+			b := &bumper{pre: tt.pre}
+			if got := b.preRelease(current, next); got != tt.want {
+				t.Errorf("preRelease() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumperNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		bumper  bumper
+		current string
+		want    string
+		wantErr bool
+	}{
+		{name: "patch", bumper: bumper{bump: bumpPatch, patchMax: 9, minorMax: 99}, current: "1.2.3", want: "1.2.4"},
+		{name: "minor", bumper: bumper{bump: bumpMinor, patchMax: 9, minorMax: 99}, current: "1.2.3", want: "1.3.0"},
+		{name: "major", bumper: bumper{bump: bumpMajor, patchMax: 9, minorMax: 99}, current: "1.2.3", want: "2.0.0"},
+		{name: "patch carry", bumper: bumper{bump: bumpPatch, patchMax: 9, minorMax: 99}, current: "1.2.9", want: "1.3.0"},
+		{name: "minor carry", bumper: bumper{bump: bumpPatch, patchMax: 9, minorMax: 99}, current: "1.99.9", want: "2.0.0"},
+		{name: "strict", bumper: bumper{bump: bumpPatch, strict: true}, current: "1.2.9", want: "1.2.10"},
+		{name: "build", bumper: bumper{bump: bumpPatch, strict: true, build: "sha.5114f85"}, current: "1.2.3+old", want: "1.2.4+sha.5114f85"},
+		{name: "pre", bumper: bumper{bump: bumpPatch, strict: true, pre: "rc"}, current: "1.2.3", want: "1.2.4-rc.1"},
+		{name: "pre again", bumper: bumper{bump: bumpPatch, strict: true, pre: "rc"}, current: "1.2.4-rc.1", want: "1.2.4-rc.2"},
+		{name: "release pre", bumper: bumper{bump: bumpPatch, strict: true}, current: "1.2.4-rc.2", want: "1.2.4"},
+		{name: "explicit bump from pre", bumper: bumper{bump: bumpMinor, explicit: true, strict: true, pre: "rc"}, current: "1.2.4-rc.2", want: "1.3.0-rc.1"},
+		{name: "pre not greater", bumper: bumper{bump: bumpPatch, strict: true, pre: "alpha"}, current: "1.2.4-rc.2", wantErr: true},
+		{name: "unknown bump", bumper: bumper{bump: "build"}, current: "1.2.3", wantErr: true},
+		{name: "invalid pre", bumper: bumper{bump: bumpPatch, strict: true, pre: "rc_1"}, current: "1.2.3", wantErr: true},
+		{name: "empty pre identifier", bumper: bumper{bump: bumpPatch, strict: true, pre: "rc..1"}, current: "1.2.3", wantErr: true},
+		{name: "leading zero pre", bumper: bumper{bump: bumpPatch, strict: true, pre: "rc.01"}, current: "1.2.3", wantErr: true},
+		{name: "leading zero build", bumper: bumper{bump: bumpPatch, strict: true, build: "001"}, current: "1.2.3", want: "1.2.4+001"},
+		{name: "invalid build", bumper: bumper{bump: bumpPatch, strict: true, build: "a+b"}, current: "1.2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := parseVersion(tt.current)
+			if err != nil {
+				t.Fatalf("parseVersion: %v", err)
+			}
+			got, err := tt.bumper.next(current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseTags(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{tags: []string{"v1.2.4-rc.1"}},
+		{tags: []string{"v1.2.4-rc.1", "v1.2.4"}, want: []string{"v1.2.4"}},
+		{tags: []string{"v1.2.3.4"}, want: []string{"v1.2.3.4"}},
+		{tags: []string{"release-1", "1.2.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.tags, ","), func(t *testing.T) {
+			if got := releaseTags("v", tt.tags); !slices.Equal(got, tt.want) {
+				t.Errorf("releaseTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
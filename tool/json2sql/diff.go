@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateTimeLayout 没有开启parseTime时DATETIME和TIMESTAMP返回的文本格式
+const dateTimeLayout = "2006-01-02 15:04:05.999999"
+
+// diffResult 把快照同步到线上表需要执行的语句，按DELETE、UPDATE、INSERT的顺序执行，避免唯一键冲突
+type diffResult struct {
+	deletes []string
+	updates []string
+	inserts []string
+}
+
+func (d *diffResult) statements() []string {
+	statements := make([]string, 0, len(d.deletes)+len(d.updates)+len(d.inserts))
+	statements = append(statements, d.deletes...)
+	statements = append(statements, d.updates...)
+	return append(statements, d.inserts...)
+}
+
+// primaryKeys 查询表的主键列
+func primaryKeys(db *sql.DB, table string) ([]string, error) {
+	var schema any
+	if database, name, ok := strings.Cut(table, "."); ok {
+		schema, table = database, name
+	}
+	rows, err := db.Query("SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = COALESCE(?, DATABASE()) AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION", schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("table[%s] has no primary key, use -key", table)
+	}
+	return keys, nil
+}
+
+// snapshotKey 快照中一行的主键，用于和线上的行匹配
+func snapshotKey(r *row, keys []string) (string, error) {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		value, ok := r.value(key)
+		if !ok || value[0] == 'n' {
+			return "", fmt.Errorf("key column[%s] not found or null", key)
+		}
+		if value[0] == '"' {
+			var s string
+			_ = json.Unmarshal(value, &s)
+			parts[i] = s
+			continue
+		}
+		parts[i] = string(value)
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// diff 对比快照和线上的表，where限制对比的范围，快照是部分数据时避免删除范围外的行
+//
+// 只对比快照中有的列，线上表中多出来的列不会修改，timeFormat是快照中DATETIME和TIMESTAMP的格式
+func diff(db *sql.DB, table string, where string, keys []string, timeFormat string, snapshot []*row) (*diffResult, error) {
+	index := make(map[string]int, len(snapshot)) // k=主键,v=快照中的序号
+	for i, r := range snapshot {
+		key, err := snapshotKey(r, keys)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot row %d: %v", i+1, err)
+		}
+		if _, ok := index[key]; ok {
+			return nil, fmt.Errorf("snapshot row %d: duplicate key %q", i+1, strings.ReplaceAll(key, "\x00", ","))
+		}
+		index[key] = i
+	}
+
+	query := "SELECT * FROM " + tableIdentifier(table)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]int, len(columnTypes)) // k=列名,v=序号
+	types := make([]string, len(columnTypes))
+	typeOf := make(map[string]string, len(columnTypes)) // k=列名,v=数据库类型
+	for i, columnType := range columnTypes {
+		positions[columnType.Name()] = i
+		types[i] = columnType.DatabaseTypeName()
+		typeOf[columnType.Name()] = types[i]
+	}
+	keyPositions := make([]int, len(keys))
+	for i, key := range keys {
+		position, ok := positions[key]
+		if !ok {
+			return nil, fmt.Errorf("key column[%s] not found in table", key)
+		}
+		keyPositions[i] = position
+	}
+	for _, r := range snapshot {
+		for _, column := range r.columns {
+			if _, ok := positions[column]; !ok {
+				return nil, fmt.Errorf("snapshot column[%s] not found in table", column)
+			}
+		}
+	}
+
+	result := &diffResult{}
+	seen := make([]bool, len(snapshot))
+	values := make([]any, len(columnTypes))
+	ptrs := make([]any, len(columnTypes))
+	for i := range ptrs {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(keys))
+		for i, position := range keyPositions {
+			parts[i] = text(values[position], types[position])
+		}
+		i, ok := index[strings.Join(parts, "\x00")]
+		if !ok {
+			result.deletes = append(result.deletes, deleteStatement(table, keys, keyPositions, values, types))
+			continue
+		}
+		seen[i] = true
+		if statement := updateStatement(table, keys, snapshot[i], positions, values, types, timeFormat); statement != "" {
+			result.updates = append(result.updates, statement)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, r := range snapshot {
+		if seen[i] {
+			continue
+		}
+		b, _ := newBuilder(modeInsert, table, nil)
+		b.types, b.timeFormat = typeOf, timeFormat
+		b.add(r)
+		statement, _ := b.statement()
+		result.inserts = append(result.inserts, statement)
+	}
+	return result, nil
+}
+
+func deleteStatement(table string, keys []string, keyPositions []int, values []any, types []string) string {
+	builder := &strings.Builder{}
+	builder.WriteString("DELETE FROM ")
+	writeTable(builder, table)
+	builder.WriteString(" WHERE ")
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteString(" AND ")
+		}
+		writeIdentifier(builder, key)
+		builder.WriteString(" = ")
+		builder.WriteString(quote(text(values[keyPositions[i]], types[keyPositions[i]])))
+	}
+	return builder.String()
+}
+
+// updateStatement 只更新值不同的列，没有不同时返回空字符串
+func updateStatement(table string, keys []string, r *row, positions map[string]int, values []any, types []string, timeFormat string) string {
+	builder := &strings.Builder{}
+	changed := 0
+	for i, column := range r.columns {
+		position := positions[column]
+		if equal(r.values[i], values[position], types[position], timeFormat) {
+			continue
+		}
+		if changed == 0 {
+			builder.WriteString("UPDATE ")
+			writeTable(builder, table)
+			builder.WriteString(" SET ")
+		} else {
+			builder.WriteByte(',')
+		}
+		changed++
+		writeIdentifier(builder, column)
+		builder.WriteByte('=')
+		builder.WriteString(literal(snapshotValue(r.values[i], types[position], timeFormat)))
+	}
+	if changed == 0 {
+		return ""
+	}
+	builder.WriteString(" WHERE ")
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteString(" AND ")
+		}
+		value, _ := r.value(key)
+		writeIdentifier(builder, key)
+		builder.WriteString(" = ")
+		builder.WriteString(literal(snapshotValue(value, types[positions[key]], timeFormat)))
+	}
+	return builder.String()
+}
+
+// text 线上的值转换成文本，text协议返回的都是[]byte，BIT转换成数字，开启parseTime时时间按text协议的格式输出
+func text(value any, typ string) string {
+	switch v := value.(type) {
+	case []byte:
+		if typ == "BIT" {
+			buffer := make([]byte, 8)
+			copy(buffer[8-min(len(v), 8):], v)
+			return strconv.FormatUint(binary.BigEndian.Uint64(buffer), 10)
+		}
+		return string(v)
+	case time.Time:
+		if typ == "DATE" {
+			return v.Format(time.DateOnly)
+		}
+		return v.Format(dateTimeLayout)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// equal 快照中的值和线上的值是否相同，数字按数值比较，JSON按语义比较，时间按sql2json的-time-format比较
+func equal(snapshot json.RawMessage, live any, typ string, timeFormat string) bool {
+	if snapshot[0] == 'n' || live == nil {
+		return snapshot[0] == 'n' && live == nil
+	}
+	if typ == "DATETIME" || typ == "TIMESTAMP" {
+		// 0000-00-00 00:00:00之类解析失败的值按文本比较
+		if t, ok := liveTime(live); ok {
+			return timeEqual(snapshot, t, timeFormat)
+		}
+	}
+	liveText := text(live, typ)
+	switch snapshot[0] {
+	case 't':
+		return liveText == "1"
+	case 'f':
+		return liveText == "0"
+	case '"':
+		var s string
+		_ = json.Unmarshal(snapshot, &s)
+		return s == liveText || numeric(typ) && numberEqual(s, liveText)
+	case '{', '[':
+		return canonical(snapshot) == canonical([]byte(liveText))
+	default:
+		if !numeric(typ) {
+			return string(snapshot) == liveText
+		}
+		return numberEqual(string(snapshot), liveText)
+	}
+}
+
+// numeric 数字类型的列按数值比较，字符串列中的007和7是不同的值
+func numeric(typ string) bool {
+	switch strings.TrimPrefix(typ, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR", "DECIMAL", "FLOAT", "DOUBLE", "BIT":
+		return true
+	}
+	return false
+}
+
+// liveTime 和sql2json一样，没有开启parseTime时按time.Local解析
+func liveTime(live any) (time.Time, bool) {
+	if t, ok := live.(time.Time); ok {
+		return t, true
+	}
+	b, ok := live.([]byte)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, string(b), time.Local)
+	return t, err == nil
+}
+
+// timeEqual 线上的时间按timeFormat格式化后和快照比较，快照精度更高时解析后按时刻比较
+func timeEqual(snapshot json.RawMessage, live time.Time, timeFormat string) bool {
+	value := string(snapshot)
+	if snapshot[0] == '"' {
+		_ = json.Unmarshal(snapshot, &value)
+	}
+	switch strings.ToLower(timeFormat) {
+	case "unix":
+		return numberEqual(value, strconv.FormatInt(live.Unix(), 10))
+	case "unix_milli":
+		return numberEqual(value, strconv.FormatInt(live.UnixMilli(), 10))
+	}
+	if value == live.Format(timeFormat) {
+		return true
+	}
+	for _, layout := range []string{timeFormat, dateTimeLayout, time.RFC3339Nano} {
+		if t, err := time.ParseInLocation(layout, value, live.Location()); err == nil && t.Equal(live) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotValue 快照中按timeFormat输出的DATETIME和TIMESTAMP转换成MySQL能插入的格式，其他类型和解析失败的值原样返回
+func snapshotValue(value json.RawMessage, typ string, timeFormat string) json.RawMessage {
+	if typ != "DATETIME" && typ != "TIMESTAMP" || value[0] == 'n' {
+		return value
+	}
+	t, ok := snapshotTime(value, timeFormat)
+	if !ok {
+		return value
+	}
+	buffer, _ := json.Marshal(t.Format(dateTimeLayout))
+	return buffer
+}
+
+// snapshotTime 按sql2json的-time-format解析快照中的时间，unix和unix_milli是时间戳，其他是time的layout
+func snapshotTime(value json.RawMessage, timeFormat string) (time.Time, bool) {
+	s := string(value)
+	if value[0] == '"' {
+		_ = json.Unmarshal(value, &s)
+	}
+	switch strings.ToLower(timeFormat) {
+	case "unix":
+		i, err := strconv.ParseInt(s, 10, 64)
+		return time.Unix(i, 0), err == nil
+	case "unix_milli":
+		i, err := strconv.ParseInt(s, 10, 64)
+		return time.UnixMilli(i), err == nil
+	}
+	t, err := time.ParseInLocation(timeFormat, s, time.Local)
+	return t, err == nil
+}
+
+// columnTypes 查询表的字段类型，k=列名,v=数据库类型
+func columnTypes(db *sql.DB, table string) (map[string]string, error) {
+	rows, err := db.Query("SELECT * FROM " + tableIdentifier(table) + " LIMIT 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(columns))
+	for _, column := range columns {
+		types[column.Name()] = column.DatabaseTypeName()
+	}
+	return types, nil
+}
+
+func numberEqual(a string, b string) bool {
+	x, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return false
+	}
+	y, ok := new(big.Rat).SetString(b)
+	return ok && x.Cmp(y) == 0
+}
+
+// canonical 重新序列化JSON，对象的字段按key排序，去掉空白
+func canonical(value []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return string(value)
+	}
+	buffer, _ := json.Marshal(v)
+	return string(buffer)
+}
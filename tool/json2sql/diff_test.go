@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEqual(t *testing.T) {
+	live := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.Local)
+	tests := []struct {
+		name       string
+		snapshot   string
+		live       any
+		typ        string
+		timeFormat string
+		want       bool
+	}{
+		{name: "null", snapshot: `null`, live: nil, typ: "VARCHAR", want: true},
+		{name: "null to value", snapshot: `null`, live: []byte(""), typ: "VARCHAR", want: false},
+		{name: "string", snapshot: `"a"`, live: []byte("a"), typ: "VARCHAR", want: true},
+		{name: "string differ", snapshot: `"a"`, live: []byte("b"), typ: "VARCHAR", want: false},
+		{name: "bool", snapshot: `true`, live: []byte("1"), typ: "TINYINT", want: true},
+		{name: "bit", snapshot: `false`, live: []byte{0}, typ: "BIT", want: true},
+		{name: "number", snapshot: `1.50`, live: []byte("1.5"), typ: "DECIMAL", want: true},
+		{name: "decimal string", snapshot: `"1.50"`, live: []byte("1.5000"), typ: "DECIMAL", want: true},
+		{name: "json", snapshot: `{"b":1,"a":[1,2]}`, live: []byte(`{"a": [1, 2], "b": 1}`), typ: "JSON", want: true},
+		{name: "json differ", snapshot: `{"a":1}`, live: []byte(`{"a": 2}`), typ: "JSON", want: false},
+		{name: "date parse time", snapshot: `"2024-01-02"`, live: live, typ: "DATE", want: true},
+		{name: "datetime", snapshot: `"2024-01-02 03:04:05"`, live: []byte("2024-01-02 03:04:05"), typ: "DATETIME", timeFormat: time.DateTime, want: true},
+		{name: "datetime differ", snapshot: `"2024-01-02 03:04:06"`, live: []byte("2024-01-02 03:04:05"), typ: "DATETIME", timeFormat: time.DateTime, want: false},
+		{name: "datetime6 truncated", snapshot: `"2024-01-02 03:04:05"`, live: []byte("2024-01-02 03:04:05.123456"), typ: "DATETIME", timeFormat: time.DateTime, want: true},
+		{name: "datetime6 full", snapshot: `"2024-01-02 03:04:05.123456"`, live: []byte("2024-01-02 03:04:05.123456"), typ: "DATETIME", timeFormat: time.DateTime, want: true},
+		{name: "parse time", snapshot: `"2024-01-02 03:04:05"`, live: live, typ: "TIMESTAMP", timeFormat: time.DateTime, want: true},
+		{name: "rfc3339", snapshot: `"` + live.Format(time.RFC3339) + `"`, live: live, typ: "DATETIME", timeFormat: time.RFC3339, want: true},
+		{name: "unix", snapshot: `1704164645`, live: time.Unix(1704164645, 0), typ: "DATETIME", timeFormat: "unix", want: true},
+		{name: "unix milli", snapshot: `1704164645123`, live: time.UnixMilli(1704164645123), typ: "DATETIME", timeFormat: "unix_milli", want: true},
+		{name: "unix differ", snapshot: `1704164646`, live: time.Unix(1704164645, 0), typ: "DATETIME", timeFormat: "unix", want: false},
+		{name: "varchar leading zero", snapshot: `"007"`, live: []byte("7"), typ: "VARCHAR", want: false},
+		{name: "varchar fraction", snapshot: `"1.0"`, live: []byte("1"), typ: "VARCHAR", want: false},
+		{name: "varchar number", snapshot: `1.0`, live: []byte("1"), typ: "VARCHAR", want: false},
+		{name: "int string", snapshot: `"007"`, live: []byte("7"), typ: "INT", want: true},
+		{name: "unsigned", snapshot: `7`, live: []byte("7.0"), typ: "UNSIGNED BIGINT", want: true},
+		{name: "zero datetime", snapshot: `"0000-00-00 00:00:00"`, live: []byte("0000-00-00 00:00:00"), typ: "DATETIME", timeFormat: time.DateTime, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := equal(json.RawMessage(tt.snapshot), tt.live, tt.typ, tt.timeFormat); got != tt.want {
+				t.Errorf("equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	live := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	tests := []struct {
+		value any
+		typ   string
+		want  string
+	}{
+		{value: []byte("abc"), typ: "VARCHAR", want: "abc"},
+		{value: []byte{1, 0}, typ: "BIT", want: "256"},
+		{value: live, typ: "DATETIME", want: "2024-01-02 03:04:05"},
+		{value: live.Add(123 * time.Millisecond), typ: "DATETIME", want: "2024-01-02 03:04:05.123"},
+		{value: live, typ: "DATE", want: "2024-01-02"},
+		{value: int64(7), typ: "INT", want: "7"},
+	}
+	for _, tt := range tests {
+		if got := text(tt.value, tt.typ); got != tt.want {
+			t.Errorf("text(%v, %s) = %s, want %s", tt.value, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotValue(t *testing.T) {
+	unix := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local).Unix()
+	tests := []struct {
+		value      string
+		typ        string
+		timeFormat string
+		want       string
+	}{
+		{value: strconv.FormatInt(unix, 10), typ: "DATETIME", timeFormat: "unix", want: `"2024-01-02 03:04:05"`},
+		{value: strconv.FormatInt(unix*1000+123, 10), typ: "TIMESTAMP", timeFormat: "unix_milli", want: `"2024-01-02 03:04:05.123"`},
+		{value: `"2024-01-02T03:04:05.5"`, typ: "DATETIME", timeFormat: "2006-01-02T15:04:05.999999999", want: `"2024-01-02 03:04:05.5"`},
+		{value: `"2024-01-02 03:04:05"`, typ: "DATETIME", timeFormat: time.DateTime, want: `"2024-01-02 03:04:05"`},
+		{value: `"0000-00-00 00:00:00"`, typ: "DATETIME", timeFormat: time.DateTime, want: `"0000-00-00 00:00:00"`},
+		{value: `null`, typ: "DATETIME", timeFormat: "unix", want: `null`},
+		{value: `1704164645`, typ: "BIGINT", timeFormat: "unix", want: `1704164645`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := string(snapshotValue(json.RawMessage(tt.value), tt.typ, tt.timeFormat)); got != tt.want {
+				t.Errorf("snapshotValue() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateStatement(t *testing.T) {
+	positions := map[string]int{"id": 0, "name": 1, "updated_at": 2, "a.b": 3}
+	types := []string{"BIGINT", "VARCHAR", "DATETIME", "VARCHAR"}
+	unix := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local).Unix()
+	tests := []struct {
+		name       string
+		row        *row
+		values     []any
+		timeFormat string
+		want       string
+	}{
+		{
+			name:   "same",
+			row:    &row{columns: []string{"id", "name", "updated_at"}, values: []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`"a"`), json.RawMessage(`"2024-01-02 03:04:05"`)}},
+			values: []any{[]byte("1"), []byte("a"), []byte("2024-01-02 03:04:05.000001"), []byte("x")},
+		},
+		{
+			name:   "changed",
+			row:    &row{columns: []string{"id", "name", "updated_at"}, values: []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`"it's"`), json.RawMessage(`null`)}},
+			values: []any{[]byte("1"), []byte("a"), []byte("2024-01-02 03:04:05"), []byte("x")},
+			want:   "UPDATE `db`.`user` SET `name`='it\\'s',`updated_at`=NULL WHERE `id` = 1",
+		},
+		{
+			name:       "unix time",
+			row:        &row{columns: []string{"id", "updated_at", "a.b"}, values: []json.RawMessage{json.RawMessage(`1`), json.RawMessage(strconv.FormatInt(unix+1, 10)), json.RawMessage(`"y"`)}},
+			values:     []any{[]byte("1"), []byte("a"), []byte("2024-01-02 03:04:05"), []byte("x")},
+			timeFormat: "unix",
+			want:       "UPDATE `db`.`user` SET `updated_at`='2024-01-02 03:04:06',`a.b`='y' WHERE `id` = 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeFormat := tt.timeFormat
+			if timeFormat == "" {
+				timeFormat = time.DateTime
+			}
+			got := updateStatement("db.user", []string{"id"}, tt.row, positions, tt.values, types, timeFormat)
+			if got != tt.want {
+				t.Errorf("updateStatement() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
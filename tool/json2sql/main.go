@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// json2sql -json tmp.json -sql tmp.sql -table database.table
+// json2sql -json tmp.ndjson -table database.table -mode upsert -batch 500 -max-packet 16777216 -dsn 'user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local'
+// json2sql -json tmp.json -table database.table -diff -where 'status = 1' -time-format unix -sql fix.sql -dsn 'user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local'
+func main() {
+	// 定义需要解析的命令行参数
+	var jsonPath string
+	var sqlPath string
+	var tableName string
+	var batch int
+	var maxPacket int
+	var mode string
+	var update string
+	var dsn string
+	var txRows int
+	var diffMode bool
+	var key string
+	var where string
+	var apply bool
+	var timeFormat string
+	flag.StringVar(&jsonPath, "json", "tmp.json", "json array or ndjson file path, such as the output of sql2json")
+	flag.StringVar(&sqlPath, "sql", "tmp.sql", "sql file path")
+	flag.StringVar(&tableName, "table", "tmp", "table name")
+	flag.IntVar(&batch, "batch", 100, "rows per INSERT statement")
+	flag.IntVar(&maxPacket, "max-packet", 0, "max bytes per INSERT statement, default 90% of max_allowed_packet in dsn mode, 90% of 4MB otherwise")
+	flag.StringVar(&mode, "mode", modeInsert, "insert|ignore|replace|upsert")
+	flag.StringVar(&update, "update", "", "columns to update in upsert mode, separated by comma, default all columns")
+	flag.StringVar(&dsn, "dsn", "", "execute against mysql instead of writing sql file, required by -diff")
+	flag.IntVar(&txRows, "tx-rows", 10000, "rows per transaction in dsn mode")
+	flag.BoolVar(&diffMode, "diff", false, "compare json with the table by primary key and write INSERT/UPDATE/DELETE to sql file")
+	flag.StringVar(&key, "key", "", "primary key columns for -diff, separated by comma, default read from table")
+	flag.StringVar(&where, "where", "", "only compare rows matching this condition in -diff, for partial snapshots")
+	flag.BoolVar(&apply, "apply", false, "execute -diff statements in one transaction instead of writing sql file")
+	flag.StringVar(&timeFormat, "time-format", time.DateTime, "DATETIME and TIMESTAMP layout of the json, same as sql2json -time-format, other layouts than the default require -dsn to read column types")
+	// 开始解析命令行
+	flag.Parse()
+	// 命令行参数都不匹配，打印help
+	if flag.NFlag() == 0 {
+		flag.Usage()
+		return
+	}
+
+	log.SetFlags(0)
+	log.SetPrefix("\033[1;32m[json2sql]\033[m ")
+
+	if batch <= 0 || txRows <= 0 || maxPacket < 0 {
+		log.Fatalf("❌ batch和tx-rows必须大于0，max-packet不能小于0")
+	}
+	if diffMode && dsn == "" {
+		log.Fatalf("❌ -diff需要指定-dsn")
+	}
+	// 需要按字段类型把快照中的时间转换成MySQL的格式
+	if timeFormat != time.DateTime && dsn == "" {
+		log.Fatalf("❌ -time-format需要指定-dsn读取字段类型")
+	}
+	b, err := newBuilder(strings.ToLower(mode), tableName, splitList(update))
+	if err != nil {
+		log.Fatalf("❌ 参数错误: %s", err.Error())
+	}
+	jsonFile, err := os.Open(jsonPath)
+	if err != nil {
+		log.Fatalf("❌ 打开JSON文件失败: %s", err.Error())
+	}
+	defer jsonFile.Close()
+	reader, err := newRowReader(jsonFile)
+	if err != nil {
+		log.Fatalf("❌ 读取JSON文件失败: %s", err.Error())
+	}
+
+	var db *sql.DB
+	if dsn != "" {
+		db, err = sql.Open("mysql", dsn)
+		if err != nil {
+			log.Fatalf("❌ [%s]参数解析失败: %s", dsn, err.Error())
+		}
+		defer db.Close()
+		// sql.Open 只验证参数格式，不建立真实连接，需要 Ping 确认连通性
+		if err := db.Ping(); err != nil {
+			log.Fatalf("❌ 连接失败: %s", err.Error())
+		}
+		log.Printf("✅ 连接成功")
+	}
+	if diffMode {
+		runDiff(db, reader, tableName, splitList(key), where, timeFormat, sqlPath, apply)
+		return
+	}
+
+	var out output
+	if db != nil {
+		dbOut := &dbOutput{db: db, txRows: txRows}
+		if maxPacket == 0 {
+			if maxPacket, err = dbOut.maxPacket(); err != nil {
+				log.Fatalf("❌ %s", err.Error())
+			}
+		}
+		if b.types, err = columnTypes(db, tableName); err != nil {
+			log.Fatalf("❌ 获取字段类型失败: %s", err.Error())
+		}
+		out = dbOut
+	} else {
+		if out, err = newFileOutput(sqlPath); err != nil {
+			log.Fatalf("❌ %s", err.Error())
+		}
+		if maxPacket == 0 {
+			maxPacket = defaultMaxPacket * 9 / 10
+		}
+	}
+	b.maxBytes, b.timeFormat = maxPacket, timeFormat
+	rows := 0
+	for {
+		r, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			out.abort()
+			log.Fatalf("❌ 读取JSON文件失败: %s", err.Error())
+		}
+		// 字段不同或者超过max_allowed_packet的行不能拼在一条语句中
+		row := b.row(r)
+		if !b.same(r) || !b.fits(row) {
+			flush(out, b, rows)
+		}
+		b.addRow(r, row)
+		rows++
+		if b.rows >= batch {
+			flush(out, b, rows)
+		}
+	}
+	flush(out, b, rows)
+	if err := out.close(); err != nil {
+		log.Fatalf("❌ 导入失败: %s", err.Error())
+	}
+	if db != nil {
+		log.Printf("✅ 共导入%d行到: %s", rows, tableName)
+		return
+	}
+	log.Printf("✅ 共%d行，结果已保存到: %s", rows, sqlPath)
+}
+
+// flush 输出builder中的行，失败时回滚未提交的数据后退出
+func flush(out output, b *builder, rows int) {
+	n := b.rows
+	statement, err := b.statement()
+	if err == nil {
+		err = out.write(statement, n)
+	}
+	if err != nil {
+		out.abort()
+		log.Fatalf("❌ 导入失败，已读取%d行数据: %s", rows, err.Error())
+	}
+}
+
+func runDiff(db *sql.DB, reader *rowReader, table string, keys []string, where string, timeFormat string, sqlPath string, apply bool) {
+	var snapshot []*row
+	for {
+		r, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("❌ 读取JSON文件失败: %s", err.Error())
+		}
+		snapshot = append(snapshot, r)
+	}
+	if len(keys) == 0 {
+		var err error
+		if keys, err = primaryKeys(db, table); err != nil {
+			log.Fatalf("❌ 获取主键失败: %s", err.Error())
+		}
+	}
+	result, err := diff(db, table, where, keys, timeFormat, snapshot)
+	if err != nil {
+		log.Fatalf("❌ 对比失败: %s", err.Error())
+	}
+	log.Printf("✅ 对比完成: INSERT %d行，UPDATE %d行，DELETE %d行", len(result.inserts), len(result.updates), len(result.deletes))
+	statements := result.statements()
+	if len(statements) == 0 {
+		log.Printf("✅ 没有差异")
+		return
+	}
+
+	var out output
+	if apply {
+		// 所有语句在一个事务中执行，部分失败时整体回滚
+		out = &dbOutput{db: db, txRows: len(statements) + 1}
+	} else if out, err = newFileOutput(sqlPath); err != nil {
+		log.Fatalf("❌ %s", err.Error())
+	}
+	for _, statement := range statements {
+		if err := out.write(statement, 1); err != nil {
+			out.abort()
+			log.Fatalf("❌ 执行失败: %s: %s", statement, err.Error())
+		}
+	}
+	if err := out.close(); err != nil {
+		log.Fatalf("❌ 执行失败: %s", err.Error())
+	}
+	if apply {
+		log.Printf("✅ 已执行%d条语句", len(statements))
+		return
+	}
+	log.Printf("✅ 结果已保存到: %s", sqlPath)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// defaultMaxPacket 写SQL文件时不知道目标库的max_allowed_packet，按MySQL 5.7的默认值4MB
+const defaultMaxPacket = 4 << 20
+
+// output 输出到SQL文件或者直接在数据库中执行
+type output interface {
+	write(statement string, rows int) error
+	abort()
+	close() error
+}
+
+type fileOutput struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newFileOutput(path string) (*fileOutput, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.New("创建SQL文件失败: " + err.Error())
+	}
+	return &fileOutput{file: file, writer: bufio.NewWriterSize(file, 1<<20)}, nil
+}
+
+func (f *fileOutput) write(statement string, rows int) error {
+	if statement == "" {
+		return nil
+	}
+	_, _ = f.writer.WriteString(statement)
+	_, err := f.writer.WriteString(";\n")
+	return err
+}
+
+func (f *fileOutput) abort() {
+	_ = f.file.Close()
+}
+
+func (f *fileOutput) close() error {
+	if err := f.writer.Flush(); err != nil {
+		_ = f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+// dbOutput 每txRows行提交一次事务，失败时回滚当前事务，之前已经提交的事务不会回滚
+type dbOutput struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	txRows    int
+	rows      int // 当前事务中的行数
+	committed int // 已经提交的行数
+}
+
+// maxPacket 返回max_allowed_packet的90%，留出协议头等开销
+func (d *dbOutput) maxPacket() (int, error) {
+	var packet int64
+	if err := d.db.QueryRow("SELECT @@max_allowed_packet").Scan(&packet); err != nil {
+		return 0, errors.New("查询max_allowed_packet失败: " + err.Error())
+	}
+	return int(packet * 9 / 10), nil
+}
+
+func (d *dbOutput) write(statement string, rows int) error {
+	if statement == "" {
+		return nil
+	}
+	if d.tx == nil {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return err
+		}
+		d.tx = tx
+	}
+	if _, err := d.tx.Exec(statement); err != nil {
+		return err
+	}
+	d.rows += rows
+	if d.rows >= d.txRows {
+		return d.commit()
+	}
+	return nil
+}
+
+func (d *dbOutput) commit() error {
+	if d.tx == nil {
+		return nil
+	}
+	err := d.tx.Commit()
+	d.tx = nil
+	if err != nil {
+		return err
+	}
+	d.committed += d.rows
+	d.rows = 0
+	log.Printf("📢 已提交%d行", d.committed)
+	return nil
+}
+
+func (d *dbOutput) abort() {
+	if d.tx != nil {
+		_ = d.tx.Rollback()
+		log.Printf("📢 已回滚未提交的%d行，之前已提交%d行", d.rows, d.committed)
+	}
+}
+
+func (d *dbOutput) close() error {
+	return d.commit()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/soulnov23/go-tool/pkg/utils"
+)
+
+// row JSON中的一个对象，列保持对象中字段的顺序
+type row struct {
+	columns []string
+	values  []json.RawMessage
+}
+
+func (r *row) value(column string) (json.RawMessage, bool) {
+	for i, name := range r.columns {
+		if name == column {
+			return r.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// rowReader 逐个读取JSON数组或者NDJSON中的对象，不会把整个文件加载到内存中
+type rowReader struct {
+	decoder *json.Decoder
+	array   bool
+}
+
+func newRowReader(r io.Reader) (*rowReader, error) {
+	reader := bufio.NewReader(r)
+	if prefix, err := reader.Peek(3); err == nil && bytes.Equal(prefix, []byte("\xef\xbb\xbf")) {
+		_, _ = reader.Discard(3)
+	}
+	// 第一个非空白字符是[时按JSON数组读取，否则按NDJSON读取
+	array := false
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			_, _ = reader.Discard(1)
+			continue
+		}
+		array = b[0] == '['
+		break
+	}
+	decoder := json.NewDecoder(reader)
+	if array {
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+	return &rowReader{decoder: decoder, array: array}, nil
+}
+
+// next 读取下一个对象，读完时返回io.EOF
+func (r *rowReader) next() (*row, error) {
+	if !r.decoder.More() {
+		if r.array {
+			if _, err := r.decoder.Token(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, io.EOF
+	}
+	token, err := r.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("offset %d: want object, got %v", r.decoder.InputOffset(), token)
+	}
+	result := &row{}
+	for r.decoder.More() {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := r.decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		result.columns = append(result.columns, token.(string))
+		result.values = append(result.values, value)
+	}
+	if _, err := r.decoder.Token(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// literal 把JSON的值转换成SQL字面量，嵌套的对象和数组按JSON字符串插入
+func literal(value json.RawMessage) string {
+	switch value[0] {
+	case 'n':
+		return "NULL"
+	case 't':
+		return "1"
+	case 'f':
+		return "0"
+	case '"':
+		var s string
+		_ = json.Unmarshal(value, &s)
+		return quote(s)
+	case '{', '[':
+		buffer := &bytes.Buffer{}
+		if err := json.Compact(buffer, value); err != nil {
+			return quote(string(value))
+		}
+		return quote(buffer.String())
+	default:
+		return string(value)
+	}
+}
+
+func quote(value string) string {
+	return "'" + utils.MySQLRealEscapeString(value) + "'"
+}
+
+// writeTable 表名写成database.table时库名和表名分别包裹
+func writeTable(builder *strings.Builder, table string) {
+	for i, part := range strings.Split(table, ".") {
+		if i > 0 {
+			builder.WriteByte('.')
+		}
+		writeIdentifier(builder, part)
+	}
+}
+
+// writeIdentifier 用反引号包裹标识符，列名中的.是名字的一部分
+func writeIdentifier(builder *strings.Builder, name string) {
+	builder.WriteByte('`')
+	builder.WriteString(strings.ReplaceAll(name, "`", "``"))
+	builder.WriteByte('`')
+}
+
+func tableIdentifier(table string) string {
+	builder := &strings.Builder{}
+	writeTable(builder, table)
+	return builder.String()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: `null`, want: "NULL"},
+		{value: `true`, want: "1"},
+		{value: `false`, want: "0"},
+		{value: `12345678901234567890`, want: "12345678901234567890"},
+		{value: `1.5e3`, want: "1.5e3"},
+		{value: `"it's"`, want: `'it\'s'`},
+		{value: `"2024-01-02 03:04:05"`, want: "'2024-01-02 03:04:05'"},
+		{value: `{"a": [1, 2], "b": "c"}`, want: `'{\"a\":[1,2],\"b\":\"c\"}'`},
+		{value: `[ ]`, want: "'[]'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := literal(json.RawMessage(tt.value)); got != tt.want {
+				t.Errorf("literal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string // 每行的列名和值交替排列
+	}{
+		{
+			name:  "array",
+			input: "\xef\xbb\xbf [{\"id\":1,\"name\":\"a\"},\n{\"name\":null,\"id\":2}]",
+			want:  [][]string{{"id", "1", "name", `"a"`}, {"name", "null", "id", "2"}},
+		},
+		{
+			name:  "ndjson",
+			input: "{\"id\":1,\"tags\":[1,2]}\n{\"id\":2,\"meta\":{\"k\":\"v\"}}\n",
+			want:  [][]string{{"id", "1", "tags", "[1,2]"}, {"id", "2", "meta", `{"k":"v"}`}},
+		},
+		{
+			name:  "empty array",
+			input: "[]",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := newRowReader(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("newRowReader: %v", err)
+			}
+			var got [][]string
+			for {
+				r, err := reader.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("next: %v", err)
+				}
+				var fields []string
+				for i, column := range r.columns {
+					fields = append(fields, column, string(r.values[i]))
+				}
+				got = append(got, fields)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowReaderError(t *testing.T) {
+	for _, input := range []string{`[1]`, `{"id":1`, `[{"id":1},`} {
+		t.Run(input, func(t *testing.T) {
+			reader, err := newRowReader(strings.NewReader(input))
+			if err != nil {
+				return
+			}
+			for {
+				_, err := reader.next()
+				if err == io.EOF {
+					t.Fatalf("next() reached EOF, want error")
+				}
+				if err != nil {
+					return
+				}
+			}
+		})
+	}
+}
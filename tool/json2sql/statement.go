@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	modeInsert  = "insert"
+	modeIgnore  = "ignore"
+	modeReplace = "replace"
+	modeUpsert  = "upsert"
+)
+
+// modes k=mode,v=语句开头
+var modes = map[string]string{
+	modeInsert:  "INSERT INTO ",
+	modeIgnore:  "INSERT IGNORE INTO ",
+	modeReplace: "REPLACE INTO ",
+	modeUpsert:  "INSERT INTO ",
+}
+
+// builder 把字段相同的连续多行拼成一条INSERT语句，字段变化或者超过maxBytes时需要先输出已经添加的行
+type builder struct {
+	mode          string
+	table         string
+	updateColumns []string          // upsert时更新的列，为空时更新所有列
+	types         map[string]string // k=列名,v=数据库类型，用于把快照中的时间转换成MySQL的格式
+	timeFormat    string            // 快照中DATETIME和TIMESTAMP的格式
+	maxBytes      int               // 每条语句最大字节数，由输出目标的max_allowed_packet决定
+	columns       []string
+	prefix        string // INSERT INTO `t` (`a`,`b`) VALUES
+	suffix        string // ON DUPLICATE KEY UPDATE `a`=VALUES(`a`)
+	err           error
+	values        strings.Builder
+	rows          int
+}
+
+func newBuilder(mode string, table string, updateColumns []string) (*builder, error) {
+	if _, ok := modes[mode]; !ok {
+		return nil, fmt.Errorf("mode[%s] not support", mode)
+	}
+	if mode != modeUpsert && len(updateColumns) != 0 {
+		return nil, fmt.Errorf("update columns only support mode[%s]", modeUpsert)
+	}
+	return &builder{mode: mode, table: table, updateColumns: updateColumns}, nil
+}
+
+// same 是否可以和已经添加的行拼在一条语句中
+func (b *builder) same(r *row) bool {
+	return b.rows == 0 || slices.Equal(b.columns, r.columns)
+}
+
+// row 把一行拼成(v1,v2)
+func (b *builder) row(r *row) string {
+	row := &strings.Builder{}
+	row.WriteByte('(')
+	for i, value := range r.values {
+		if i > 0 {
+			row.WriteByte(',')
+		}
+		row.WriteString(literal(snapshotValue(value, b.types[r.columns[i]], b.timeFormat)))
+	}
+	row.WriteByte(')')
+	return row.String()
+}
+
+// fits 判断加上row后语句是否超过maxBytes，单行超过时也只能单独成一条语句
+func (b *builder) fits(row string) bool {
+	return b.rows == 0 || b.maxBytes <= 0 || len(b.prefix)+b.values.Len()+1+len(row)+len(b.suffix) <= b.maxBytes
+}
+
+func (b *builder) add(r *row) {
+	b.addRow(r, b.row(r))
+}
+
+func (b *builder) addRow(r *row, row string) {
+	if b.rows == 0 {
+		b.columns = r.columns
+		b.prefix, b.suffix, b.err = b.header()
+	} else {
+		b.values.WriteByte(',')
+	}
+	b.rows++
+	b.values.WriteString(row)
+}
+
+// header 按第一行的字段生成语句的开头和upsert的结尾
+func (b *builder) header() (string, string, error) {
+	prefix := &strings.Builder{}
+	prefix.WriteString(modes[b.mode])
+	writeTable(prefix, b.table)
+	prefix.WriteString(" (")
+	for i, column := range b.columns {
+		if i > 0 {
+			prefix.WriteByte(',')
+		}
+		writeIdentifier(prefix, column)
+	}
+	prefix.WriteString(") VALUES ")
+	if b.mode != modeUpsert {
+		return prefix.String(), "", nil
+	}
+	updateColumns := b.updateColumns
+	if len(updateColumns) == 0 {
+		updateColumns = b.columns
+	}
+	suffix := &strings.Builder{}
+	suffix.WriteString(" ON DUPLICATE KEY UPDATE ")
+	for i, column := range updateColumns {
+		if !slices.Contains(b.columns, column) {
+			return "", "", fmt.Errorf("update column[%s] not in row columns", column)
+		}
+		if i > 0 {
+			suffix.WriteByte(',')
+		}
+		writeIdentifier(suffix, column)
+		suffix.WriteString("=VALUES(")
+		writeIdentifier(suffix, column)
+		suffix.WriteByte(')')
+	}
+	return prefix.String(), suffix.String(), nil
+}
+
+// statement 返回已经添加的行拼成的语句并清空，没有行时返回空字符串
+func (b *builder) statement() (string, error) {
+	if b.rows == 0 {
+		return "", nil
+	}
+	if b.err != nil {
+		return "", b.err
+	}
+	statement := b.prefix + b.values.String() + b.suffix
+	b.values.Reset()
+	b.rows = 0
+	return statement, nil
+}
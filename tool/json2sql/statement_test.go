@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	unix := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local).Unix()
+	rows := []*row{
+		{columns: []string{"id", "a.b", "created_at"}, values: []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`"x"`), json.RawMessage(strconv.FormatInt(unix, 10))}},
+		{columns: []string{"id", "a.b", "created_at"}, values: []json.RawMessage{json.RawMessage(`2`), json.RawMessage(`"y"`), json.RawMessage(`null`)}},
+		{columns: []string{"id", "a.b", "created_at"}, values: []json.RawMessage{json.RawMessage(`3`), json.RawMessage(`"z"`), json.RawMessage(`null`)}},
+	}
+	b, err := newBuilder(modeUpsert, "db.t", []string{"a.b"})
+	if err != nil {
+		t.Fatalf("newBuilder: %v", err)
+	}
+	b.types = map[string]string{"id": "BIGINT", "a.b": "VARCHAR", "created_at": "DATETIME"}
+	b.timeFormat = "unix"
+	b.maxBytes = 150
+	var statements []string
+	for _, r := range rows {
+		row := b.row(r)
+		if !b.same(r) || !b.fits(row) {
+			statement, err := b.statement()
+			if err != nil {
+				t.Fatalf("statement: %v", err)
+			}
+			statements = append(statements, statement)
+		}
+		b.addRow(r, row)
+	}
+	statement, err := b.statement()
+	if err != nil {
+		t.Fatalf("statement: %v", err)
+	}
+	statements = append(statements, statement)
+	want := []string{
+		"INSERT INTO `db`.`t` (`id`,`a.b`,`created_at`) VALUES (1,'x','2024-01-02 03:04:05'),(2,'y',NULL) ON DUPLICATE KEY UPDATE `a.b`=VALUES(`a.b`)",
+		"INSERT INTO `db`.`t` (`id`,`a.b`,`created_at`) VALUES (3,'z',NULL) ON DUPLICATE KEY UPDATE `a.b`=VALUES(`a.b`)",
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("statements = %q, want %q", statements, want)
+	}
+	for i := range want {
+		if statements[i] != want[i] {
+			t.Errorf("statement %d = %s, want %s", i, statements[i], want[i])
+		}
+	}
+}
+
+func TestBuilderUpdateColumn(t *testing.T) {
+	b, _ := newBuilder(modeUpsert, "t", []string{"name"})
+	b.add(&row{columns: []string{"id"}, values: []json.RawMessage{json.RawMessage(`1`)}})
+	if _, err := b.statement(); err == nil {
+		t.Error("statement() error = nil, want update column not in row columns")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	pkglog "github.com/soulnov23/go-tool/pkg/log"
+	"github.com/soulnov23/go-tool/pkg/mysql"
+	"github.com/soulnov23/go-tool/pkg/mysql/migrate"
+	"gorm.io/gorm/logger"
+)
+
+// migrate -dsn 'user:password@tcp(ip:port)/database?charset=utf8mb4&loc=Local' -dir ./migrations up [version]
+// migrate -dsn 'user:password@tcp(ip:port)/database?charset=utf8mb4&loc=Local' -dir ./migrations down [steps]
+// migrate -dsn 'user:password@tcp(ip:port)/database?charset=utf8mb4&loc=Local' -dir ./migrations status
+func main() {
+	// 定义需要解析的命令行参数
+	var dsn string
+	var dir string
+	var table string
+	var lockTimeout time.Duration
+	var dryRun bool
+	flag.StringVar(&dsn, "dsn", "user:password@tcp(ip:port)/database?charset=utf8mb4&loc=Local", "mysql dsn")
+	flag.StringVar(&dir, "dir", "./migrations", "migration files dir, {version}_{name}.up.sql and {version}_{name}.down.sql")
+	flag.StringVar(&table, "table", "schema_migrations", "table to record applied migrations")
+	flag.DurationVar(&lockTimeout, "lock-timeout", 10*time.Second, "wait time for the migration lock")
+	flag.BoolVar(&dryRun, "dry-run", false, "print sql without executing")
+	// 开始解析命令行
+	flag.Parse()
+	// 命令行参数都不匹配，打印help
+	if flag.NFlag() == 0 || flag.NArg() == 0 {
+		flag.Usage()
+		return
+	}
+
+	log.SetFlags(0)
+	log.SetPrefix("\033[1;32m[migrate]\033[m ")
+
+	migrations, err := migrate.Load(os.DirFS(dir), ".")
+	if err != nil {
+		log.Fatalf("❌ 读取迁移文件失败: %s", err.Error())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db, err := mysql.New(ctx, dsn, pkglog.DefaultLogger, mysql.WithLogLevel(logger.Silent), mysql.WithDryRun(dryRun))
+	if err != nil {
+		log.Fatalf("❌ 连接失败: %s", err.Error())
+	}
+	defer mysql.Close(db)
+	log.Printf("✅ 连接成功")
+	migrator := migrate.New(db, migrations, migrate.WithTable(table), migrate.WithLockTimeout(lockTimeout), migrate.WithOutput(os.Stdout))
+
+	switch command := flag.Arg(0); command {
+	case "up":
+		target := argUint(1, 0)
+		applied, err := migrator.Up(context.Background(), target)
+		for _, migration := range applied {
+			log.Printf("✅ 已执行: %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ 执行迁移失败: %s", err.Error())
+		}
+		log.Printf("✅ 执行完成，共%d个迁移", len(applied))
+	case "down":
+		steps := argUint(1, 1)
+		reverted, err := migrator.Down(context.Background(), int(steps))
+		for _, migration := range reverted {
+			log.Printf("✅ 已回滚: %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ 回滚迁移失败: %s", err.Error())
+		}
+		log.Printf("✅ 回滚完成，共%d个迁移", len(reverted))
+	case "status":
+		statuses, err := migrator.Status(context.Background())
+		if err != nil {
+			log.Fatalf("❌ 查询迁移状态失败: %s", err.Error())
+		}
+		for _, status := range statuses {
+			switch {
+			case status.Missing:
+				log.Printf("❌ %d_%s 已执行但找不到迁移文件", status.Version, status.Name)
+			case status.Modified:
+				log.Printf("❌ %d_%s 执行后迁移文件被修改", status.Version, status.Name)
+			case status.Applied:
+				log.Printf("✅ %d_%s 执行于 %s", status.Version, status.Name, status.AppliedAt.Format(time.DateTime))
+			default:
+				log.Printf("📢 %d_%s 未执行", status.Version, status.Name)
+			}
+		}
+	default:
+		log.Fatalf("❌ 不支持的命令: %s，支持up down status", command)
+	}
+}
+
+func argUint(index int, defaultValue uint64) uint64 {
+	if flag.NArg() <= index {
+		return defaultValue
+	}
+	value, err := strconv.ParseUint(flag.Arg(index), 10, 64)
+	if err != nil {
+		log.Fatalf("❌ [%s]参数解析失败: %s", flag.Arg(index), err.Error())
+	}
+	return value
+}
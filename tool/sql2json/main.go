@@ -1,25 +1,55 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
 	"flag"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
-	"github.com/soulnov23/go-tool/pkg/utils"
 )
 
-// sql2json -dsn 'user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local' -sql 'select * from database.table' -output 'tmp.json'
+// progressRows 每导出这么多行打印一次进度
+const progressRows = 100000
+
+// argList 可以重复指定的-arg参数，按顺序替换sql中的?
+type argList []string
+
+func (a *argList) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *argList) Set(value string) error {
+	*a = append(*a, value)
+	return nil
+}
+
+// sql2json -dsn 'user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local' -sql 'select * from database.table where id > ?' -arg 100 -output 'tmp.json'
+// sql2json -dsn 'user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local' -sql-file query.sql -format ndjson -limit 1000 -output '-'
 func main() {
 	// 定义需要解析的命令行参数
 	var dsn string
 	var sqlQuery string
+	var sqlFile string
+	var args argList
 	var output string
+	var format string
+	var limit int
+	var timeFormat string
+	var tinyIntBool bool
 	flag.StringVar(&dsn, "dsn", "user:password@tcp(ip:port)/?charset=utf8mb4&loc=Local", "mysql dsn")
 	flag.StringVar(&sqlQuery, "sql", "select * from database.table", "sql query")
-	flag.StringVar(&output, "output", "tmp.json", "output file path")
+	flag.StringVar(&sqlFile, "sql-file", "", "read sql query from file")
+	flag.Var(&args, "arg", "sql query argument for ?, can be repeated")
+	flag.StringVar(&output, "output", "tmp.json", "output file path, - for stdout")
+	flag.StringVar(&format, "format", "", "output format json|ndjson|csv|yaml, default by output file extension")
+	flag.IntVar(&limit, "limit", 0, "max rows to export, 0 means no limit")
+	flag.StringVar(&timeFormat, "time-format", time.DateTime, "DATETIME and TIMESTAMP layout, unix or unix_milli for timestamp")
+	flag.BoolVar(&tinyIntBool, "tinyint-bool", false, "export TINYINT as bool")
 	// 开始解析命令行
 	flag.Parse()
 	// 命令行参数都不匹配，打印help
@@ -31,6 +61,19 @@ func main() {
 	log.SetFlags(0)
 	log.SetPrefix("\033[1;32m[sql2json]\033[m ")
 
+	if sqlFile != "" {
+		buffer, err := os.ReadFile(sqlFile)
+		if err != nil {
+			log.Fatalf("❌ 读取SQL文件失败: %s", err.Error())
+		}
+		sqlQuery = strings.TrimSpace(string(buffer))
+	}
+	format = formatOf(format, output)
+	newWriter, ok := writers[format]
+	if !ok {
+		log.Fatalf("❌ 不支持的输出格式: %s", format)
+	}
+
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		log.Fatalf("❌ [%s]参数解析失败: %s", dsn, err.Error())
@@ -41,49 +84,78 @@ func main() {
 		log.Fatalf("❌ 连接失败: %s", err.Error())
 	}
 	log.Printf("✅ 连接成功")
-	rows, err := db.Query(sqlQuery)
+
+	// 达到limit时取消查询，否则关闭结果集时驱动会读完剩下的所有行
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queryArgs := make([]any, 0, len(args))
+	for _, arg := range args {
+		queryArgs = append(queryArgs, arg)
+	}
+	rows, err := db.QueryContext(ctx, sqlQuery, queryArgs...)
 	if err != nil {
 		log.Fatalf("❌ 查询失败: %s", err.Error())
 	}
 	defer rows.Close()
 	log.Printf("✅ 查询成功")
-	columns, err := rows.Columns()
+	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
 		log.Fatalf("❌ 获取列名失败: %s", err.Error())
 	}
+	columns := make([]string, len(columnTypes))
+	types := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = columnType.Name()
+		types[i] = columnType.DatabaseTypeName()
+	}
 	log.Printf("✅ 获取列名成功")
-	result := make([]map[string]any, 0)
+
+	file := os.Stdout
+	if output != "-" {
+		file, err = os.Create(output)
+		if err != nil {
+			log.Fatalf("❌ 创建文件失败: %s: %s", output, err.Error())
+		}
+		defer file.Close()
+	}
+	buffer := bufio.NewWriterSize(file, 1<<20)
+	w := newWriter(buffer)
+	if err := w.Begin(columns); err != nil {
+		log.Fatalf("❌ 写入文件失败: %s: %s", output, err.Error())
+	}
+
+	c := &converter{timeFormat: timeFormat, tinyIntBool: tinyIntBool}
 	values := make([]any, len(columns))
 	ptrs := make([]any, len(columns))
 	for i := range ptrs {
 		ptrs[i] = &values[i]
 	}
-	for rows.Next() {
+	count := 0
+	for (limit <= 0 || count < limit) && rows.Next() {
 		if err := rows.Scan(ptrs...); err != nil {
 			log.Fatalf("❌ 扫描行失败: %s", err.Error())
 		}
-		record := make(map[string]any, len(columns))
-		for i, column := range columns {
-			value := values[i]
-			if bytes, ok := value.([]byte); ok {
-				value = string(bytes)
-				var jsonValue any
-				if jsoniter.Unmarshal(bytes, &jsonValue) == nil {
-					switch jsonValue.(type) {
-					case map[string]any, []any:
-						value = jsonValue
-					}
-				}
-			}
-			record[column] = value
+		for i, value := range values {
+			values[i] = c.convert(types[i], value)
+		}
+		if err := w.Write(values); err != nil {
+			log.Fatalf("❌ 写入文件失败: %s: %s", output, err.Error())
+		}
+		count++
+		if count%progressRows == 0 {
+			log.Printf("📢 已导出%d行", count)
 		}
-		result = append(result, record)
 	}
-	if err := rows.Err(); err != nil {
+	if limit > 0 && count >= limit {
+		cancel()
+	} else if err := rows.Err(); err != nil {
 		log.Fatalf("❌ 行迭代失败: %s", err.Error())
 	}
-	if err := os.WriteFile(output, utils.Bytesify(result), 0o644); err != nil {
+	if err := w.End(); err != nil {
+		log.Fatalf("❌ 写入文件失败: %s: %s", output, err.Error())
+	}
+	if err := buffer.Flush(); err != nil {
 		log.Fatalf("❌ 写入文件失败: %s: %s", output, err.Error())
 	}
-	log.Printf("✅ 结果已保存到: %s", output)
+	log.Printf("✅ 共导出%d行，结果已保存到: %s", count, output)
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateTimeLayout 没有开启parseTime时DATETIME和TIMESTAMP返回的文本格式
+const dateTimeLayout = "2006-01-02 15:04:05.999999"
+
+// converter 按列的数据库类型把驱动返回的值转换成输出的值，text协议返回[]byte，binary协议返回int64、float64和time.Time，
+// JSON列转换成json.RawMessage
+type converter struct {
+	timeFormat  string // DATETIME和TIMESTAMP的输出格式，unix和unix_milli输出时间戳
+	tinyIntBool bool   // TINYINT按bool输出，一般是TINYINT(1)
+}
+
+func (c *converter) convert(typ string, value any) any {
+	if value == nil {
+		return nil
+	}
+	bytes, isBytes := value.([]byte)
+	switch typ {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		if isBytes {
+			if i, err := strconv.ParseInt(string(bytes), 10, 64); err == nil {
+				value = i
+			}
+		}
+		if c.tinyIntBool && typ == "TINYINT" {
+			if i, ok := value.(int64); ok {
+				return i != 0
+			}
+		}
+		return value
+	case "UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+		if isBytes {
+			if i, err := strconv.ParseUint(string(bytes), 10, 64); err == nil {
+				return i
+			}
+		}
+		return value
+	case "FLOAT", "DOUBLE":
+		if isBytes {
+			if f, err := strconv.ParseFloat(string(bytes), 64); err == nil {
+				return f
+			}
+		}
+		return value
+	case "DECIMAL":
+		// 转成float64会丢失精度，按字符串输出
+		if isBytes {
+			return string(bytes)
+		}
+		return value
+	case "BIT":
+		if !isBytes {
+			return value
+		}
+		// BIT(1)一般用作bool
+		if len(bytes) == 1 && bytes[0] <= 1 {
+			return bytes[0] == 1
+		}
+		buffer := make([]byte, 8)
+		copy(buffer[8-min(len(bytes), 8):], bytes)
+		return binary.BigEndian.Uint64(buffer)
+	case "DATETIME", "TIMESTAMP":
+		if isBytes {
+			t, err := time.ParseInLocation(dateTimeLayout, string(bytes), time.Local)
+			if err != nil {
+				// 0000-00-00 00:00:00之类的值原样输出
+				return string(bytes)
+			}
+			value = t
+		}
+		if t, ok := value.(time.Time); ok {
+			return c.formatTime(t)
+		}
+		return value
+	case "DATE":
+		if t, ok := value.(time.Time); ok {
+			return t.Format(time.DateOnly)
+		}
+	case "JSON":
+		// 原样输出，避免大整数和浮点数解析后丢失精度
+		if isBytes && json.Valid(bytes) {
+			return json.RawMessage(bytes)
+		}
+	}
+	if isBytes {
+		return string(bytes)
+	}
+	return value
+}
+
+func (c *converter) formatTime(t time.Time) any {
+	switch strings.ToLower(c.timeFormat) {
+	case "unix":
+		return t.Unix()
+	case "unix_milli":
+		return t.UnixMilli()
+	default:
+		return t.Format(c.timeFormat)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/soulnov23/go-tool/pkg/json/jsoniter"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+	formatYAML   = "yaml"
+)
+
+// writer 逐行输出查询结果，行内的列保持查询结果的顺序
+type writer interface {
+	Begin(columns []string) error
+	Write(values []any) error
+	End() error
+}
+
+// formatOf 没有指定-format时按输出文件的后缀选择格式
+func formatOf(format string, output string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".csv":
+		return formatCSV
+	case ".yaml", ".yml":
+		return formatYAML
+	default:
+		return formatJSON
+	}
+}
+
+// writers k=format,v=创建writer的函数
+var writers = map[string]func(w *bufio.Writer) writer{
+	formatJSON:   func(w *bufio.Writer) writer { return &jsonWriter{w: w, array: true} },
+	formatNDJSON: func(w *bufio.Writer) writer { return &jsonWriter{w: w, array: false} },
+	formatCSV:    func(w *bufio.Writer) writer { return &csvWriter{w: csv.NewWriter(w)} },
+	formatYAML:   func(w *bufio.Writer) writer { return &yamlWriter{w: w} },
+}
+
+// jsonWriter array为true时输出JSON数组，否则每行一个JSON对象
+type jsonWriter struct {
+	w       *bufio.Writer
+	array   bool
+	columns [][]byte // 序列化后的列名
+	rows    int
+}
+
+func (j *jsonWriter) Begin(columns []string) error {
+	for _, column := range columns {
+		key, err := jsoniter.Marshal(column)
+		if err != nil {
+			return err
+		}
+		j.columns = append(j.columns, key)
+	}
+	if j.array {
+		_, err := j.w.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonWriter) Write(values []any) error {
+	if j.array {
+		if j.rows > 0 {
+			_ = j.w.WriteByte(',')
+		}
+		_ = j.w.WriteByte('\n')
+	}
+	j.rows++
+	_ = j.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			_ = j.w.WriteByte(',')
+		}
+		buffer, err := jsoniter.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("marshal column %s: %v", j.columns[i], err)
+		}
+		_, _ = j.w.Write(j.columns[i])
+		_ = j.w.WriteByte(':')
+		_, _ = j.w.Write(buffer)
+	}
+	_ = j.w.WriteByte('}')
+	if !j.array {
+		return j.w.WriteByte('\n')
+	}
+	return nil
+}
+
+func (j *jsonWriter) End() error {
+	if !j.array {
+		return nil
+	}
+	if j.rows > 0 {
+		_ = j.w.WriteByte('\n')
+	}
+	_, err := j.w.WriteString("]\n")
+	return err
+}
+
+// csvWriter 第一行是列名，NULL输出为空字符串，JSON列输出JSON文本
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvWriter) Begin(columns []string) error {
+	c.record = make([]string, len(columns))
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Write(values []any) error {
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			c.record[i] = ""
+		case string:
+			c.record[i] = v
+		case bool:
+			c.record[i] = strconv.FormatBool(v)
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case uint64:
+			c.record[i] = strconv.FormatUint(v, 10)
+		case float64:
+			c.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case json.RawMessage:
+			c.record[i] = string(v)
+		default:
+			buffer, err := jsoniter.Marshal(v)
+			if err != nil {
+				return err
+			}
+			c.record[i] = string(buffer)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// yamlWriter 每行输出为序列中的一个映射，拼接起来是一个完整的YAML序列
+type yamlWriter struct {
+	w       io.Writer
+	columns []*yaml.Node
+	rows    int
+}
+
+func (y *yamlWriter) Begin(columns []string) error {
+	for _, column := range columns {
+		y.columns = append(y.columns, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: column})
+	}
+	return nil
+}
+
+func (y *yamlWriter) Write(values []any) error {
+	row := &yaml.Node{Kind: yaml.MappingNode}
+	for i, value := range values {
+		node := &yaml.Node{}
+		if raw, ok := value.(json.RawMessage); ok {
+			// JSON是YAML的子集，按YAML解析保留数字类型
+			if err := yaml.Unmarshal(raw, node); err != nil {
+				return fmt.Errorf("decode column %s: %v", y.columns[i].Value, err)
+			}
+			node = node.Content[0]
+		} else if err := node.Encode(value); err != nil {
+			return fmt.Errorf("encode column %s: %v", y.columns[i].Value, err)
+		}
+		row.Content = append(row.Content, y.columns[i], node)
+	}
+	buffer, err := yaml.Marshal(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{row}})
+	if err != nil {
+		return err
+	}
+	y.rows++
+	_, err = y.w.Write(buffer)
+	return err
+}
+
+func (y *yamlWriter) End() error {
+	if y.rows == 0 {
+		_, err := io.WriteString(y.w, "[]\n")
+		return err
+	}
+	return nil
+}